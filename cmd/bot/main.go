@@ -4,11 +4,16 @@ import (
 	"context"
 	"dcabot/internal/config"
 	"dcabot/internal/engine"
-	"dcabot/internal/exchange/bybit"
+	"dcabot/internal/exchange"
+	_ "dcabot/internal/exchange/all"
 	"dcabot/internal/logger"
+	"dcabot/internal/metrics"
+	"dcabot/internal/secrets"
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
@@ -29,15 +34,40 @@ func main() {
 		MaxBackups: cfg.Runtime.Log.MaxBackups,
 		MaxAge:     cfg.Runtime.Log.MaxAge,
 		Compress:   cfg.Runtime.Log.Compress,
+		Fields: logrus.Fields{
+			"exchange":    cfg.Exchange.Name,
+			"environment": cfg.Exchange.Environment,
+		},
+		Redact: secrets.Redact,
 	})
 
 	logger.Info("Бот запущен.")
 
-	client := bybit.New(cfg.Exchange.BaseUrl, cfg.Exchange.WSPublicURL, cfg.Exchange.WSPrivateURL, cfg.Exchange.AccountType, cfg.Exchange.ApiKey, cfg.Exchange.Secret, logger)
+	if err := config.CheckEnvironment(cfg); err != nil {
+		logger.WithError(err).Fatal("Запуск остановлен проверкой окружения.")
+	}
+
+	if err := secrets.Apply(context.Background(), cfg); err != nil {
+		logger.WithError(err).Fatal("Не удалось загрузить секреты.")
+	}
+
+	client, err := exchange.New(cfg.Exchange, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Не удалось создать клиента биржи.")
+	}
+	if err := config.RecordEnvironment(cfg); err != nil {
+		logger.WithError(err).Fatal("Не удалось сохранить окружение запуска.")
+	}
 	eng := engine.New(cfg, client, logger)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	metrics.SetTags(cfg.Exchange.Name, cfg.Exchange.Environment)
+	metrics.Handle("/status", eng)
+	if cfg.Runtime.MetricsAddr != "" {
+		go metrics.Serve(ctx, cfg.Runtime.MetricsAddr, logger)
+	}
+
 	go func() {
 		if err := eng.Start(ctx); err != nil {
 			logger.WithError(err).Fatal("\"Двигатель\" завершился с ошибкой.")
@@ -46,6 +76,7 @@ func main() {
 	<-sigCh
 
 	cancel()
+	eng.Wait()
 
 	logger.Info("Бот остановлен.")
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"dcabot/internal/secrets"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	file := flag.String("file", "data/keystore.json", "путь к хранилищу ключей")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Использование: keystore [-file path] set <key> | delete <key> | list")
+		fmt.Fprintln(os.Stderr, "Пароль берётся из DCABOT_KEYSTORE_PASSPHRASE, значение для set читается из stdin.")
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	passphrase := os.Getenv("DCABOT_KEYSTORE_PASSPHRASE")
+	if passphrase == "" {
+		passphrase = prompt(reader, "Пароль хранилища: ")
+	}
+
+	ks, err := secrets.OpenKeystore(*file, passphrase)
+	if err != nil {
+		fail(err)
+	}
+
+	switch args[0] {
+	case "set":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		value := prompt(reader, "Значение: ")
+		if value == "" {
+			fail(fmt.Errorf("Пустое значение секрета."))
+		}
+		ks.Set(args[1], value)
+		if err := ks.Save(); err != nil {
+			fail(err)
+		}
+	case "delete":
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		ks.Delete(args[1])
+		if err := ks.Save(); err != nil {
+			fail(err)
+		}
+	case "list":
+		for _, key := range ks.Keys() {
+			fmt.Println(key)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func prompt(reader *bufio.Reader, label string) string {
+	fmt.Fprint(os.Stderr, label)
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		fail(err)
+	}
+	return strings.TrimSpace(line)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -11,18 +13,30 @@ type Config struct {
 	Exchange ExchangeConfig `mapstructure:"exchange"`
 	Bot      BotConfig      `mapstructure:"bot"`
 	Runtime  RuntimeConfig  `mapstructure:"runtime"`
+	Secrets  SecretsCfg     `mapstructure:"secrets"`
 }
 
 type ExchangeConfig struct {
-	BaseUrl      string `mapstructure:"base_url"`
-	WSPublicURL  string `mapstructure:"ws_public_url"`
-	WSPrivateURL string `mapstructure:"ws_private_url"`
-	AccountType  string `mapstructure:"account_type"`
-	ApiKey       string `mapstructure:"api_key"`
-	Secret       string `mapstructure:"secret"`
+	Name           string `mapstructure:"name"`
+	Environment    string `mapstructure:"environment"`
+	ConfirmMainnet bool   `mapstructure:"confirm_mainnet"`
+	BaseUrl        string `mapstructure:"base_url"`
+	WSPublicURL    string `mapstructure:"ws_public_url"`
+	WSPrivateURL   string `mapstructure:"ws_private_url"`
+	AccountType    string `mapstructure:"account_type"`
+	ApiKey         string `mapstructure:"api_key"`
+	Secret         string `mapstructure:"secret"`
+	Passphrase     string `mapstructure:"passphrase"`
+	SignMethod     string `mapstructure:"sign_method"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	TickerStream   string `mapstructure:"ticker_stream"`
+
+	RecvWindow       int           `mapstructure:"recv_window"`
+	TimeSyncInterval time.Duration `mapstructure:"time_sync_interval"`
 }
 
 type BotConfig struct {
+	Strategy         string  `mapstructure:"strategy"`
 	Symbol           string  `mapstructure:"symbol"`
 	Side             string  `mapstructure:"side"`
 	BaseOrderQty     float64 `mapstructure:"base_order_qty"`
@@ -33,12 +47,96 @@ type BotConfig struct {
 	SOStepMultiplier float64 `mapstructure:"so_step_multiplier"`
 	SOBaseQty        float64 `mapstructure:"so_base_qty"`
 	SOQtyMultiplier  float64 `mapstructure:"so_qty_multiplier"`
+
+	MaxActiveSafetyOrders int           `mapstructure:"max_active_safety_orders"`
+	SOAnchor              string        `mapstructure:"so_anchor"`
+	SOStepMode            string        `mapstructure:"so_step_mode"`
+	SOStepVolatility      VolatilityCfg `mapstructure:"so_step_volatility"`
+
+	Compounding CompoundingCfg `mapstructure:"compounding"`
+	Accumulate  AccumulateCfg  `mapstructure:"accumulate"`
+	Grid        GridCfg        `mapstructure:"grid"`
+	OrderPolicy OrderPolicyCfg `mapstructure:"order_policy"`
+	Reconcile   ReconcileCfg   `mapstructure:"reconcile"`
+	Dust        DustCfg        `mapstructure:"dust"`
+
+	ReservedBase float64 `mapstructure:"reserved_base"`
+}
+
+type DustCfg struct {
+	Mode            string        `mapstructure:"mode"`
+	ConvertTo       string        `mapstructure:"convert_to"`
+	ConvertInterval time.Duration `mapstructure:"convert_interval"`
+}
+
+type ReconcileCfg struct {
+	Mode     string        `mapstructure:"mode"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+type OrderPolicyCfg struct {
+	OnCancel   string `mapstructure:"on_cancel"`
+	OnReject   string `mapstructure:"on_reject"`
+	MaxRetries int    `mapstructure:"max_retries"`
+}
+
+type GridCfg struct {
+	LowerPrice  float64 `mapstructure:"lower_price"`
+	UpperPrice  float64 `mapstructure:"upper_price"`
+	Levels      int     `mapstructure:"levels"`
+	Spacing     string  `mapstructure:"spacing"`
+	QtyPerLevel float64 `mapstructure:"qty_per_level"`
+	InitialBuy  bool    `mapstructure:"initial_buy"`
+}
+
+type AccumulateCfg struct {
+	Schedule           string        `mapstructure:"schedule"`
+	Every              time.Duration `mapstructure:"every"`
+	QuoteAmount        float64       `mapstructure:"quote_amount"`
+	OrderType          string        `mapstructure:"order_type"`
+	LimitOffsetPercent float64       `mapstructure:"limit_offset_percent"`
+	MAInterval         string        `mapstructure:"ma_interval"`
+	MAPeriod           int           `mapstructure:"ma_period"`
+	BelowMAMultiplier  float64       `mapstructure:"below_ma_multiplier"`
+	LedgerFile         string        `mapstructure:"ledger_file"`
+}
+
+type CompoundingCfg struct {
+	Enabled       bool    `mapstructure:"enabled"`
+	BaseCapital   float64 `mapstructure:"base_capital"`
+	MinMultiplier float64 `mapstructure:"min_multiplier"`
+	MaxMultiplier float64 `mapstructure:"max_multiplier"`
+}
+
+type VolatilityCfg struct {
+	Interval   string  `mapstructure:"interval"`
+	Period     int     `mapstructure:"period"`
+	Multiplier float64 `mapstructure:"multiplier"`
+	MinPercent float64 `mapstructure:"min_percent"`
+	MaxPercent float64 `mapstructure:"max_percent"`
+}
+
+type SecretsCfg struct {
+	KeystoreFile       string   `mapstructure:"keystore_file"`
+	KeystorePassphrase string   `mapstructure:"keystore_passphrase"`
+	Vault              VaultCfg `mapstructure:"vault"`
+}
+
+type VaultCfg struct {
+	Address string        `mapstructure:"address"`
+	Token   string        `mapstructure:"token"`
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 type RuntimeConfig struct {
 	DryRun              bool   `mapstructure:"dry_run"`
 	RestoreStateOnStart bool   `mapstructure:"restore_state_on_start"`
+	StateFile           string `mapstructure:"state_file"`
+	EnvironmentFile     string `mapstructure:"environment_file"`
 	Log                 LogCfg `mapsttructure:"log"`
+
+	TickerStaleAfter time.Duration `mapstructure:"ticker_stale_after"`
+	MetricsAddr      string        `mapstructure:"metrics_addr"`
 }
 
 type LogCfg struct {
@@ -67,15 +165,123 @@ func Load() (*Config, error) {
 
 	cfg.Exchange.ApiKey = os.ExpandEnv(cfg.Exchange.ApiKey)
 	cfg.Exchange.Secret = os.ExpandEnv(cfg.Exchange.Secret)
+	cfg.Exchange.Passphrase = os.ExpandEnv(cfg.Exchange.Passphrase)
+	cfg.Secrets.KeystoreFile = os.ExpandEnv(cfg.Secrets.KeystoreFile)
+	cfg.Secrets.KeystorePassphrase = os.ExpandEnv(cfg.Secrets.KeystorePassphrase)
+	cfg.Secrets.Vault.Address = os.ExpandEnv(cfg.Secrets.Vault.Address)
+	cfg.Secrets.Vault.Token = os.ExpandEnv(cfg.Secrets.Vault.Token)
+
+	if cfg.Exchange.Name == "" {
+		cfg.Exchange.Name = "bybit"
+	}
+
+	switch strings.ToLower(cfg.Exchange.Environment) {
+	case "":
+		cfg.Exchange.Environment = EnvironmentMainnet
+	case EnvironmentMainnet, EnvironmentTestnet, EnvironmentDemo:
+		cfg.Exchange.Environment = strings.ToLower(cfg.Exchange.Environment)
+	default:
+		return nil, fmt.Errorf("Неизвестное окружение exchange.environment: %s", cfg.Exchange.Environment)
+	}
+
+	if cfg.Exchange.SignMethod == "" {
+		cfg.Exchange.SignMethod = "hmac"
+	}
+
+	if cfg.Exchange.RecvWindow == 0 {
+		cfg.Exchange.RecvWindow = 5000
+	}
+	if cfg.Exchange.TimeSyncInterval == 0 {
+		cfg.Exchange.TimeSyncInterval = 10 * time.Minute
+	}
+
+	if cfg.Exchange.TickerStream == "" {
+		cfg.Exchange.TickerStream = "miniTicker"
+	}
 
 	if cfg.Exchange.AccountType == "" {
 		cfg.Exchange.AccountType = "UNIFIED"
 	}
 
+	if cfg.Bot.Strategy == "" {
+		cfg.Bot.Strategy = "dca"
+	}
+
 	if cfg.Bot.QtyUnit == "" {
 		cfg.Bot.QtyUnit = "baseCoin"
 	}
 
+	if cfg.Bot.SOAnchor == "" {
+		cfg.Bot.SOAnchor = "entry"
+	}
+
+	if cfg.Bot.SOStepMode == "" {
+		cfg.Bot.SOStepMode = "fixed"
+	}
+	if cfg.Bot.SOStepVolatility.Interval == "" {
+		cfg.Bot.SOStepVolatility.Interval = "60"
+	}
+	if cfg.Bot.SOStepVolatility.Period == 0 {
+		cfg.Bot.SOStepVolatility.Period = 14
+	}
+	if cfg.Bot.SOStepVolatility.Multiplier == 0 {
+		cfg.Bot.SOStepVolatility.Multiplier = 1
+	}
+
+	if cfg.Bot.Compounding.MinMultiplier == 0 {
+		cfg.Bot.Compounding.MinMultiplier = 0.5
+	}
+	if cfg.Bot.Compounding.MaxMultiplier == 0 {
+		cfg.Bot.Compounding.MaxMultiplier = 2
+	}
+
+	if cfg.Bot.Accumulate.OrderType == "" {
+		cfg.Bot.Accumulate.OrderType = "market"
+	}
+	if cfg.Bot.Accumulate.MAInterval == "" {
+		cfg.Bot.Accumulate.MAInterval = "D"
+	}
+	if cfg.Bot.Accumulate.LedgerFile == "" {
+		cfg.Bot.Accumulate.LedgerFile = "data/ledger.json"
+	}
+
+	if cfg.Bot.Grid.Spacing == "" {
+		cfg.Bot.Grid.Spacing = "arithmetic"
+	}
+
+	if cfg.Bot.OrderPolicy.OnCancel == "" {
+		cfg.Bot.OrderPolicy.OnCancel = "retry"
+	}
+	if cfg.Bot.OrderPolicy.OnReject == "" {
+		cfg.Bot.OrderPolicy.OnReject = "alert"
+	}
+	if cfg.Bot.OrderPolicy.MaxRetries == 0 {
+		cfg.Bot.OrderPolicy.MaxRetries = 3
+	}
+	if cfg.Bot.Reconcile.Mode == "" {
+		cfg.Bot.Reconcile.Mode = "halt"
+	}
+	if cfg.Bot.Reconcile.Interval == 0 {
+		cfg.Bot.Reconcile.Interval = time.Minute
+	}
+	if cfg.Bot.Dust.Mode == "" {
+		cfg.Bot.Dust.Mode = "carry"
+	}
+	if cfg.Bot.Dust.ConvertInterval == 0 {
+		cfg.Bot.Dust.ConvertInterval = 24 * time.Hour
+	}
+
+	if cfg.Runtime.StateFile == "" {
+		cfg.Runtime.StateFile = "data/state.json"
+	}
+	if cfg.Runtime.EnvironmentFile == "" {
+		cfg.Runtime.EnvironmentFile = EnvironmentFileFor(cfg.Runtime.StateFile)
+	}
+
+	if cfg.Runtime.TickerStaleAfter == 0 {
+		cfg.Runtime.TickerStaleAfter = 30 * time.Second
+	}
+
 	if cfg.Runtime.Log.Level == "" {
 		cfg.Runtime.Log.Level = "info"
 	}
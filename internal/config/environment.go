@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	EnvironmentMainnet = "mainnet"
+	EnvironmentTestnet = "testnet"
+	EnvironmentDemo    = "demo"
+)
+
+type environmentMarker struct {
+	Exchange    string    `json:"exchange"`
+	Environment string    `json:"environment"`
+	StateFile   string    `json:"state_file"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EnvironmentFileFor возвращает файл окружения рядом с файлом состояния: у каждого
+// экземпляра бота своя отметка последнего окружения.
+func EnvironmentFileFor(stateFile string) string {
+	return strings.TrimSuffix(stateFile, filepath.Ext(stateFile)) + ".environment.json"
+}
+
+// CheckEnvironment сверяет окружение с последним запуском этого файла состояния.
+// Отметка не меняется: её пишет RecordEnvironment после проверок биржи.
+func CheckEnvironment(cfg *Config) error {
+	path := cfg.Runtime.EnvironmentFile
+	current := cfg.Exchange.Environment
+
+	var last environmentMarker
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &last); err != nil {
+			return fmt.Errorf("Не удалось разобрать файл окружения %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return fmt.Errorf("Не удалось прочитать файл окружения %s: %w", path, err)
+	}
+	if last.StateFile != "" && last.StateFile != cfg.Runtime.StateFile {
+		// Отметка другого экземпляра бота с общим environment_file.
+		last = environmentMarker{}
+	}
+
+	if current == EnvironmentMainnet && last.Environment != "" && last.Environment != EnvironmentMainnet && !cfg.Exchange.ConfirmMainnet {
+		return fmt.Errorf("Конфиг последний раз запускался в окружении %s, запуск на mainnet требует exchange.confirm_mainnet: true", last.Environment)
+	}
+	return nil
+}
+
+// RecordEnvironment запоминает окружение успешного запуска.
+func RecordEnvironment(cfg *Config) error {
+	path := cfg.Runtime.EnvironmentFile
+	marker := environmentMarker{
+		Exchange:    cfg.Exchange.Name,
+		Environment: cfg.Exchange.Environment,
+		StateFile:   cfg.Runtime.StateFile,
+		UpdatedAt:   time.Now(),
+	}
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Не удалось подготовить файл окружения: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("Не удалось создать каталог для файла окружения: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("Не удалось записать файл окружения %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("Не удалось заменить файл окружения %s: %w", path, err)
+	}
+	return nil
+}
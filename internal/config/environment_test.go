@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func environmentConfig(dir, stateFile, environment string) *Config {
+	cfg := &Config{}
+	cfg.Exchange.Name = "bybit"
+	cfg.Exchange.Environment = environment
+	cfg.Runtime.StateFile = filepath.Join(dir, stateFile)
+	cfg.Runtime.EnvironmentFile = EnvironmentFileFor(cfg.Runtime.StateFile)
+	return cfg
+}
+
+func TestEnvironmentFileFor(t *testing.T) {
+	tests := []struct {
+		stateFile string
+		want      string
+	}{
+		{stateFile: "data/state.json", want: "data/state.environment.json"},
+		{stateFile: "data/btc", want: "data/btc.environment.json"},
+		{stateFile: "/var/lib/bot/eth.state.json", want: "/var/lib/bot/eth.state.environment.json"},
+	}
+	for _, tt := range tests {
+		if got := EnvironmentFileFor(tt.stateFile); got != tt.want {
+			t.Errorf("EnvironmentFileFor(%q) = %q, want %q", tt.stateFile, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		shared   bool
+		current  string
+		confirm  bool
+		wantErr  bool
+	}{
+		{name: "first start on mainnet", current: EnvironmentMainnet},
+		{name: "mainnet after mainnet", previous: EnvironmentMainnet, current: EnvironmentMainnet},
+		{name: "testnet after mainnet", previous: EnvironmentMainnet, current: EnvironmentTestnet},
+		{name: "mainnet after testnet", previous: EnvironmentTestnet, current: EnvironmentMainnet, wantErr: true},
+		{name: "mainnet after demo", previous: EnvironmentDemo, current: EnvironmentMainnet, wantErr: true},
+		{name: "mainnet after testnet confirmed", previous: EnvironmentTestnet, current: EnvironmentMainnet, confirm: true},
+		{name: "shared file of another bot", previous: EnvironmentTestnet, shared: true, current: EnvironmentMainnet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := environmentConfig(dir, "state.json", tt.current)
+			cfg.Exchange.ConfirmMainnet = tt.confirm
+			if tt.previous != "" {
+				previous := environmentConfig(dir, "state.json", tt.previous)
+				if tt.shared {
+					previous = environmentConfig(dir, "other.json", tt.previous)
+					previous.Runtime.EnvironmentFile = cfg.Runtime.EnvironmentFile
+				}
+				if err := RecordEnvironment(previous); err != nil {
+					t.Fatalf("RecordEnvironment: %v", err)
+				}
+			}
+			before, _ := os.ReadFile(cfg.Runtime.EnvironmentFile)
+
+			err := CheckEnvironment(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckEnvironment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Проверка не пишет отметку: её запись - после проверок биржи.
+			after, _ := os.ReadFile(cfg.Runtime.EnvironmentFile)
+			if string(before) != string(after) {
+				t.Fatal("CheckEnvironment изменил файл окружения")
+			}
+		})
+	}
+}
+
+func TestRecordEnvironmentPerStateFile(t *testing.T) {
+	dir := t.TempDir()
+	testnet := environmentConfig(dir, "btc.json", EnvironmentTestnet)
+	if err := RecordEnvironment(testnet); err != nil {
+		t.Fatalf("RecordEnvironment: %v", err)
+	}
+	info, err := os.Stat(testnet.Runtime.EnvironmentFile)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("права файла окружения = %o, want 600", perm)
+	}
+
+	// Другой экземпляр бота со своим файлом состояния не видит отметку testnet.
+	if err := CheckEnvironment(environmentConfig(dir, "eth.json", EnvironmentMainnet)); err != nil {
+		t.Fatalf("CheckEnvironment другого state_file: %v", err)
+	}
+	if err := CheckEnvironment(environmentConfig(dir, "btc.json", EnvironmentMainnet)); err == nil {
+		t.Fatal("ожидалась ошибка запуска mainnet после testnet")
+	}
+}
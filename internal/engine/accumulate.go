@@ -0,0 +1,376 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	strategyDCA        = "dca"
+	strategyAccumulate = "accumulate"
+)
+
+type AccumulationLedger struct {
+	ID               string          `json:"id"`
+	Symbol           string          `json:"symbol"`
+	TotalQty         float64         `json:"total_qty"`
+	TotalCost        float64         `json:"total_cost"`
+	AvgCost          float64         `json:"avg_cost"`
+	Fills            int             `json:"fills"`
+	LastSlot         time.Time       `json:"last_slot"`
+	ProcessedExecIDs map[string]bool `json:"processed_exec_ids"`
+	Entries          []LedgerEntry   `json:"entries"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+type LedgerEntry struct {
+	LinkID  string    `json:"link_id"`
+	OrderID string    `json:"order_id"`
+	ExecID  string    `json:"exec_id"`
+	Price   float64   `json:"price"`
+	Qty     float64   `json:"qty"`
+	Cost    float64   `json:"cost"`
+	Time    time.Time `json:"time"`
+}
+
+func (e *Engine) strategy() string {
+	switch strings.ToLower(strings.TrimSpace(e.cfg.Bot.Strategy)) {
+	case strategyAccumulate:
+		return strategyAccumulate
+	case strategyGrid:
+		return strategyGrid
+	default:
+		return strategyDCA
+	}
+}
+
+func isAccumulationLinkID(linkID string) bool {
+	return strings.Contains(linkID, "-acc-")
+}
+
+func (e *Engine) startAccumulation(ctx context.Context) error {
+	accCfg := e.cfg.Bot.Accumulate
+	if accCfg.QuoteAmount <= 0 {
+		return fmt.Errorf("Не задан объём покупки accumulate.quote_amount.")
+	}
+	if accCfg.Schedule == "" && accCfg.Every <= 0 {
+		return fmt.Errorf("Не задано расписание accumulate.schedule или accumulate.every.")
+	}
+	if accCfg.Schedule != "" {
+		schedule, err := parseCron(accCfg.Schedule)
+		if err != nil {
+			return err
+		}
+		e.cron = schedule
+	}
+
+	e.loadLedger(ctx)
+	var ledger AccumulationLedger
+	e.apply(ctx, func() {
+		if e.ledger.ID == "" {
+			e.ledger.ID = newDealID()
+		}
+		if e.ledger.ProcessedExecIDs == nil {
+			e.ledger.ProcessedExecIDs = map[string]bool{}
+		}
+		e.ledger.Symbol = e.cfg.Bot.Symbol
+		ledger = e.ledger
+	})
+	e.saveLedger()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"ledger_id":  ledger.ID,
+		"schedule":   accCfg.Schedule,
+		"every":      accCfg.Every.String(),
+		"amount":     accCfg.QuoteAmount,
+		"order_type": accCfg.OrderType,
+		"total_qty":  ledger.TotalQty,
+		"avg_cost":   ledger.AvgCost,
+		"fills":      ledger.Fills,
+	}).Info("Режим накопления запущен.")
+
+	if err := e.reconcileLedger(ctx); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось сверить журнал накопления с исполнениями.")
+	}
+
+	go e.runAccumulation(ctx)
+	return nil
+}
+
+func (e *Engine) nextAccumulationSlot(now time.Time) time.Time {
+	if e.cron != nil {
+		return e.cron.Next(now)
+	}
+	every := e.cfg.Bot.Accumulate.Every
+	return now.Truncate(every).Add(every)
+}
+
+func (e *Engine) runAccumulation(ctx context.Context) {
+	for {
+		slot := e.nextAccumulationSlot(time.Now())
+		if slot.IsZero() {
+			e.logEntry().Error("Расписание накопления не содержит ближайших запусков.")
+			return
+		}
+		e.logEntry().WithField("next", slot.Format(time.RFC3339)).Info("Следующая покупка по расписанию.")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(slot)):
+		}
+
+		if err := e.accumulateOnce(ctx, slot); err != nil {
+			e.logEntry().WithError(err).Error("Покупка по расписанию не выполнена.")
+		}
+	}
+}
+
+func (e *Engine) accumulateOnce(ctx context.Context, slot time.Time) error {
+	accCfg := e.cfg.Bot.Accumulate
+
+	e.mu.Lock()
+	ledgerID := e.ledger.ID
+	lastSlot := e.ledger.LastSlot
+	e.mu.Unlock()
+	if !lastSlot.Before(slot) {
+		e.logEntry().WithField("slot", slot.Format(time.RFC3339)).Info("Покупка за этот слот уже выполнена.")
+		return nil
+	}
+
+	linkID := fmt.Sprintf("%s-acc-%d", ledgerID, slot.Unix())
+
+	if canceled, err := e.cancelOpenAccumulationOrders(ctx); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось отменить предыдущие лимитные покупки.")
+	} else if canceled > 0 {
+		e.logEntry().WithField("count", canceled).Info("Отменены неисполненные лимитные покупки.")
+	}
+
+	price, err := e.waitForTickerPrice(ctx, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	amount := accCfg.QuoteAmount
+	if accCfg.MAPeriod > 0 && accCfg.BelowMAMultiplier > 0 {
+		if ma, err := e.movingAverage(ctx, accCfg.MAInterval, accCfg.MAPeriod); err != nil {
+			e.logEntry().WithError(err).Warn("Не удалось рассчитать скользящую среднюю, покупка без повышения объёма.")
+		} else if ma > 0 && price < ma {
+			amount = amount * accCfg.BelowMAMultiplier
+			e.logEntry().WithFields(map[string]interface{}{
+				"price":  price,
+				"ma":     ma,
+				"amount": amount,
+			}).Info("Цена ниже скользящей средней, объём покупки увеличен.")
+		}
+	}
+
+	order := models.Order{
+		Symbol: e.cfg.Bot.Symbol,
+		Side:   models.OrderSideBuy,
+		Kind:   models.OrderKindEntry,
+		LinkID: linkID,
+	}
+	if strings.EqualFold(accCfg.OrderType, "limit") {
+		limitPrice := e.roundPrice(price * (1 - accCfg.LimitOffsetPercent/100))
+		if limitPrice <= 0 {
+			return fmt.Errorf("Некорректная цена лимитной покупки: %f", limitPrice)
+		}
+		order.Type = models.OrderTypeLimit
+		order.Price = limitPrice
+		order.Qty = e.roundQty(amount / limitPrice)
+		order.TimeInForce = "GTC"
+		order.PriceStep = e.rules.TickSize
+		order.QtyStep = e.rules.LotSize
+		if order.Qty < e.rules.MinQty {
+			return fmt.Errorf("Объём лимитной покупки меньше минимального: %f", order.Qty)
+		}
+	} else {
+		order.Type = models.OrderTypeMarket
+		order.Qty = amount
+		order.MarketUnit = "quoteCoin"
+		order.TimeInForce = "IOC"
+		order.QtyStep = e.rules.LotSize
+	}
+	if err := e.validateMinNotional(order, price); err != nil {
+		return err
+	}
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"link_id": linkID,
+		"type":    order.Type,
+		"amount":  amount,
+		"price":   order.Price,
+		"qty":     order.Qty,
+		"slot":    slot.Format(time.RFC3339),
+	}).Info("Покупка по расписанию.")
+
+	placed, err := e.placeOrderIdempotent(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	e.apply(ctx, func() {
+		e.ledger.LastSlot = slot
+		e.ledger.UpdatedAt = time.Now()
+	})
+	e.saveLedger()
+	e.log.WithOrderID(placed.ID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("Покупка по расписанию отправлена.")
+
+	if order.Type == models.OrderTypeMarket {
+		if _, _, err := e.waitEntryFill(ctx, linkID); err != nil {
+			return err
+		}
+		return e.reconcileLedger(ctx)
+	}
+	return nil
+}
+
+func (e *Engine) movingAverage(ctx context.Context, interval string, period int) (float64, error) {
+	klines, err := e.withRetryKlines(ctx, e.cfg.Bot.Symbol, interval, period)
+	if err != nil {
+		return 0, err
+	}
+	if len(klines) < period {
+		return 0, fmt.Errorf("Недостаточно свечей для скользящей средней: %d < %d", len(klines), period)
+	}
+	var sum float64
+	for _, kline := range klines[len(klines)-period:] {
+		sum += kline.Close
+	}
+	return sum / float64(period), nil
+}
+
+func (e *Engine) onAccumulationFill(fill models.Fill) {
+	e.mu.Lock()
+	if !strings.HasPrefix(fill.LinkID, e.ledger.ID+"-acc-") || fill.Side != models.OrderSideBuy {
+		e.mu.Unlock()
+		return
+	}
+	if fill.ExecID != "" {
+		if e.ledger.ProcessedExecIDs[fill.ExecID] {
+			e.mu.Unlock()
+			return
+		}
+		e.ledger.ProcessedExecIDs[fill.ExecID] = true
+	}
+	cost := fill.Price * fill.Qty
+	e.ledger.TotalQty += fill.Qty
+	e.ledger.TotalCost += cost
+	e.ledger.AvgCost = CalcAvgPrice(e.ledger.TotalCost, e.ledger.TotalQty)
+	e.ledger.Fills++
+	e.ledger.Entries = append(e.ledger.Entries, LedgerEntry{
+		LinkID:  fill.LinkID,
+		OrderID: fill.OrderID,
+		ExecID:  fill.ExecID,
+		Price:   fill.Price,
+		Qty:     fill.Qty,
+		Cost:    cost,
+		Time:    fill.Timestamp,
+	})
+	e.ledger.UpdatedAt = time.Now()
+	ledger := e.ledger
+	e.mu.Unlock()
+	e.saveLedger()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"link_id":    fill.LinkID,
+		"qty":        fill.Qty,
+		"price":      fill.Price,
+		"total_qty":  ledger.TotalQty,
+		"total_cost": ledger.TotalCost,
+		"avg_cost":   ledger.AvgCost,
+	}).Info("fill accumulate")
+}
+
+func (e *Engine) reconcileLedger(ctx context.Context) error {
+	fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: e.cfg.Bot.Symbol})
+	if err != nil {
+		return err
+	}
+	e.call(ctx, func(ctx context.Context) {
+		for _, fill := range fills {
+			if isAccumulationLinkID(fill.LinkID) {
+				e.onAccumulationFill(fill)
+			}
+		}
+	})
+	return nil
+}
+
+func (e *Engine) cancelOpenAccumulationOrders(ctx context.Context) (int, error) {
+	openOrders, err := e.withRetryOrders(ctx, e.cfg.Bot.Symbol)
+	if err != nil {
+		return 0, err
+	}
+	e.mu.Lock()
+	prefix := e.ledger.ID + "-acc-"
+	e.mu.Unlock()
+
+	canceled := 0
+	for _, ord := range openOrders {
+		if !strings.HasPrefix(ord.LinkID, prefix) || ord.ID == "" {
+			continue
+		}
+		orderID := ord.ID
+		if err := e.withRetryVoid(ctx, func() error {
+			return e.client.CancelOrder(ctx, e.cfg.Bot.Symbol, orderID)
+		}); err != nil && !isOrderNotExistError(err) {
+			return canceled, err
+		}
+		canceled++
+	}
+	return canceled, nil
+}
+
+func (e *Engine) loadLedger(ctx context.Context) {
+	path := e.cfg.Bot.Accumulate.LedgerFile
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			e.logEntry().WithError(err).Warn("Не удалось прочитать журнал накопления.")
+		}
+		return
+	}
+	var ledger AccumulationLedger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось разобрать журнал накопления.")
+		return
+	}
+	e.apply(ctx, func() {
+		e.ledger = ledger
+	})
+}
+
+func (e *Engine) saveLedger() {
+	path := e.cfg.Bot.Accumulate.LedgerFile
+	if path == "" {
+		return
+	}
+	e.mu.Lock()
+	e.ledgerSeq++
+	seq := e.ledgerSeq
+	data, err := json.MarshalIndent(e.ledger, "", "  ")
+	e.mu.Unlock()
+	if err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось подготовить журнал накопления.")
+		return
+	}
+	if !e.ledgerSaver.save(seq, data) {
+		e.writeLedger(data)
+	}
+}
+
+func (e *Engine) writeLedger(data []byte) {
+	if err := writeFileAtomic(e.cfg.Bot.Accumulate.LedgerFile, data); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось сохранить журнал накопления.")
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"dcabot/internal/models"
+	"testing"
+)
+
+func TestAccumulationLedgerAvgCost(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	e.ledger = AccumulationLedger{ID: "L", ProcessedExecIDs: map[string]bool{}}
+
+	tests := []struct {
+		name      string
+		fill      models.Fill
+		totalQty  float64
+		totalCost float64
+		avgCost   float64
+		fills     int
+	}{
+		{
+			name:     "first buy",
+			fill:     models.Fill{LinkID: "L-acc-1", ExecID: "e1", Side: models.OrderSideBuy, Price: 100, Qty: 1},
+			totalQty: 1, totalCost: 100, avgCost: 100, fills: 1,
+		},
+		{
+			name:     "cheaper buy lowers average",
+			fill:     models.Fill{LinkID: "L-acc-2", ExecID: "e2", Side: models.OrderSideBuy, Price: 80, Qty: 1.5},
+			totalQty: 2.5, totalCost: 220, avgCost: 88, fills: 2,
+		},
+		{
+			name:     "replayed exec id ignored",
+			fill:     models.Fill{LinkID: "L-acc-1", ExecID: "e1", Side: models.OrderSideBuy, Price: 100, Qty: 1},
+			totalQty: 2.5, totalCost: 220, avgCost: 88, fills: 2,
+		},
+		{
+			name:     "sell ignored",
+			fill:     models.Fill{LinkID: "L-acc-3", ExecID: "e3", Side: models.OrderSideSell, Price: 120, Qty: 1},
+			totalQty: 2.5, totalCost: 220, avgCost: 88, fills: 2,
+		},
+		{
+			name:     "other ledger ignored",
+			fill:     models.Fill{LinkID: "X-acc-1", ExecID: "e4", Side: models.OrderSideBuy, Price: 50, Qty: 1},
+			totalQty: 2.5, totalCost: 220, avgCost: 88, fills: 2,
+		},
+		{
+			name:     "partial fill of limit buy",
+			fill:     models.Fill{LinkID: "L-acc-5", ExecID: "e5", Side: models.OrderSideBuy, Price: 90, Qty: 0.5},
+			totalQty: 3, totalCost: 265, avgCost: 265.0 / 3, fills: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e.onAccumulationFill(tt.fill)
+			ledger := e.ledger
+			if !approxEqual(ledger.TotalQty, tt.totalQty) || !approxEqual(ledger.TotalCost, tt.totalCost) ||
+				!approxEqual(ledger.AvgCost, tt.avgCost) || ledger.Fills != tt.fills {
+				t.Fatalf("журнал qty=%v cost=%v avg=%v fills=%d, ожидалось qty=%v cost=%v avg=%v fills=%d",
+					ledger.TotalQty, ledger.TotalCost, ledger.AvgCost, ledger.Fills, tt.totalQty, tt.totalCost, tt.avgCost, tt.fills)
+			}
+			if len(ledger.Entries) != tt.fills {
+				t.Fatalf("в журнале %d записей, ожидалось %d", len(ledger.Entries), tt.fills)
+			}
+		})
+	}
+}
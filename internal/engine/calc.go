@@ -5,6 +5,12 @@ import (
 	"math"
 )
 
+const (
+	soAnchorEntry    = "entry"
+	soAnchorLastFill = "last_fill"
+	soAnchorAvgPrice = "avg_price"
+)
+
 func CalcAvgPrice(totalCost, totalQty float64) float64 {
 	if totalQty == 0 {
 		return 0
@@ -28,30 +34,53 @@ func RoundDown(value, step float64) float64 {
 	return math.Floor(value/step) * step
 }
 
+func CalcGridLevels(lower, upper float64, count int, geometric bool) []float64 {
+	if count < 2 || lower <= 0 || upper <= lower {
+		return nil
+	}
+	levels := make([]float64, count)
+	ratio := math.Pow(upper/lower, 1/float64(count-1))
+	step := (upper - lower) / float64(count-1)
+	for i := 0; i < count; i++ {
+		if geometric {
+			levels[i] = lower * math.Pow(ratio, float64(i))
+		} else {
+			levels[i] = lower + step*float64(i)
+		}
+	}
+	return levels
+}
+
 type SafetyOrder struct {
+	Level        int
 	Price        float64
 	Qty          float64
 	TotalPercent float64
 }
 
 func CalcSafetyOrders(entryPrice float64, soCount int, soStepPercent, soStepMultiplier, soBaseQty, soQtyMultiplier float64, side models.OrderSide) []SafetyOrder {
+	return CalcSafetyOrdersFrom(entryPrice, 0, soCount, soStepPercent, soStepMultiplier, soBaseQty, soQtyMultiplier, side)
+}
+
+func CalcSafetyOrdersFrom(anchorPrice float64, fromLevel, soCount int, soStepPercent, soStepMultiplier, soBaseQty, soQtyMultiplier float64, side models.OrderSide) []SafetyOrder {
 	var orders []SafetyOrder
 
 	totalPercent := 0.0
 
-	for i := 0; i < soCount; i++ {
+	for i := fromLevel; i < soCount; i++ {
 		step := soStepPercent * math.Pow(soStepMultiplier, float64(i))
 		totalPercent += step
 		qty := soBaseQty * math.Pow(soQtyMultiplier, float64(i))
-		price := entryPrice
+		price := anchorPrice
 
 		if side == models.OrderSideBuy {
-			price = entryPrice * (1 - totalPercent/100.0)
+			price = anchorPrice * (1 - totalPercent/100.0)
 		} else {
-			price = entryPrice * (1 + totalPercent/100.0)
+			price = anchorPrice * (1 + totalPercent/100.0)
 		}
 
 		orders = append(orders, SafetyOrder{
+			Level:        i + 1,
 			Price:        price,
 			Qty:          qty,
 			TotalPercent: totalPercent,
@@ -59,3 +88,61 @@ func CalcSafetyOrders(entryPrice float64, soCount int, soStepPercent, soStepMult
 	}
 	return orders
 }
+
+func CalcATRPercent(klines []models.Kline, period int) float64 {
+	if period <= 0 || len(klines) < period+1 {
+		return 0
+	}
+	window := klines[len(klines)-period-1:]
+	var sum float64
+	for i := 1; i < len(window); i++ {
+		prevClose := window[i-1].Close
+		tr := window[i].High - window[i].Low
+		tr = math.Max(tr, math.Abs(window[i].High-prevClose))
+		tr = math.Max(tr, math.Abs(window[i].Low-prevClose))
+		sum += tr
+	}
+	lastClose := window[len(window)-1].Close
+	if lastClose <= 0 {
+		return 0
+	}
+	return sum / float64(period) / lastClose * 100
+}
+
+func CalcRealizedVolatilityPercent(klines []models.Kline, period int) float64 {
+	if period <= 1 || len(klines) < period+1 {
+		return 0
+	}
+	window := klines[len(klines)-period-1:]
+	returns := make([]float64, 0, period)
+	for i := 1; i < len(window); i++ {
+		if window[i-1].Close <= 0 || window[i].Close <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(window[i].Close/window[i-1].Close))
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	return math.Sqrt(variance) * 100
+}
+
+func ClampPercent(value, minPercent, maxPercent float64) float64 {
+	if minPercent > 0 && value < minPercent {
+		value = minPercent
+	}
+	if maxPercent > 0 && value > maxPercent {
+		value = maxPercent
+	}
+	return value
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"dcabot/internal/models"
+	"math"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCalcSafetyOrdersFrom(t *testing.T) {
+	tests := []struct {
+		name           string
+		anchorPrice    float64
+		fromLevel      int
+		soCount        int
+		stepPercent    float64
+		stepMultiplier float64
+		baseQty        float64
+		qtyMultiplier  float64
+		side           models.OrderSide
+		want           []SafetyOrder
+	}{
+		{
+			name: "buy from entry", anchorPrice: 100, soCount: 3, stepPercent: 1, stepMultiplier: 1, baseQty: 1, qtyMultiplier: 1,
+			side: models.OrderSideBuy,
+			want: []SafetyOrder{{Level: 1, Price: 99, Qty: 1, TotalPercent: 1}, {Level: 2, Price: 98, Qty: 1, TotalPercent: 2}, {Level: 3, Price: 97, Qty: 1, TotalPercent: 3}},
+		},
+		{
+			name: "sell from entry", anchorPrice: 100, soCount: 2, stepPercent: 1, stepMultiplier: 1, baseQty: 1, qtyMultiplier: 1,
+			side: models.OrderSideSell,
+			want: []SafetyOrder{{Level: 1, Price: 101, Qty: 1, TotalPercent: 1}, {Level: 2, Price: 102, Qty: 1, TotalPercent: 2}},
+		},
+		{
+			name: "step and qty multipliers", anchorPrice: 100, soCount: 3, stepPercent: 1, stepMultiplier: 2, baseQty: 1, qtyMultiplier: 2,
+			side: models.OrderSideBuy,
+			want: []SafetyOrder{{Level: 1, Price: 99, Qty: 1, TotalPercent: 1}, {Level: 2, Price: 97, Qty: 2, TotalPercent: 3}, {Level: 3, Price: 93, Qty: 4, TotalPercent: 7}},
+		},
+		{
+			name: "remaining grid from anchor level", anchorPrice: 90, fromLevel: 2, soCount: 4, stepPercent: 1, stepMultiplier: 2, baseQty: 1, qtyMultiplier: 2,
+			side: models.OrderSideBuy,
+			want: []SafetyOrder{{Level: 3, Price: 86.4, Qty: 4, TotalPercent: 4}, {Level: 4, Price: 79.2, Qty: 8, TotalPercent: 12}},
+		},
+		{
+			name: "anchor at last level", anchorPrice: 90, fromLevel: 4, soCount: 4, stepPercent: 1, stepMultiplier: 1, baseQty: 1, qtyMultiplier: 1,
+			side: models.OrderSideBuy, want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalcSafetyOrdersFrom(tt.anchorPrice, tt.fromLevel, tt.soCount, tt.stepPercent, tt.stepMultiplier, tt.baseQty, tt.qtyMultiplier, tt.side)
+			if len(got) != len(tt.want) {
+				t.Fatalf("получено %d уровней, ожидалось %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				so := got[i]
+				if so.Level != want.Level || !approxEqual(so.Price, want.Price) || !approxEqual(so.Qty, want.Qty) || !approxEqual(so.TotalPercent, want.TotalPercent) {
+					t.Fatalf("уровень %d = %+v, ожидалось %+v", i, so, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCalcATRPercent(t *testing.T) {
+	tests := []struct {
+		name   string
+		klines []models.Kline
+		period int
+		want   float64
+	}{
+		{
+			name:   "high-low range",
+			klines: []models.Kline{{High: 101, Low: 99, Close: 100}, {High: 102, Low: 98, Close: 100}, {High: 103, Low: 99, Close: 102}},
+			period: 2,
+			want:   8.0 / 2 / 102 * 100,
+		},
+		{
+			name:   "gap from previous close",
+			klines: []models.Kline{{High: 101, Low: 99, Close: 100}, {High: 110, Low: 108, Close: 109}},
+			period: 1,
+			want:   10.0 / 109 * 100,
+		},
+		{
+			name:   "only last period bars",
+			klines: []models.Kline{{High: 200, Low: 50, Close: 100}, {High: 101, Low: 99, Close: 100}, {High: 102, Low: 98, Close: 100}},
+			period: 1,
+			want:   4.0 / 100 * 100,
+		},
+		{
+			name:   "not enough klines",
+			klines: []models.Kline{{High: 101, Low: 99, Close: 100}, {High: 102, Low: 98, Close: 100}},
+			period: 2,
+		},
+		{
+			name:   "zero period",
+			klines: []models.Kline{{High: 101, Low: 99, Close: 100}},
+		},
+		{
+			name:   "zero last close",
+			klines: []models.Kline{{High: 101, Low: 99, Close: 100}, {High: 102, Low: 98, Close: 0}},
+			period: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalcATRPercent(tt.klines, tt.period); !approxEqual(got, tt.want) {
+				t.Fatalf("CalcATRPercent = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcRealizedVolatilityPercent(t *testing.T) {
+	closes := func(values ...float64) []models.Kline {
+		klines := make([]models.Kline, len(values))
+		for i, value := range values {
+			klines[i] = models.Kline{Close: value}
+		}
+		return klines
+	}
+
+	tests := []struct {
+		name   string
+		klines []models.Kline
+		period int
+		want   float64
+	}{
+		{name: "constant growth", klines: closes(100, 110, 121), period: 2, want: 0},
+		{name: "up and down", klines: closes(100, 110, 100), period: 2, want: math.Log(1.1) * math.Sqrt2 * 100},
+		{name: "only last period bars", klines: closes(50, 100, 110, 100), period: 2, want: math.Log(1.1) * math.Sqrt2 * 100},
+		{name: "period one", klines: closes(100, 110), period: 1},
+		{name: "not enough klines", klines: closes(100, 110), period: 2},
+		{name: "zero close skipped", klines: closes(100, 0, 110), period: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalcRealizedVolatilityPercent(tt.klines, tt.period); !approxEqual(got, tt.want) {
+				t.Fatalf("CalcRealizedVolatilityPercent = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampPercent(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		min, max float64
+		want     float64
+	}{
+		{name: "inside range", value: 1.5, min: 0.5, max: 3, want: 1.5},
+		{name: "below min", value: 0.2, min: 0.5, max: 3, want: 0.5},
+		{name: "above max", value: 5, min: 0.5, max: 3, want: 3},
+		{name: "no limits", value: 5, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClampPercent(tt.value, tt.min, tt.max); got != tt.want {
+				t.Fatalf("ClampPercent = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInferSOStepPercent(t *testing.T) {
+	tests := []struct {
+		name           string
+		entryPrice     float64
+		price          float64
+		level          int
+		stepMultiplier float64
+		side           models.OrderSide
+		want           float64
+	}{
+		{name: "buy flat steps", entryPrice: 100, price: 97, level: 3, stepMultiplier: 1, side: models.OrderSideBuy, want: 1},
+		{name: "buy growing steps", entryPrice: 100, price: 93, level: 3, stepMultiplier: 2, side: models.OrderSideBuy, want: 1},
+		{name: "sell flat steps", entryPrice: 100, price: 103, level: 3, stepMultiplier: 1, side: models.OrderSideSell, want: 1},
+		{name: "price on wrong side", entryPrice: 100, price: 101, level: 1, stepMultiplier: 1, side: models.OrderSideBuy},
+		{name: "no level", entryPrice: 100, price: 99, level: 0, stepMultiplier: 1, side: models.OrderSideBuy},
+		{name: "no entry", price: 99, level: 1, stepMultiplier: 1, side: models.OrderSideBuy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inferSOStepPercent(tt.entryPrice, tt.price, tt.level, tt.stepMultiplier, tt.side)
+			if !approxEqual(got, tt.want) {
+				t.Fatalf("inferSOStepPercent = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"dcabot/internal/config"
+	"dcabot/internal/models"
+	"fmt"
+	"math"
+	"strings"
+)
+
+type dcaStrategy struct {
+	bot config.BotConfig
+}
+
+func newDCAStrategy(cfg *config.Config) *dcaStrategy {
+	return &dcaStrategy{bot: cfg.Bot}
+}
+
+func (s *dcaStrategy) Name() string {
+	return strategyDCA
+}
+
+func (s *dcaStrategy) OnEvent(event StrategyEvent, deal DealView) []Intent {
+	if !deal.Active {
+		return nil
+	}
+	switch event.Type {
+	case StrategyEventFill:
+		if event.Fill == nil {
+			return nil
+		}
+		return s.onFill(*event.Fill, deal)
+	case StrategyEventOrder:
+		if event.Order == nil {
+			return nil
+		}
+		order := *event.Order
+		isTP := (deal.TPOrderID != "" && order.ID == deal.TPOrderID) || (deal.TPLinkID != "" && order.LinkID == deal.TPLinkID)
+		if isTP && order.Status == models.OrderStatusFilled && isQtyZeroFor(deal.TotalQty+deal.CarriedDust, deal.Rules.LotSize) {
+			return []Intent{{Type: IntentClose, Reason: "TP полностью исполнен (order status)."}}
+		}
+	}
+	return nil
+}
+
+func (s *dcaStrategy) onFill(fill models.Fill, deal DealView) []Intent {
+	if isTPLinkID(fill.LinkID) || deal.TPLinkID == fill.LinkID || (deal.TPOrderID != "" && deal.TPOrderID == fill.OrderID) {
+		if isQtyZeroFor(deal.TotalQty+deal.CarriedDust, deal.Rules.LotSize) {
+			return []Intent{{Type: IntentClose, Reason: "TP полностью исполнен."}}
+		}
+		return nil
+	}
+	if fill.Side != deal.Side {
+		return nil
+	}
+
+	intents := []Intent{{
+		Type: IntentAmend,
+		Order: models.Order{
+			ID:     deal.TPOrderID,
+			LinkID: deal.TPLinkID,
+			Kind:   models.OrderKindTP,
+			Side:   oppositeSide(deal.Side),
+			Price:  RoundDown(CalcTPPrice(deal.AvgPrice, s.bot.TPPercent, deal.Side), deal.Rules.TickSize),
+			Qty:    RoundDown(deal.TotalQty+deal.CarriedDust, deal.Rules.LotSize),
+		},
+		Reason: "Исполнен ордер -- перестановка TP.",
+	}}
+
+	if isSafetyLinkID(fill.LinkID) && deal.FilledByLink[fill.LinkID] <= fill.Qty {
+		intents = append(intents, s.reconcileSafety(deal)...)
+	}
+	return intents
+}
+
+func (s *dcaStrategy) reconcileSafety(deal DealView) []Intent {
+	planned := PlanSafetyOrders(deal, s.bot)
+	_, liveTo := SafetyWindow(deal.FilledByLink, s.bot.SOCount, s.bot.MaxActiveSafetyOrders)
+
+	expected := make(map[string]bool, len(planned))
+	var intents []Intent
+	for _, order := range planned {
+		expected[order.LinkID] = true
+		level, _ := safetyLevelFromLinkID(order.LinkID)
+		if level > liveTo || deal.FilledByLink[order.LinkID] > 0 {
+			continue
+		}
+		if orderID := deal.SafetyOrders[order.LinkID]; orderID != "" {
+			continue
+		}
+		if order.Price <= 0 || order.Qty < deal.Rules.MinQty {
+			continue
+		}
+		intents = append(intents, Intent{Type: IntentPlace, Order: order, Reason: "Следующий уровень сетки страховочных ордеров."})
+	}
+
+	var cancels []Intent
+	for linkID, orderID := range deal.SafetyOrders {
+		if expected[linkID] || orderID == "" || deal.FilledByLink[linkID] > 0 {
+			continue
+		}
+		cancels = append(cancels, Intent{
+			Type:   IntentCancel,
+			Order:  models.Order{ID: orderID, LinkID: linkID, Kind: models.OrderKindSafety},
+			Reason: "Страховочный ордер не соответствует текущей сетке.",
+		})
+	}
+	return append(cancels, intents...)
+}
+
+func normalizeSOAnchor(anchor string) string {
+	switch strings.ToLower(strings.TrimSpace(anchor)) {
+	case soAnchorLastFill:
+		return soAnchorLastFill
+	case soAnchorAvgPrice:
+		return soAnchorAvgPrice
+	default:
+		return soAnchorEntry
+	}
+}
+
+func normalizeQtyUnit(unit string) string {
+	if strings.EqualFold(strings.TrimSpace(unit), "quoteCoin") {
+		return "quoteCoin"
+	}
+	return "baseCoin"
+}
+
+func isQtyZeroFor(qty, lotSize float64) bool {
+	threshold := lotSize / 2
+	if threshold <= 0 {
+		threshold = 1e-9
+	}
+	return qty <= threshold
+}
+
+func SafetyWindow(filledByLink map[string]float64, soCount, maxActive int) (int, int) {
+	lastFilled := 0
+	for linkID, qty := range filledByLink {
+		if qty <= 0 {
+			continue
+		}
+		if level, ok := safetyLevelFromLinkID(linkID); ok && level > lastFilled {
+			lastFilled = level
+		}
+	}
+	liveTo := soCount
+	if maxActive > 0 && lastFilled+maxActive < soCount {
+		liveTo = lastFilled + maxActive
+	}
+	return lastFilled, liveTo
+}
+
+func safetyGridFor(deal DealView, bot config.BotConfig) ([]SafetyOrder, float64) {
+	anchorPrice := deal.EntryPrice
+	anchorLevel := deal.GridAnchorLevel
+	if anchorLevel > 0 && deal.GridAnchorPrice > 0 {
+		anchorPrice = deal.GridAnchorPrice
+	} else {
+		anchorLevel = 0
+	}
+	stepPercent := deal.SOStepPercent
+	if stepPercent <= 0 {
+		stepPercent = bot.SOStepPercent
+	}
+	sizeMultiplier := deal.SizeMultiplier
+	if sizeMultiplier <= 0 {
+		sizeMultiplier = 1
+	}
+	return CalcSafetyOrdersFrom(anchorPrice, anchorLevel, bot.SOCount, stepPercent, bot.SOStepMultiplier, bot.SOBaseQty*sizeMultiplier, bot.SOQtyMultiplier, deal.Side), anchorPrice
+}
+
+func safetyLinkIDFor(dealID string, level, anchorLevel int) string {
+	if anchorLevel > 0 {
+		return fmt.Sprintf("%s-so-%d-a%d", dealID, level, anchorLevel)
+	}
+	return fmt.Sprintf("%s-so-%d", dealID, level)
+}
+
+func PlanSafetyOrders(deal DealView, bot config.BotConfig) []models.Order {
+	grid, _ := safetyGridFor(deal, bot)
+	quoteUnit := normalizeQtyUnit(bot.QtyUnit) == "quoteCoin"
+	anchorLevel := deal.GridAnchorLevel
+	if deal.GridAnchorPrice <= 0 {
+		anchorLevel = 0
+	}
+
+	orders := make([]models.Order, 0, len(grid))
+	for _, so := range grid {
+		price := RoundDown(so.Price, deal.Rules.TickSize)
+		qty := so.Qty
+		if quoteUnit {
+			if price <= 0 {
+				qty = 0
+			} else {
+				qty = qty / price
+			}
+		}
+		qty = RoundDown(qty, deal.Rules.LotSize)
+		orders = append(orders, models.Order{
+			Symbol:      deal.Symbol,
+			Side:        deal.Side,
+			Type:        models.OrderTypeLimit,
+			Kind:        models.OrderKindSafety,
+			Price:       math.Max(price, 0),
+			Qty:         qty,
+			LinkID:      safetyLinkIDFor(deal.DealID, so.Level, anchorLevel),
+			TimeInForce: "GTC",
+			PriceStep:   deal.Rules.TickSize,
+			QtyStep:     deal.Rules.LotSize,
+		})
+	}
+	return orders
+}
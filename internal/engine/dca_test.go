@@ -0,0 +1,282 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafetyWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		filled     map[string]float64
+		soCount    int
+		maxActive  int
+		lastFilled int
+		liveTo     int
+	}{
+		{name: "whole grid without limit", filled: nil, soCount: 5, maxActive: 0, lastFilled: 0, liveTo: 5},
+		{name: "first window", filled: map[string]float64{"d-entry": 1}, soCount: 5, maxActive: 2, lastFilled: 0, liveTo: 2},
+		{name: "slides after fill", filled: map[string]float64{"d-so-1": 1, "d-so-2": 1}, soCount: 5, maxActive: 2, lastFilled: 2, liveTo: 4},
+		{name: "highest filled level wins", filled: map[string]float64{"d-so-3": 1, "d-so-1": 1}, soCount: 6, maxActive: 1, lastFilled: 3, liveTo: 4},
+		{name: "capped by grid size", filled: map[string]float64{"d-so-4": 1}, soCount: 5, maxActive: 3, lastFilled: 4, liveTo: 5},
+		{name: "anchored link id", filled: map[string]float64{"d-so-3-a2": 0.5}, soCount: 5, maxActive: 1, lastFilled: 3, liveTo: 4},
+		{name: "zero qty ignored", filled: map[string]float64{"d-so-3": 0}, soCount: 5, maxActive: 1, lastFilled: 0, liveTo: 1},
+		{name: "tp and entry ignored", filled: map[string]float64{"d-tp-1700000000-1": 1, "d-entry": 1}, soCount: 5, maxActive: 2, lastFilled: 0, liveTo: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastFilled, liveTo := SafetyWindow(tt.filled, tt.soCount, tt.maxActive)
+			if lastFilled != tt.lastFilled || liveTo != tt.liveTo {
+				t.Fatalf("SafetyWindow = (%d, %d), ожидалось (%d, %d)", lastFilled, liveTo, tt.lastFilled, tt.liveTo)
+			}
+		})
+	}
+}
+
+func TestSafetyLevelFromLinkID(t *testing.T) {
+	tests := []struct {
+		linkID string
+		level  int
+		ok     bool
+	}{
+		{linkID: "abc-so-1", level: 1, ok: true},
+		{linkID: "abc-so-12", level: 12, ok: true},
+		{linkID: "abc-so-3-a2", level: 3, ok: true},
+		{linkID: "abc-so-0", ok: false},
+		{linkID: "abc-so-x", ok: false},
+		{linkID: "abc-tp-1700000000-1", ok: false},
+		{linkID: "abc-entry", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkID, func(t *testing.T) {
+			level, ok := safetyLevelFromLinkID(tt.linkID)
+			if level != tt.level || ok != tt.ok {
+				t.Fatalf("safetyLevelFromLinkID = (%d, %v), ожидалось (%d, %v)", level, ok, tt.level, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsSafetyLevelLive(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	e.state = DealState{
+		FilledByLink: map[string]float64{"d-so-1": 1},
+		Orders:       map[string]OrderRecord{"d-so-3": {Skipped: true}},
+	}
+
+	tests := []struct {
+		name   string
+		linkID string
+		level  int
+		liveTo int
+		want   bool
+	}{
+		{name: "inside window", linkID: "d-so-2", level: 2, liveTo: 3, want: true},
+		{name: "beyond window", linkID: "d-so-4", level: 4, liveTo: 3, want: false},
+		{name: "already filled", linkID: "d-so-1", level: 1, liveTo: 3, want: false},
+		{name: "skipped", linkID: "d-so-3", level: 3, liveTo: 3, want: false},
+		{name: "no level", linkID: "d-so-0", level: 0, liveTo: 3, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.isSafetyLevelLive(tt.linkID, tt.level, tt.liveTo); got != tt.want {
+				t.Fatalf("isSafetyLevelLive = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafetyGridFor(t *testing.T) {
+	bot := newTestEngine(t, newFakeClient()).cfg.Bot
+	bot.SOCount = 4
+
+	tests := []struct {
+		name        string
+		deal        DealView
+		anchorPrice float64
+		levels      []int
+		prices      []float64
+		qty         float64
+	}{
+		{
+			name:        "entry anchor",
+			deal:        DealView{Side: models.OrderSideBuy, EntryPrice: 100},
+			anchorPrice: 100, levels: []int{1, 2, 3, 4}, prices: []float64{99, 98, 97, 96}, qty: 1,
+		},
+		{
+			name:        "re-anchored after level 2",
+			deal:        DealView{Side: models.OrderSideBuy, EntryPrice: 100, GridAnchorLevel: 2, GridAnchorPrice: 95},
+			anchorPrice: 95, levels: []int{3, 4}, prices: []float64{94.05, 93.1}, qty: 1,
+		},
+		{
+			name:        "anchor level without price",
+			deal:        DealView{Side: models.OrderSideBuy, EntryPrice: 100, GridAnchorLevel: 2},
+			anchorPrice: 100, levels: []int{1, 2, 3, 4}, prices: []float64{99, 98, 97, 96}, qty: 1,
+		},
+		{
+			name:        "deal step and size multiplier",
+			deal:        DealView{Side: models.OrderSideBuy, EntryPrice: 100, SOStepPercent: 2, SizeMultiplier: 1.5},
+			anchorPrice: 100, levels: []int{1, 2, 3, 4}, prices: []float64{98, 96, 94, 92}, qty: 1.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid, anchorPrice := safetyGridFor(tt.deal, bot)
+			if anchorPrice != tt.anchorPrice {
+				t.Fatalf("anchor price = %v, ожидалось %v", anchorPrice, tt.anchorPrice)
+			}
+			if len(grid) != len(tt.levels) {
+				t.Fatalf("получено %d уровней, ожидалось %d", len(grid), len(tt.levels))
+			}
+			for i, so := range grid {
+				if so.Level != tt.levels[i] || !approxEqual(so.Price, tt.prices[i]) || !approxEqual(so.Qty, tt.qty) {
+					t.Fatalf("уровень %d = %+v, ожидалось level=%d price=%v qty=%v", i, so, tt.levels[i], tt.prices[i], tt.qty)
+				}
+			}
+		})
+	}
+}
+
+func TestSafetyLinkIDAnchor(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       int
+		anchorLevel int
+		want        string
+	}{
+		{name: "entry grid", level: 3, anchorLevel: 0, want: "d1-so-3"},
+		{name: "re-anchored grid", level: 3, anchorLevel: 2, want: "d1-so-3-a2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkID := safetyLinkIDFor("d1", tt.level, tt.anchorLevel)
+			if linkID != tt.want {
+				t.Fatalf("safetyLinkIDFor = %q, ожидалось %q", linkID, tt.want)
+			}
+			if level, ok := safetyLevelFromLinkID(linkID); !ok || level != tt.level {
+				t.Fatalf("уровень из %q = %d, ожидался %d", linkID, level, tt.level)
+			}
+			if anchor := safetyAnchorFromLinkID(linkID); anchor != tt.anchorLevel {
+				t.Fatalf("якорь из %q = %d, ожидался %d", linkID, anchor, tt.anchorLevel)
+			}
+		})
+	}
+}
+
+func TestGridAnchorFromFills(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	fills := []models.Fill{
+		{LinkID: "d-so-2", Side: models.OrderSideBuy, Price: 98, Qty: 2, Timestamp: start.Add(2 * time.Minute)},
+		{LinkID: "d-entry", Side: models.OrderSideBuy, Price: 100, Qty: 1, Timestamp: start},
+		{LinkID: "d-so-1", Side: models.OrderSideBuy, Price: 99, Qty: 1, Timestamp: start.Add(time.Minute)},
+		{LinkID: "d-tp-1700000000-1", Side: models.OrderSideSell, Price: 101, Qty: 1, Timestamp: start.Add(3 * time.Minute)},
+	}
+
+	tests := []struct {
+		anchor string
+		level  int
+		price  float64
+	}{
+		{anchor: soAnchorEntry, level: 0, price: 0},
+		{anchor: soAnchorLastFill, level: 2, price: 98},
+		{anchor: soAnchorAvgPrice, level: 2, price: 98.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.anchor, func(t *testing.T) {
+			e := newTestEngine(t, newFakeClient())
+			e.cfg.Bot.SOAnchor = tt.anchor
+			level, price := e.gridAnchorFromFills(fills, models.OrderSideBuy)
+			if level != tt.level || !approxEqual(price, tt.price) {
+				t.Fatalf("gridAnchorFromFills = (%d, %v), ожидалось (%d, %v)", level, price, tt.level, tt.price)
+			}
+		})
+	}
+}
+
+func TestDCAStrategyIntents(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	rules, _ := e.client.GetInstrumentRules(context.Background(), fakeSymbol)
+	strategy := newDCAStrategy(e.cfg)
+
+	const tpLinkID = "d-tp-1700000000-1"
+	deal := func(edit func(deal *DealView)) DealView {
+		view := DealView{
+			Active:       true,
+			DealID:       "d",
+			Symbol:       fakeSymbol,
+			Side:         models.OrderSideBuy,
+			EntryPrice:   100,
+			AvgPrice:     99.5,
+			TotalQty:     2,
+			TPOrderID:    "tp1",
+			TPLinkID:     tpLinkID,
+			SafetyOrders: map[string]string{"d-so-2": "o2", "d-so-3": "o3", "d-so-4": "o4"},
+			FilledByLink: map[string]float64{"d-entry": 1, "d-so-1": 1},
+			Rules:        rules,
+		}
+		if edit != nil {
+			edit(&view)
+		}
+		return view
+	}
+	fill := func(linkID, orderID string, side models.OrderSide, qty float64) StrategyEvent {
+		return StrategyEvent{Type: StrategyEventFill, Fill: &models.Fill{LinkID: linkID, OrderID: orderID, Side: side, Price: 99, Qty: qty}}
+	}
+	order := func(id, linkID string, status models.OrderStatus) StrategyEvent {
+		return StrategyEvent{Type: StrategyEventOrder, Order: &models.Order{ID: id, LinkID: linkID, Status: status}}
+	}
+	closed := func(view *DealView) { view.TotalQty = 0 }
+
+	tests := []struct {
+		name  string
+		event StrategyEvent
+		deal  DealView
+		want  []string
+	}{
+		{name: "inactive deal", event: fill("d-so-1", "o1", models.OrderSideBuy, 1), deal: deal(func(view *DealView) { view.Active = false })},
+		{name: "ticker", event: StrategyEvent{Type: StrategyEventTicker, Ticker: &models.Ticker{LastPrice: 90}}, deal: deal(nil)},
+		{name: "timer", event: StrategyEvent{Type: StrategyEventTimer}, deal: deal(nil)},
+		{name: "reconnect", event: StrategyEvent{Type: StrategyEventReconnect}, deal: deal(nil)},
+		{name: "fill without payload", event: StrategyEvent{Type: StrategyEventFill}, deal: deal(nil)},
+		{name: "entry fill moves tp", event: fill("d-entry", "e1", models.OrderSideBuy, 1), deal: deal(nil), want: []string{"Amend:" + tpLinkID}},
+		{name: "safety fill moves tp", event: fill("d-so-1", "o1", models.OrderSideBuy, 1), deal: deal(nil), want: []string{"Amend:" + tpLinkID}},
+		{
+			name:  "first safety fill refills grid",
+			event: fill("d-so-1", "o1", models.OrderSideBuy, 1),
+			deal: deal(func(view *DealView) {
+				view.SafetyOrders = map[string]string{"d-so-3": "o3", "d-so-9": "o9"}
+			}),
+			want: []string{"Amend:" + tpLinkID, "Cancel:d-so-9", "Place:d-so-2", "Place:d-so-4"},
+		},
+		{
+			name:  "later partial fill skips grid",
+			event: fill("d-so-1", "o1", models.OrderSideBuy, 0.4),
+			deal: deal(func(view *DealView) {
+				view.SafetyOrders = map[string]string{}
+			}),
+			want: []string{"Amend:" + tpLinkID},
+		},
+		{name: "opposite side fill", event: fill("manual", "m1", models.OrderSideSell, 1), deal: deal(nil)},
+		{name: "tp partial fill", event: fill(tpLinkID, "tp1", models.OrderSideSell, 1), deal: deal(nil)},
+		{name: "tp full fill", event: fill(tpLinkID, "tp1", models.OrderSideSell, 2), deal: deal(closed), want: []string{"Close:"}},
+		{name: "tp fill by order id", event: fill("foreign", "tp1", models.OrderSideSell, 2), deal: deal(closed), want: []string{"Close:"}},
+		{name: "tp order filled", event: order("tp1", tpLinkID, models.OrderStatusFilled), deal: deal(closed), want: []string{"Close:"}},
+		{name: "tp order filled with qty left", event: order("tp1", tpLinkID, models.OrderStatusFilled), deal: deal(nil)},
+		{name: "tp order partially filled", event: order("tp1", tpLinkID, models.OrderStatusPartiallyFilled), deal: deal(closed)},
+		{name: "safety order filled", event: order("o2", "d-so-2", models.OrderStatusFilled), deal: deal(closed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, intent := range strategy.OnEvent(tt.event, tt.deal) {
+				got = append(got, string(intent.Type)+":"+intent.Order.LinkID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("решения стратегии %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
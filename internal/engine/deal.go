@@ -2,19 +2,27 @@ package engine
 
 import (
 	"context"
+	"dcabot/internal/exchange"
 	"dcabot/internal/models"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 )
 
 func (e *Engine) openDeal(ctx context.Context) error {
-	if e.state.Active {
+	active := false
+	e.apply(ctx, func() {
+		if active = e.state.Active; active {
+			return
+		}
+		e.ensureDealID()
+		e.ensureStateMaps()
+	})
+	if active {
 		return nil
 	}
 
-	e.ensureDealID()
-	e.ensureStateMaps()
 	side, err := normalizeSide(e.cfg.Bot.Side)
 	if err != nil {
 		return err
@@ -22,7 +30,8 @@ func (e *Engine) openDeal(ctx context.Context) error {
 	entryLinkID := e.linkID("entry")
 	qtyUnit := e.qtyUnit()
 
-	entryQty := e.cfg.Bot.BaseOrderQty
+	sizeMultiplier := e.sizeMultiplier()
+	entryQty := e.cfg.Bot.BaseOrderQty * sizeMultiplier
 	if !strings.EqualFold(qtyUnit, "quoteCoin") {
 		entryQty = e.roundQty(entryQty)
 	}
@@ -43,7 +52,7 @@ func (e *Engine) openDeal(ctx context.Context) error {
 		return fmt.Errorf("Объём входа меньше минимального: %f", entryOrder.Qty)
 	}
 
-	priceHint := e.state.LastTicker.LastPrice
+	priceHint := e.lastPrice()
 	if priceHint == 0 && e.rules.MinNotional > 0 {
 		var err error
 		priceHint, err = e.waitForTickerPrice(ctx, 10*time.Second)
@@ -55,10 +64,13 @@ func (e *Engine) openDeal(ctx context.Context) error {
 		return err
 	}
 
+	soStepPercent := e.resolveSOStepPercent(ctx)
+
 	e.logEntry().WithFields(map[string]interface{}{
-		"side": entryOrder.Side,
-		"type": entryOrder.Type,
-		"qty":  entryOrder.Qty,
+		"side":            entryOrder.Side,
+		"type":            entryOrder.Type,
+		"qty":             entryOrder.Qty,
+		"size_multiplier": sizeMultiplier,
 	}).Info("Входной ордер.")
 
 	_, err = e.placeOrderIdempotent(ctx, entryOrder)
@@ -68,41 +80,55 @@ func (e *Engine) openDeal(ctx context.Context) error {
 
 	e.logEntry().Info("Отправка market ордер на вход.")
 
-	fill, execIDs, err := e.waitEntryFill(ctx, entryLinkID)
+	fill, fills, err := e.waitEntryFill(ctx, entryLinkID)
 	if err != nil {
 		return err
 	}
 
-	e.mu.Lock()
-	e.state = DealState{
-		Active:      true,
-		DealID:      e.state.DealID,
-		Symbol:      entryOrder.Symbol,
-		Side:        entryOrder.Side,
-		EntryPrice:  fill.Price,
-		EntryLinkID: entryLinkID,
-		AvgPrice:    fill.Price,
-		TotalQty:    fill.Qty,
-		FilledByLink: map[string]float64{
-			entryLinkID: fill.Qty,
-		},
-		TPFilledQty:      0,
-		ProcessedExecIDs: map[string]bool{},
-		PlannedTPQty:     0,
-		SafetyOrders:     map[string]string{},
-		UpdatedAt:        time.Now(),
-	}
-	for _, execID := range execIDs {
-		if execID != "" {
-			e.state.ProcessedExecIDs[execID] = true
+	var carried float64
+	e.apply(ctx, func() {
+		carried = e.takeCarriedDust()
+		e.state = DealState{
+			Active:      true,
+			DealID:      e.state.DealID,
+			Symbol:      entryOrder.Symbol,
+			Side:        entryOrder.Side,
+			EntryPrice:  fill.Price,
+			EntryLinkID: entryLinkID,
+			AvgPrice:    fill.Price,
+			TotalQty:    fill.Qty,
+			FilledByLink: map[string]float64{
+				entryLinkID: fill.Qty,
+			},
+			TPFilledQty:      0,
+			ProcessedExecIDs: map[string]bool{},
+			PlannedTPQty:     0,
+			SafetyOrders:     map[string]string{},
+			SOStepPercent:    soStepPercent,
+			SizeMultiplier:   sizeMultiplier,
+			UpdatedAt:        time.Now(),
+			Orders:           e.state.Orders,
+			Ledger:           PositionLedger{Opened: fill.Qty, Carried: carried},
+			CarriedDust:      carried,
+		}
+		for _, entryFill := range fills {
+			if entryFill.ExecID != "" {
+				e.state.ProcessedExecIDs[entryFill.ExecID] = true
+			}
+			e.chargeFee(entryFill)
 		}
+	})
+	e.saveState()
+	if carried > 0 {
+		e.logEntry().WithField("dust", carried).Info("Пыль прошлых сделок перенесена в TP новой сделки.")
 	}
-	e.mu.Unlock()
 
 	return e.placeTPAndSafety(ctx, fill.Price)
 }
 
-func (e *Engine) waitEntryFill(ctx context.Context, linkID string) (models.Fill, []string, error) {
+// waitEntryFill ждёт исполнения market ордера и возвращает сводное исполнение по средней
+// цене вместе с исходными исполнениями: их комиссии учитываются по отдельности.
+func (e *Engine) waitEntryFill(ctx context.Context, linkID string) (models.Fill, []models.Fill, error) {
 	timeout := time.NewTimer(20 * time.Second)
 	defer timeout.Stop()
 
@@ -116,14 +142,14 @@ func (e *Engine) waitEntryFill(ctx context.Context, linkID string) (models.Fill,
 		case <-timeout.C:
 			return models.Fill{}, nil, fmt.Errorf("Не дождались исполнения входа.")
 		case <-ticker.C:
-			fills, err := e.withRetryFills(ctx, e.cfg.Bot.Symbol)
+			fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: e.cfg.Bot.Symbol, LinkID: linkID})
 			if err != nil {
 				continue
 			}
 			var totalQty float64
 			var totalCost float64
 			var lastFill models.Fill
-			var execIDs []string
+			var matched []models.Fill
 			for _, fill := range fills {
 				if fill.LinkID != linkID {
 					continue
@@ -131,9 +157,7 @@ func (e *Engine) waitEntryFill(ctx context.Context, linkID string) (models.Fill,
 				totalQty += fill.Qty
 				totalCost += fill.Price * fill.Qty
 				lastFill = fill
-				if fill.ExecID != "" {
-					execIDs = append(execIDs, fill.ExecID)
-				}
+				matched = append(matched, fill)
 			}
 			if totalQty > 0 {
 				return models.Fill{
@@ -146,22 +170,26 @@ func (e *Engine) waitEntryFill(ctx context.Context, linkID string) (models.Fill,
 					Qty:       totalQty,
 					Timestamp: lastFill.Timestamp,
 					Sequence:  lastFill.Sequence,
-				}, execIDs, nil
+				}, matched, nil
 			}
 		}
 	}
 }
 
 func (e *Engine) placeTPAndSafety(ctx context.Context, entryPrice float64) error {
-	tpPrice := CalcTPPrice(entryPrice, e.cfg.Bot.TPPercent, e.state.Side)
+	e.mu.Lock()
+	side := e.state.Side
+	totalQty := e.roundQty(e.state.TotalQty + e.state.CarriedDust)
+	e.mu.Unlock()
+
+	tpPrice := CalcTPPrice(entryPrice, e.cfg.Bot.TPPercent, side)
 	tpPrice = e.roundPrice(tpPrice)
-	totalQty := e.roundQty(e.state.TotalQty)
 
 	if err := e.placeTP(ctx, tpPrice, totalQty, e.nextTPSuffix()); err != nil {
 		return err
 	}
 
-	if err := e.placeSafetyOrders(ctx, entryPrice); err != nil {
+	if err := e.placeSafetyOrders(ctx); err != nil {
 		return err
 	}
 
@@ -169,190 +197,267 @@ func (e *Engine) placeTPAndSafety(ctx context.Context, entryPrice float64) error
 }
 
 func (e *Engine) requestClose(ctx context.Context, reason string) {
-	e.mu.Lock()
-	if !e.state.Active || e.state.Closing {
-		e.mu.Unlock()
+	requested := false
+	e.apply(ctx, func() {
+		if !e.state.Active || e.state.Closing {
+			return
+		}
+		e.state.Closing = true
+		e.state.CloseRequested = true
+		e.state.CloseReason = reason
+		requested = true
+	})
+	if !requested {
 		return
 	}
-	e.state.Closing = true
-	e.state.CloseRequested = true
-	e.state.CloseReason = reason
-	e.mu.Unlock()
 
 	e.logEntry().WithField("reason", reason).Info("Закрытие цикла сделки.")
 
-	go func() {
-		const settleDelay = 1 * time.Second
+	e.submit(jobDeal, "close", e.closeDeal, nil)
+}
 
-		if err := e.cancelSafetyOrders(ctx); err != nil {
-			e.logEntry().WithError(err).Warn("Не удалось отменить страховочные ордера.")
-		} else {
-			e.logEntry().Info("Страховочные ордера отменены.")
-		}
+// cancelClosing снимает признак закрытия; вызывается под e.mu.
+func (e *Engine) cancelClosing() {
+	e.state.Closing = false
+	e.state.CloseRequested = false
+	e.state.CloseReason = ""
+}
 
-		for {
-			if ctx.Err() != nil {
-				return
-			}
+// closeDeal доводит закрытие до конца в пуле под jobDeal: перестановки TP ждут его окончания.
+func (e *Engine) closeDeal(ctx context.Context) error {
+	const settleDelay = 1 * time.Second
 
-			e.mu.Lock()
-			active := e.state.Active
-			closing := e.state.Closing
-			totalQty := e.state.TotalQty
-			lastFillAt := e.state.LastFillAt
-			e.mu.Unlock()
+	if err := e.cancelSafetyOrders(ctx); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось отменить страховочные ордера.")
+	} else {
+		e.logEntry().Info("Страховочные ордера отменены.")
+	}
 
-			if !active || !closing {
-				return
-			}
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
 
-			if !e.isQtyZero(totalQty) {
-				e.logEntry().Warn("Закрытие отменено: есть позиция, сделка продолжается.")
-				e.mu.Lock()
-				e.state.Closing = false
-				e.state.CloseRequested = false
-				e.state.CloseReason = ""
-				e.mu.Unlock()
-				if err := e.rebuildTP(ctx); err != nil {
-					e.logEntry().WithError(err).Warn("Не удалось переставить TP.")
-				}
-				return
+		e.mu.Lock()
+		active := e.state.Active
+		closing := e.state.Closing
+		totalQty := e.state.TotalQty
+		lastFillAt := e.state.LastFillAt
+		e.mu.Unlock()
+
+		if !active || !closing {
+			return nil
+		}
+
+		if !e.isQtyZero(totalQty) {
+			e.logEntry().Warn("Закрытие отменено: есть позиция, сделка продолжается.")
+			e.apply(ctx, e.cancelClosing)
+			if err := e.rebuildTP(ctx); err != nil {
+				e.logEntry().WithError(err).Warn("Не удалось переставить TP.")
 			}
+			return nil
+		}
 
-			if baseQty, err := e.baseAvailable(ctx); err == nil {
-				rounded := e.roundQty(baseQty)
-				if !e.isQtyZero(rounded) {
+		if baseQty, err := e.baseAvailable(ctx); err == nil {
+			rounded := e.roundQty(baseQty)
+			// В расхождение идёт только объём сверх позиции сделки по её исполнениям.
+			position := e.ledgerPosition()
+			drift := baseQty - position
+			if e.isDustQty(baseQty, e.lastPrice()) || e.isQtyZero(baseQty) {
+				e.setDealDust(ctx, math.Max(baseQty, 0))
+			} else if !e.isQtyZero(e.roundQty(drift)) {
+				switch e.onBalanceDrift(ctx, drift, map[string]interface{}{"base_qty": baseQty, "position": position, "stage": "close"}) {
+				case reconcileAdopt:
 					e.logEntry().Warn("Закрытие отменено: есть позиция по балансу, сделка продолжается.")
-					e.mu.Lock()
-					e.state.TotalQty = rounded
-					e.state.Closing = false
-					e.state.CloseRequested = false
-					e.state.CloseReason = ""
-					e.mu.Unlock()
+					e.apply(ctx, func() {
+						e.state.Ledger.Adopted += rounded - e.state.Ledger.Position()
+						e.state.TotalQty = rounded
+						e.cancelClosing()
+					})
 					if err := e.rebuildTP(ctx); err != nil {
 						e.logEntry().WithError(err).Warn("Не удалось переставить TP.")
 					}
-					return
+					return nil
+				case reconcileHalt:
+					return nil
+				case reconcileIgnore:
+					e.setDealDust(ctx, math.Max(position, 0))
 				}
 			}
+		}
 
-			if !lastFillAt.IsZero() && time.Since(lastFillAt) < settleDelay {
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(settleDelay):
-				}
-				continue
-			}
-
-			hasOpen, err := e.hasOpenBotOrders(ctx)
-			if err != nil {
-				e.logEntry().WithError(err).Warn("Не удалось проверить открытые ордера перед завершением цикла.")
-			} else if !hasOpen {
-				e.finalizeClose(ctx)
-				return
-			} else {
-				if canceled, cancelErr := e.cancelOpenBotOrders(ctx); cancelErr != nil {
-					e.logEntry().WithError(cancelErr).Warn("Не удалось отменить открытые ордера перед завершением цикла.")
-				} else if canceled > 0 {
-					e.logEntry().WithField("count", canceled).Info("Отмена открытых ордеров перед завершением цикла.")
-				}
-				e.logEntry().Info("Ожидание закрытия открытых ордеров перед завершением цикла.")
-			}
-
+		if !lastFillAt.IsZero() && time.Since(lastFillAt) < settleDelay {
 			select {
 			case <-ctx.Done():
-				return
+				return nil
 			case <-time.After(settleDelay):
 			}
+			continue
 		}
-	}()
+
+		hasOpen, err := e.hasOpenBotOrders(ctx)
+		if err != nil {
+			e.logEntry().WithError(err).Warn("Не удалось проверить открытые ордера перед завершением цикла.")
+		} else if !hasOpen {
+			e.finalizeClose(ctx)
+			return nil
+		} else {
+			if canceled, cancelErr := e.cancelOpenBotOrders(ctx); cancelErr != nil {
+				e.logEntry().WithError(cancelErr).Warn("Не удалось отменить открытые ордера перед завершением цикла.")
+			} else if canceled > 0 {
+				e.logEntry().WithField("count", canceled).Info("Отмена открытых ордеров перед завершением цикла.")
+			}
+			e.logEntry().Info("Ожидание закрытия открытых ордеров перед завершением цикла.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(settleDelay):
+		}
+	}
 }
 
 func (e *Engine) finalizeClose(ctx context.Context) {
-	e.mu.Lock()
-	if !e.state.Active {
-		e.mu.Unlock()
+	var (
+		closed     bool
+		dealID     string
+		dust       float64
+		dealPnL    float64
+		entryPrice float64
+		fees       float64
+		unpriced   map[string]float64
+	)
+	e.apply(ctx, func() {
+		if !e.state.Active {
+			return
+		}
+		closed = true
+		now := time.Now()
+		dealID = e.state.DealID
+		dust = e.state.Dust
+		dealPnL = e.state.RealizedPnL
+		entryPrice = e.state.EntryPrice
+		fees = e.state.Fees
+		unpriced = e.state.UnpricedFees
+		e.state.Active = false
+		e.state.Closing = false
+		e.state.CloseRequested = false
+		e.state.CloseReason = ""
+		e.state.DealID = ""
+		e.state.Symbol = ""
+		e.state.Side = ""
+		e.state.EntryPrice = 0
+		e.state.EntryLinkID = ""
+		e.state.AvgPrice = 0
+		e.state.TotalQty = 0
+		e.state.FilledByLink = map[string]float64{}
+		e.state.ProcessedExecIDs = map[string]bool{}
+		e.state.TPFilledQty = 0
+		e.state.TPOrderID = ""
+		e.state.TPlinkID = ""
+		e.state.PlannedTPQty = 0
+		e.state.SafetyOrders = map[string]string{}
+		e.state.Orders = map[string]OrderRecord{}
+		e.state.Ledger = PositionLedger{}
+		e.state.Dust = 0
+		e.state.CarriedDust = 0
+		e.state.PlannedTPPrice = 0
+		e.state.GridAnchorLevel = 0
+		e.state.GridAnchorPrice = 0
+		e.state.SOStepPercent = 0
+		e.state.SizeMultiplier = 0
+		e.state.RealizedPnL = 0
+		e.state.Fees = 0
+		e.state.UnpricedFees = nil
+		e.tpTarget = 0
+		e.state.ClosedAt = &now
+		e.state.UpdatedAt = now
+	})
+	if !closed {
 		return
 	}
-	now := time.Now()
-	e.state.Active = false
-	e.state.Closing = false
-	e.state.CloseRequested = false
-	e.state.CloseReason = ""
-	e.state.DealID = ""
-	e.state.Symbol = ""
-	e.state.Side = ""
-	e.state.EntryPrice = 0
-	e.state.EntryLinkID = ""
-	e.state.AvgPrice = 0
-	e.state.TotalQty = 0
-	e.state.FilledByLink = map[string]float64{}
-	e.state.ProcessedExecIDs = map[string]bool{}
-	e.state.TPFilledQty = 0
-	e.state.TPOrderID = ""
-	e.state.TPlinkID = ""
-	e.state.PlannedTPQty = 0
-	e.state.SafetyOrders = map[string]string{}
-	e.state.PlannedTPPrice = 0
-	e.state.ClosedAt = &now
-	e.state.UpdatedAt = now
-	e.mu.Unlock()
+	e.logEntry().WithFields(map[string]interface{}{
+		"deal_id":  dealID,
+		"deal_pnl": dealPnL,
+		"fees":     fees,
+	}).Info("Результат сделки за вычетом комиссий.")
+	if len(unpriced) > 0 {
+		e.logEntry().WithFields(map[string]interface{}{
+			"deal_id":       dealID,
+			"unpriced_fees": unpriced,
+		}).Warn("Комиссия в сторонней монете не пересчитана и не вошла в результат сделки.")
+	}
+	e.addDust(ctx, dealID, dust)
+	e.applyDealResult(ctx, dealPnL, entryPrice)
+	e.saveState()
+
+	e.submit(jobDeal, "new_cycle", e.startNextCycle, nil)
+}
 
-	go func() {
-		const restartDelay = 1 * time.Second
-		const maxChecks = 5
+// startNextCycle дожидается снятия ордеров закрытой сделки и открывает новый цикл.
+func (e *Engine) startNextCycle(ctx context.Context) error {
+	const restartDelay = 1 * time.Second
+	const maxChecks = 5
 
+	if ctx.Err() != nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(restartDelay):
+	}
+	for i := 0; i < maxChecks; i++ {
 		if ctx.Err() != nil {
-			return
+			return nil
+		}
+		hasOpen, err := e.hasOpenBotOrders(ctx)
+		if err != nil {
+			e.logEntry().WithError(err).Warn("Не удалось проверить открытые ордера перед новым циклом.")
+		} else if !hasOpen {
+			break
+		} else {
+			if canceled, cancelErr := e.cancelOpenBotOrders(ctx); cancelErr != nil {
+				e.logEntry().WithError(cancelErr).Warn("Не удалось отменить открытые ордера перед новым циклом.")
+			} else if canceled > 0 {
+				e.logEntry().WithField("count", canceled).Info("Отмена открытых ордерво перед новым циклом.")
+			}
+			e.logEntry().Info("Ожидание закрытия открытых ордеров перед новым циклом.")
 		}
 		select {
 		case <-ctx.Done():
-			return
+			return nil
 		case <-time.After(restartDelay):
 		}
-		for i := 0; i < maxChecks; i++ {
-			if ctx.Err() != nil {
-				return
-			}
-			hasOpen, err := e.hasOpenBotOrders(ctx)
-			if err != nil {
-				e.logEntry().WithError(err).Warn("Не удалось проверить открытые ордера перед новым циклом.")
-			} else if !hasOpen {
-				break
-			} else {
-				if canceled, cancelErr := e.cancelOpenBotOrders(ctx); cancelErr != nil {
-					e.logEntry().WithError(cancelErr).Warn("Не удалось отменить открытые ордера перед новым циклом.")
-				} else if canceled > 0 {
-					e.logEntry().WithField("count", canceled).Info("Отмена открытых ордерво перед новым циклом.")
-				}
-				e.logEntry().Info("Ожидание закрытия открытых ордеров перед новым циклом.")
-			}
-			select {
-			case <-ctx.Done():
-				return
-			case <-time.After(restartDelay):
-			}
-		}
-		if baseQty, err := e.baseAvailable(ctx); err == nil {
-			if !e.isQtyZero(e.roundQty(baseQty)) {
-				e.logEntry().Warn("Новый цикл не запущен: есть позиция по балансу.")
-				return
+	}
+	if err := e.errHalted(); err != nil {
+		e.logEntry().WithError(err).Warn("Новый цикл не запущен.")
+		return nil
+	}
+	if baseQty, err := e.baseAvailable(ctx); err == nil {
+		if !e.isDustQty(baseQty, e.lastPrice()) && !e.isQtyZero(e.roundQty(baseQty)) {
+			switch e.onBalanceDrift(ctx, baseQty, map[string]interface{}{"base_qty": baseQty, "stage": "new_cycle"}) {
+			case reconcileAdopt:
+				e.logEntry().Warn("Есть позиция по балансу, она будет включена в новую сделку сверкой.")
+			case reconcileHalt:
+				return nil
 			}
 		}
+	}
 
-		hasOpen, err := e.hasOpenBotOrders(ctx)
-		if err != nil {
-			e.logEntry().WithError(err).Warn("Не удалось проверить открытые ордера перед новым циклом.")
-			return
-		}
-		if hasOpen {
-			e.logEntry().Warn("Новый цикл не запущен: есть открытые ордера.")
-			return
-		}
-		e.logEntry().Info("Запускаю новый цикл сделки.")
-		if err := e.openDeal(ctx); err != nil {
-			e.logEntry().WithError(err).Error("Не удалось открыть новый цикл.")
-		}
-	}()
+	hasOpen, err := e.hasOpenBotOrders(ctx)
+	if err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось проверить открытые ордера перед новым циклом.")
+		return nil
+	}
+	if hasOpen {
+		e.logEntry().Warn("Новый цикл не запущен: есть открытые ордера.")
+		return nil
+	}
+	e.logEntry().Info("Запускаю новый цикл сделки.")
+	if err := e.openDeal(ctx); err != nil {
+		e.logEntry().WithError(err).Error("Не удалось открыть новый цикл.")
+	}
+	return nil
 }
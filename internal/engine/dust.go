@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	dustCarry   = "carry"
+	dustConvert = "convert"
+
+	dustEpsilon = 1e-9
+)
+
+// DustState - остатки базовой монеты меньше минимального ордера, которые ещё не
+// перенесены в TP следующей сделки и не сконвертированы.
+type DustState struct {
+	Qty         float64            `json:"qty"`
+	ByDeal      map[string]float64 `json:"by_deal"`
+	ConvertedAt time.Time          `json:"converted_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+
+	// Unconvertible - биржа отказала в конвертации, пыль переносится в TP следующей сделки.
+	Unconvertible bool `json:"unconvertible"`
+}
+
+func normalizeDustMode(mode string) string {
+	if strings.EqualFold(strings.TrimSpace(mode), dustConvert) {
+		return dustConvert
+	}
+	return dustCarry
+}
+
+// dustMode возвращает carry, если биржа не умеет конвертировать.
+func (e *Engine) dustMode() string {
+	mode := normalizeDustMode(e.cfg.Bot.Dust.Mode)
+	if mode == dustConvert && !e.caps.Convert {
+		return dustCarry
+	}
+	return mode
+}
+
+// isDustQty не берёт e.mu и может вызываться под ним.
+func (e *Engine) isDustQty(qty, price float64) bool {
+	if qty <= dustEpsilon {
+		return false
+	}
+	rounded := e.roundQty(qty)
+	if rounded < e.rules.MinQty || rounded <= 0 {
+		return true
+	}
+	if price <= 0 {
+		return false
+	}
+	return e.validateMinNotional(models.Order{Price: price, Qty: rounded, Type: models.OrderTypeLimit}, price) != nil
+}
+
+// sweepPositionDust списывает остаток позиции меньше минимального ордера в пыль сделки,
+// чтобы он не держал сделку открытой.
+func (e *Engine) sweepPositionDust(ctx context.Context, price float64) float64 {
+	var swept float64
+	e.apply(ctx, func() {
+		qty := e.state.TotalQty + e.state.CarriedDust
+		if !e.isDustQty(qty, price) {
+			return
+		}
+		e.state.Dust += qty
+		e.state.TotalQty = 0
+		e.state.CarriedDust = 0
+		e.state.UpdatedAt = time.Now()
+		swept = qty
+	})
+	return swept
+}
+
+// setDealDust фиксирует пыль сделки по балансу перед завершением цикла.
+func (e *Engine) setDealDust(ctx context.Context, qty float64) {
+	e.apply(ctx, func() {
+		e.state.Dust = qty
+	})
+}
+
+func (e *Engine) addDust(ctx context.Context, dealID string, qty float64) {
+	if qty <= dustEpsilon {
+		return
+	}
+	var total float64
+	e.apply(ctx, func() {
+		if e.dust.ByDeal == nil {
+			e.dust.ByDeal = map[string]float64{}
+		}
+		e.dust.Qty += qty
+		if dealID != "" {
+			e.dust.ByDeal[dealID] += qty
+		}
+		e.dust.UpdatedAt = time.Now()
+		total = e.dust.Qty
+	})
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"deal_id": dealID,
+		"qty":     qty,
+		"total":   total,
+		"mode":    e.dustMode(),
+	}).Info("Остаток сделки меньше минимального ордера учтён как пыль.")
+}
+
+// takeCarriedDust вызывается под e.mu при открытии сделки: в режиме carry накопленная
+// пыль переходит в позицию и продаётся вместе с её TP.
+func (e *Engine) takeCarriedDust() float64 {
+	if (e.dustMode() != dustCarry && !e.dust.Unconvertible) || e.dust.Qty <= dustEpsilon {
+		return 0
+	}
+	qty := e.dust.Qty
+	e.dust.Qty = 0
+	e.dust.ByDeal = map[string]float64{}
+	e.dust.Unconvertible = false
+	e.dust.UpdatedAt = time.Now()
+	return qty
+}
+
+func (e *Engine) watchDust(ctx context.Context) {
+	interval := e.cfg.Bot.Dust.ConvertInterval
+	if e.strategy() != strategyDCA || normalizeDustMode(e.cfg.Bot.Dust.Mode) != dustConvert || interval <= 0 {
+		return
+	}
+	if !e.caps.Convert {
+		e.logEntry().Warn("Биржа не поддерживает конвертацию, пыль переносится в TP следующей сделки.")
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		e.submit(jobBalances, "dust_convert", e.convertDust, func(ctx context.Context, err error) {
+			if err != nil {
+				e.logEntry().WithError(err).Warn("Не удалось сконвертировать пыль.")
+			}
+		})
+	}
+}
+
+func (e *Engine) convertDust(ctx context.Context) error {
+	base := e.rules.BaseCoin
+	to := e.cfg.Bot.Dust.ConvertTo
+	if to == "" {
+		to = e.rules.QuoteCoin
+	}
+	e.mu.Lock()
+	qty := e.dust.Qty
+	e.mu.Unlock()
+	if base == "" || to == "" || qty <= dustEpsilon {
+		return nil
+	}
+
+	result, err := e.client.Convert(ctx, base, to, qty)
+	if errors.Is(err, exchange.ErrConvertUnavailable) || errors.Is(err, exchange.ErrNotSupported) {
+		e.apply(ctx, func() {
+			e.dust.Unconvertible = true
+			e.dust.UpdatedAt = time.Now()
+		})
+		e.saveState()
+		e.logEntry().WithError(err).WithField("qty", qty).Info("Пыль нельзя сконвертировать, она будет перенесена в TP следующей сделки.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	e.apply(ctx, func() {
+		e.dust.Qty -= qty
+		if e.dust.Qty <= dustEpsilon {
+			e.dust.Qty = 0
+			e.dust.ByDeal = map[string]float64{}
+		}
+		e.dust.Unconvertible = false
+		e.dust.ConvertedAt = time.Now()
+		e.dust.UpdatedAt = e.dust.ConvertedAt
+	})
+	e.saveState()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"from":        base,
+		"to":          to,
+		"qty":         qty,
+		"to_amount":   result.ToAmount,
+		"quote_tx_id": result.ID,
+		"status":      result.Status,
+	}).Info("Пыль сконвертирована.")
+	return nil
+}
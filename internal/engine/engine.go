@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"dcabot/internal/config"
+	"dcabot/internal/eventbus"
 	"dcabot/internal/exchange"
 	"dcabot/internal/logger"
 	"math"
@@ -13,22 +14,51 @@ import (
 type Engine struct {
 	cfg                *config.Config
 	client             exchange.Client
+	caps               exchange.Capabilities
 	log                *logger.Logger
 	rules              exchange.InstrumentRules
 	tpSeq              int64
 	mu                 sync.Mutex
 	state              DealState
+	sizing             SizingState
+	ledger             AccumulationLedger
+	cron               *cronSchedule
+	grid               GridState
+	strat              Strategy
+	tpTarget           float64
 	lastTickerLog      time.Time
+	tickerStale        bool
 	tpRebuildScheduled bool
 	tpRebuildAt        time.Time
+
+	workers *workerPool
+	inbox   *inbox
+	stopped <-chan struct{}
+	saver   *stateSaver
+	saveSeq uint64
+
+	ledgerSaver *stateSaver
+	ledgerSeq   uint64
+
+	reconcile ReconcileState
+	halted    string
+	dust      DustState
 }
 
 func New(cfg *config.Config, client exchange.Client, log *logger.Logger) *Engine {
 	return &Engine{
 		cfg:    cfg,
 		client: client,
+		caps:   client.Capabilities(),
 		log:    log,
 		state:  DealState{},
+		strat:  resolveStrategy(cfg),
+
+		workers: newWorkerPool(),
+		inbox:   newInbox(),
+		saver:   newStateSaver(),
+
+		ledgerSaver: newStateSaver(),
 	}
 }
 
@@ -48,14 +78,70 @@ func (e *Engine) Start(ctx context.Context) error {
 		"rules_base":         e.rules.BaseCoin,
 		"rules_quote":        e.rules.QuoteCoin,
 	}).Info("Получены ограничения торговой пары.")
+	e.logEntry().WithFields(map[string]interface{}{
+		"exchange":         e.cfg.Exchange.Name,
+		"amend":            e.caps.Amend,
+		"batch_orders":     e.caps.BatchOrders,
+		"post_only":        e.caps.PostOnly,
+		"reduce_only_spot": e.caps.ReduceOnlySpot,
+		"fee_currency":     e.caps.FeeCurrency,
+	}).Info("Возможности биржи.")
+
+	if saved, ok := e.loadSavedState(); ok {
+		e.sizing = saved.Sizing
+		e.reconcile = saved.Reconcile
+		e.dust = saved.Dust
+	}
+	if e.cfg.Bot.Compounding.Enabled {
+		e.logEntry().WithFields(map[string]interface{}{
+			"realized_pnl":    e.sizing.RealizedPnL,
+			"base_capital":    e.sizing.BaseCapital,
+			"closed_deals":    e.sizing.ClosedDeals,
+			"size_multiplier": e.sizeMultiplier(),
+		}).Info("Режим реинвестирования прибыли.")
+	}
 
 	events, err := e.client.Subscribe(ctx, e.cfg.Bot.Symbol)
 	if err != nil {
 		return err
 	}
 
-	go e.handleEvents(ctx, events)
+	e.saver.start()
+	go e.saver.run(ctx, e.writeState)
+	e.ledgerSaver.start()
+	go e.ledgerSaver.run(ctx, e.writeLedger)
+	e.stopped = ctx.Done()
+	e.workers.start(ctx, engineWorkers, e.finishJob)
+
+	bus := eventbus.New()
+	go bus.Run(ctx, events)
+	go e.handleEvents(ctx, bus.Events())
+	go e.watchTickerStaleness(ctx)
+	go e.watchBalanceDrift(ctx)
+	go e.watchDust(ctx)
+	e.logReservedBase(ctx)
+
+	if e.strat != nil {
+		e.logEntry().WithField("strategy", e.strat.Name()).Info("Стратегия принятия решений.")
+	}
+
+	switch e.strategy() {
+	case strategyAccumulate:
+		return e.startAccumulation(ctx)
+	case strategyGrid:
+		return e.runExclusive(ctx, jobDeal, e.startGrid)
+	}
+
+	return e.runExclusive(ctx, jobDeal, e.startDeal)
+}
+
+// Wait ждёт записи последнего состояния и журнала после остановки контекста Start.
+func (e *Engine) Wait() {
+	e.saver.wait()
+	e.ledgerSaver.wait()
+}
 
+func (e *Engine) startDeal(ctx context.Context) error {
 	restored, err := e.restoreActiveOrders(ctx)
 	if err != nil {
 		return err
@@ -64,7 +150,7 @@ func (e *Engine) Start(ctx context.Context) error {
 		e.logEntry().Info("Восстановлены активные ордера после рестарта, новый вход не нужен.")
 	}
 
-	if !restored && !e.state.Active {
+	if !restored && !e.isActive() {
 		if err := e.openDeal(ctx); err != nil {
 			return err
 		}
@@ -76,7 +162,7 @@ func (e *Engine) Start(ctx context.Context) error {
 func (e *Engine) withRetryRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
 	var lastErr error
 	var reconnect time.Duration = 1 * time.Second
-	for i := 0; i < 5; i++ {
+	for i := 0; i < retryAttempts; i++ {
 		rules, err := e.client.GetInstrumentRules(ctx, symbol)
 		if err == nil {
 			return rules, nil
@@ -0,0 +1,774 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/config"
+	"dcabot/internal/exchange"
+	"dcabot/internal/logger"
+	"dcabot/internal/models"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	fakeSymbol = "BTCUSDT"
+	fakePrice  = 100.0
+)
+
+// fakeClient - биржа в памяти: market-ордера исполняются сразу, лимитные исполняет тест
+// через fill. Нарушения инвариантов на стороне биржи копятся в violations.
+type fakeClient struct {
+	mu         sync.Mutex
+	orders     map[string]*models.Order
+	fills      []models.Fill
+	seq        int64
+	events     chan exchange.Event
+	violations []string
+
+	// extraBase - базовая монета на счёте сверх исполнений, например пыль прошлых сделок.
+	extraBase float64
+
+	// klines - свечи по символу для GetKlines.
+	klines map[string][]models.Kline
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		orders: map[string]*models.Order{},
+		events: make(chan exchange.Event, 4096),
+	}
+}
+
+func (f *fakeClient) Capabilities() exchange.Capabilities {
+	return exchange.Capabilities{Amend: true, FeeCurrency: exchange.FeeCurrencyQuote}
+}
+
+func (f *fakeClient) GetInstrumentRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
+	return exchange.InstrumentRules{
+		TickSize:    0.01,
+		LotSize:     0.001,
+		MinQty:      0.001,
+		MinNotional: 1,
+		BaseCoin:    "BTC",
+		QuoteCoin:   "USDT",
+	}, nil
+}
+
+func (f *fakeClient) Subscribe(ctx context.Context, symbol string) (<-chan exchange.Event, error) {
+	return f.events, nil
+}
+
+func isOpenStatus(status models.OrderStatus) bool {
+	return status == models.OrderStatusNew || status == models.OrderStatusPartiallyFilled
+}
+
+func (f *fakeClient) PlaceOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.orders {
+		if !isOpenStatus(existing.Status) {
+			continue
+		}
+		if existing.LinkID == order.LinkID {
+			f.violations = append(f.violations, "duplicate open link id "+order.LinkID)
+			return models.Order{}, fmt.Errorf("code=170141 Duplicate clientOrderId")
+		}
+		if isTPLinkID(existing.LinkID) && isTPLinkID(order.LinkID) {
+			f.violations = append(f.violations, fmt.Sprintf("second TP %s placed while %s is open", order.LinkID, existing.LinkID))
+		}
+	}
+	f.seq++
+	order.ID = fmt.Sprintf("ord-%d", f.seq)
+	order.Status = models.OrderStatusNew
+	order.Sequence = f.seq
+	order.CreateTime = time.Now()
+	stored := order
+	f.orders[order.ID] = &stored
+	if order.Type == models.OrderTypeMarket {
+		f.fillLocked(&stored, order.Qty, fakePrice)
+	}
+	return order, nil
+}
+
+func (f *fakeClient) CancelOrder(ctx context.Context, symbol, orderID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	order, ok := f.orders[orderID]
+	if !ok || !isOpenStatus(order.Status) {
+		return fmt.Errorf("code=170213 Order does not exist")
+	}
+	order.Status = models.OrderStatusCanceled
+	f.pushOrderLocked(order)
+	return nil
+}
+
+func (f *fakeClient) AmendOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.orders[order.ID]
+	if !ok || !isOpenStatus(existing.Status) {
+		return models.Order{}, fmt.Errorf("code=170213 Order does not exist")
+	}
+	if order.Qty <= existing.FilledQty {
+		return models.Order{}, fmt.Errorf("code=170135 qty below filled")
+	}
+	existing.Price = order.Price
+	existing.Qty = order.Qty
+	f.pushOrderLocked(existing)
+	return *existing, nil
+}
+
+func (f *fakeClient) GetOpenOrders(ctx context.Context, symbol string) ([]models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var open []models.Order
+	for _, order := range f.orders {
+		if isOpenStatus(order.Status) {
+			open = append(open, *order)
+		}
+	}
+	return open, nil
+}
+
+func (f *fakeClient) GetFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var fills []models.Fill
+	for _, fill := range f.fills {
+		if query.LinkID != "" && fill.LinkID != query.LinkID {
+			continue
+		}
+		if !query.Since.IsZero() && fill.Timestamp.Before(query.Since) {
+			continue
+		}
+		fills = append(fills, fill)
+	}
+	return fills, nil
+}
+
+func (f *fakeClient) GetOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var orders []models.Order
+	for _, order := range f.orders {
+		if query.LinkID == "" || order.LinkID == query.LinkID {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, nil
+}
+
+func (f *fakeClient) GetBalances(ctx context.Context, coins []string) (map[string]exchange.Balance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	base := f.extraBase
+	for _, fill := range f.fills {
+		if fill.Side == models.OrderSideBuy {
+			base += fill.Qty
+		} else {
+			base -= fill.Qty
+		}
+	}
+	return map[string]exchange.Balance{
+		"BTC":  {Coin: "BTC", Wallet: base, Available: base},
+		"USDT": {Coin: "USDT", Wallet: 1e6, Available: 1e6},
+	}, nil
+}
+
+func (f *fakeClient) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.klines[symbol], nil
+}
+
+func (f *fakeClient) Convert(ctx context.Context, fromCoin, toCoin string, amount float64) (exchange.ConvertResult, error) {
+	return exchange.ConvertResult{}, exchange.ErrNotSupported
+}
+
+// fillLocked исполняет qty ордера (не больше остатка) и отправляет исполнение и статус.
+func (f *fakeClient) fillLocked(order *models.Order, qty, price float64) (models.Fill, bool) {
+	leaves := RoundDown(order.Qty-order.FilledQty, 0.001)
+	if qty > leaves {
+		qty = leaves
+	}
+	if qty <= 0 {
+		return models.Fill{}, false
+	}
+	f.seq++
+	order.FilledQty += qty
+	order.Status = models.OrderStatusPartiallyFilled
+	if isQtyZeroFor(order.Qty-order.FilledQty, 0.001) {
+		order.Status = models.OrderStatusFilled
+	}
+	fill := models.Fill{
+		OrderID:   order.ID,
+		LinkID:    order.LinkID,
+		ExecID:    fmt.Sprintf("exec-%d", f.seq),
+		Symbol:    order.Symbol,
+		Side:      order.Side,
+		Price:     price,
+		Qty:       qty,
+		Timestamp: time.Now(),
+		Sequence:  f.seq,
+		Fee:       price * qty * 0.001,
+		FeeCoin:   "USDT",
+	}
+	f.fills = append(f.fills, fill)
+	f.events <- exchange.Event{Type: exchange.EventTypeFill, Fill: &fill}
+	f.pushOrderLocked(order)
+	return fill, true
+}
+
+func (f *fakeClient) pushOrderLocked(order *models.Order) {
+	f.seq++
+	order.Sequence = f.seq
+	order.UpdateTime = time.Now()
+	snapshot := *order
+	f.events <- exchange.Event{Type: exchange.EventTypeOrder, Order: &snapshot}
+}
+
+// fill исполняет часть открытого ордера, link id которого удовлетворяет match.
+func (f *fakeClient) fill(match func(linkID string) bool, qty float64) (models.Fill, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, order := range f.orders {
+		if isOpenStatus(order.Status) && match(order.LinkID) {
+			return f.fillLocked(order, qty, order.Price)
+		}
+	}
+	return models.Fill{}, false
+}
+
+// redeliver повторно отправляет уже учтённое исполнение, как после переподключения WS.
+func (f *fakeClient) redeliver(rnd *rand.Rand) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.fills) == 0 {
+		return
+	}
+	fill := f.fills[rnd.Intn(len(f.fills))]
+	f.events <- exchange.Event{Type: exchange.EventTypeFill, Fill: &fill}
+}
+
+func (f *fakeClient) push(event exchange.Event) {
+	f.events <- event
+}
+
+func (f *fakeClient) openOrders() []models.Order {
+	orders, _ := f.GetOpenOrders(context.Background(), fakeSymbol)
+	return orders
+}
+
+func (f *fakeClient) violationList() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.violations...)
+}
+
+func newTestEngine(t *testing.T, client exchange.Client) *Engine {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Exchange.Name = "fake"
+	cfg.Bot = config.BotConfig{
+		Strategy:         "dca",
+		Symbol:           fakeSymbol,
+		Side:             "Buy",
+		BaseOrderQty:     1,
+		QtyUnit:          "baseCoin",
+		TPPercent:        1,
+		SOCount:          4,
+		SOStepPercent:    1,
+		SOStepMultiplier: 1,
+		SOBaseQty:        1,
+		SOQtyMultiplier:  1,
+		SOAnchor:         "entry",
+		SOStepMode:       "fixed",
+		OrderPolicy:      config.OrderPolicyCfg{OnCancel: "retry", OnReject: "alert", MaxRetries: 3},
+		Reconcile:        config.ReconcileCfg{Mode: "halt"},
+		Dust:             config.DustCfg{Mode: "carry"},
+	}
+	cfg.Runtime.StateFile = filepath.Join(t.TempDir(), "state.json")
+	cfg.Runtime.TickerStaleAfter = 30 * time.Second
+	return New(cfg, client, logger.New(logger.Config{Level: "panic"}))
+}
+
+func tickerEvent(seq int64) exchange.Event {
+	return exchange.Event{Type: exchange.EventTypeTicker, Ticker: &models.Ticker{
+		Symbol:    fakeSymbol,
+		LastPrice: fakePrice,
+		Timestamp: time.Now(),
+		Sequence:  seq,
+	}}
+}
+
+// waitFor опрашивает cond до истечения timeout.
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return cond()
+}
+
+func openTPOrders(orders []models.Order) []models.Order {
+	var tps []models.Order
+	for _, order := range orders {
+		if isTPLinkID(order.LinkID) {
+			tps = append(tps, order)
+		}
+	}
+	return tps
+}
+
+func TestEngineConcurrentEventsStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("стресс-тест пропущен в -short")
+	}
+	client := newFakeClient()
+	e := newTestEngine(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer e.Wait()
+	defer cancel()
+
+	client.push(tickerEvent(1))
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !waitFor(10*time.Second, func() bool { return len(openTPOrders(client.openOrders())) == 1 }) {
+		t.Fatalf("TP не поставлен после входа, open orders: %+v", client.openOrders())
+	}
+
+	var negative sync.Once
+	var negativeQty float64
+	stop := make(chan struct{})
+	var monitor sync.WaitGroup
+	monitor.Add(1)
+	go func() {
+		defer monitor.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			e.mu.Lock()
+			qty := e.state.TotalQty
+			e.mu.Unlock()
+			if qty < 0 {
+				negative.Do(func() { negativeQty = qty })
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var tickerSeq sync.Mutex
+	seq := int64(1)
+	nextTickerSeq := func() int64 {
+		tickerSeq.Lock()
+		defer tickerSeq.Unlock()
+		seq++
+		return seq
+	}
+	run := func(n int, interval time.Duration, fn func(rnd *rand.Rand)) {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < n; i++ {
+				fn(rnd)
+				time.Sleep(interval)
+			}
+		}(int64(n) * int64(interval))
+	}
+
+	// Страховочные ордера исполняются частями.
+	run(16, 150*time.Millisecond, func(rnd *rand.Rand) {
+		client.fill(isSafetyLinkID, 0.25+float64(rnd.Intn(3))*0.125)
+	})
+	// TP исполняется понемногу, позиция не закрывается.
+	run(12, 300*time.Millisecond, func(rnd *rand.Rand) {
+		client.fill(isTPLinkID, 0.05)
+	})
+	run(600, 5*time.Millisecond, func(rnd *rand.Rand) {
+		client.push(tickerEvent(nextTickerSeq()))
+	})
+	run(100, 30*time.Millisecond, func(rnd *rand.Rand) {
+		client.redeliver(rnd)
+	})
+	run(100, 30*time.Millisecond, func(rnd *rand.Rand) {
+		orders := client.openOrders()
+		if len(orders) == 0 {
+			return
+		}
+		// Устаревший статус ордера с прежним номером последовательности.
+		order := orders[rnd.Intn(len(orders))]
+		order.Sequence = 1
+		client.push(exchange.Event{Type: exchange.EventTypeOrder, Order: &order})
+	})
+	run(12, 250*time.Millisecond, func(rnd *rand.Rand) {
+		client.push(exchange.Event{Type: exchange.EventTypeReconnect})
+	})
+	wg.Wait()
+
+	// После всплеска событий бот должен прийти к одному TP на весь остаток позиции.
+	settled := waitFor(20*time.Second, func() bool {
+		tps := openTPOrders(client.openOrders())
+		if len(tps) != 1 {
+			return false
+		}
+		e.mu.Lock()
+		want := e.roundQty(e.state.TotalQty + e.state.CarriedDust)
+		e.mu.Unlock()
+		return isQtyZeroFor(math.Abs(tps[0].Qty-tps[0].FilledQty-want), 0.001)
+	})
+	close(stop)
+	monitor.Wait()
+
+	if negativeQty < 0 {
+		t.Errorf("TotalQty стал отрицательным: %f", negativeQty)
+	}
+	for _, violation := range client.violationList() {
+		t.Errorf("нарушение на бирже: %s", violation)
+	}
+
+	e.mu.Lock()
+	dealID := e.state.DealID
+	e.mu.Unlock()
+	open := client.openOrders()
+	if !settled {
+		t.Errorf("ожидался один живой TP на остаток позиции, open orders: %+v", open)
+	}
+	seen := map[string]bool{}
+	for _, order := range open {
+		if seen[order.LinkID] {
+			t.Errorf("дублирующийся link id среди открытых ордеров: %s", order.LinkID)
+		}
+		seen[order.LinkID] = true
+		if !strings.HasPrefix(order.LinkID, dealID+"-") {
+			t.Errorf("ордер %s не относится к сделке %s", order.LinkID, dealID)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.state.Active {
+		t.Fatalf("сделка неожиданно закрыта: %+v", e.state)
+	}
+	if e.halted != "" {
+		t.Fatalf("торговля остановлена: %s", e.halted)
+	}
+	position := e.state.Ledger.Position()
+	if !isQtyZeroFor(math.Abs(position-e.state.TotalQty), 0.001) {
+		t.Errorf("TotalQty %f не совпадает с позицией по исполнениям %f", e.state.TotalQty, position)
+	}
+}
+
+// startTestEngine запускает движок и ждёт TP первой сделки. Остановка - в t.Cleanup.
+func startTestEngine(t *testing.T, e *Engine, client *fakeClient) context.CancelFunc {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		e.Wait()
+	})
+	client.push(tickerEvent(1))
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !waitFor(10*time.Second, func() bool { return len(openTPOrders(client.openOrders())) == 1 }) {
+		t.Fatalf("TP не поставлен после входа, open orders: %+v", client.openOrders())
+	}
+	return cancel
+}
+
+// waitSingleTP ждёт один открытый TP с остатком want, удовлетворяющий match.
+func waitSingleTP(t *testing.T, client *fakeClient, want float64, match func(models.Order) bool) models.Order {
+	t.Helper()
+	var tp models.Order
+	ok := waitFor(10*time.Second, func() bool {
+		tps := openTPOrders(client.openOrders())
+		if len(tps) != 1 || !match(tps[0]) {
+			return false
+		}
+		tp = tps[0]
+		return math.Abs(tp.Qty-tp.FilledQty-want) < 1e-9
+	})
+	if !ok {
+		t.Fatalf("ожидался один TP на %f, open orders: %+v", want, client.openOrders())
+	}
+	return tp
+}
+
+func TestRebuildTPAmendsAfterPartialFillReplaced(t *testing.T) {
+	client := newFakeClient()
+	e := newTestEngine(t, client)
+	startTestEngine(t, e, client)
+
+	e.mu.Lock()
+	dealID := e.state.DealID
+	e.mu.Unlock()
+	linkIs := func(linkID string) func(string) bool {
+		return func(l string) bool { return l == linkID }
+	}
+	first := openTPOrders(client.openOrders())[0]
+
+	// Частично исполненный TP нельзя amend: следующая перестановка идёт через отмену.
+	if _, ok := client.fill(isTPLinkID, 0.3); !ok {
+		t.Fatal("TP не исполнен")
+	}
+	if _, ok := client.fill(linkIs(dealID+"-so-1"), 1); !ok {
+		t.Fatal("so-1 не исполнен")
+	}
+	second := waitSingleTP(t, client, 1.7, func(o models.Order) bool { return o.LinkID != first.LinkID })
+
+	e.mu.Lock()
+	tpFilled, tpOrderID := e.state.TPFilledQty, e.state.TPOrderID
+	e.mu.Unlock()
+	if tpFilled != 0 || tpOrderID != second.ID {
+		t.Fatalf("после перестановки TPFilledQty=%f TPOrderID=%q, ожидались 0 и %q", tpFilled, tpOrderID, second.ID)
+	}
+
+	// Новый TP не исполнялся: перестановка снова идёт через amend того же ордера.
+	if _, ok := client.fill(linkIs(dealID+"-so-2"), 1); !ok {
+		t.Fatal("so-2 не исполнен")
+	}
+	waitSingleTP(t, client, 2.7, func(o models.Order) bool { return o.ID == second.ID })
+	for _, violation := range client.violationList() {
+		t.Errorf("нарушение на бирже: %s", violation)
+	}
+}
+
+// restartTestEngine останавливает e и запускает новый движок с тем же файлом состояния.
+func restartTestEngine(t *testing.T, e *Engine, stop context.CancelFunc, client *fakeClient) *Engine {
+	t.Helper()
+	stop()
+	e.Wait()
+
+	restarted := New(e.cfg, client, e.log)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		restarted.Wait()
+	})
+	if err := restarted.Start(ctx); err != nil {
+		t.Fatalf("Start после рестарта: %v", err)
+	}
+	return restarted
+}
+
+func TestRestoreKeepsSavedDealState(t *testing.T) {
+	client := newFakeClient()
+	e := newTestEngine(t, client)
+	stop := startTestEngine(t, e, client)
+
+	e.apply(context.Background(), func() {
+		e.state.Ledger.Carried = 0.4
+		e.state.Ledger.Adopted = 0.2
+		e.state.TotalQty += 0.2
+		e.state.CarriedDust = 0.4
+		e.state.Dust = 0.05
+		for linkID, rec := range e.state.Orders {
+			if rec.Kind == models.OrderKindSafety {
+				rec.Retries = 2
+				rec.Reason = "rejected"
+				e.state.Orders[linkID] = rec
+			}
+		}
+		e.dust = DustState{Qty: 0.3, ByDeal: map[string]float64{"prev": 0.3}, Unconvertible: true}
+	})
+	e.saveState()
+	e.mu.Lock()
+	want, wantDust := e.state, e.dust
+	e.mu.Unlock()
+
+	restored := restartTestEngine(t, e, stop, client)
+	restored.mu.Lock()
+	got, gotDust := restored.state, restored.dust
+	restored.mu.Unlock()
+
+	if !got.Active || got.DealID != want.DealID {
+		t.Fatalf("сделка %q не восстановлена: %+v", want.DealID, got)
+	}
+	if got.Ledger != want.Ledger {
+		t.Errorf("Ledger = %+v, ожидался %+v", got.Ledger, want.Ledger)
+	}
+	if gotDust.Qty != wantDust.Qty || gotDust.Unconvertible != wantDust.Unconvertible || gotDust.ByDeal["prev"] != wantDust.ByDeal["prev"] {
+		t.Errorf("пыль = %+v, ожидалась %+v", gotDust, wantDust)
+	}
+	if len(want.Orders) == 0 {
+		t.Fatal("нет записей жизненного цикла ордеров до рестарта")
+	}
+	for linkID, rec := range want.Orders {
+		restoredRec, ok := got.Orders[linkID]
+		if !ok {
+			t.Errorf("запись ордера %s потеряна", linkID)
+			continue
+		}
+		if restoredRec.Retries != rec.Retries || restoredRec.Reason != rec.Reason || restoredRec.Kind != rec.Kind {
+			t.Errorf("запись ордера %s = %+v, ожидалась %+v", linkID, restoredRec, rec)
+		}
+	}
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{name: "TotalQty", got: got.TotalQty, want: want.TotalQty},
+		{name: "CarriedDust", got: got.CarriedDust, want: want.CarriedDust},
+		{name: "Dust", got: got.Dust, want: want.Dust},
+		{name: "Position", got: got.Ledger.Position(), want: want.Ledger.Position()},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %f, ожидалось %f", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestRestorePlacesTPWithCarriedDust(t *testing.T) {
+	client := newFakeClient()
+	e := newTestEngine(t, client)
+	stop := startTestEngine(t, e, client)
+
+	e.apply(context.Background(), func() {
+		e.state.Ledger.Carried = 0.5
+		e.state.CarriedDust = 0.5
+	})
+	e.saveState()
+	stop()
+	e.Wait()
+
+	// Пока бот остановлен, TP снят на бирже, а пыль осталась на счёте.
+	client.mu.Lock()
+	client.extraBase = 0.5
+	for _, order := range client.orders {
+		if isTPLinkID(order.LinkID) && isOpenStatus(order.Status) {
+			order.Status = models.OrderStatusCanceled
+		}
+	}
+	client.mu.Unlock()
+
+	restored := restartTestEngine(t, e, func() {}, client)
+	waitSingleTP(t, client, 1.5, func(models.Order) bool { return true })
+	restored.mu.Lock()
+	defer restored.mu.Unlock()
+	if restored.state.CarriedDust != 0.5 || restored.state.TotalQty != 1 {
+		t.Fatalf("CarriedDust=%f TotalQty=%f, ожидались 0.5 и 1", restored.state.CarriedDust, restored.state.TotalQty)
+	}
+}
+
+// fillTP исполняет открытый TP целиком.
+func fillTP(t *testing.T, client *fakeClient) {
+	t.Helper()
+	tps := openTPOrders(client.openOrders())
+	if len(tps) != 1 {
+		t.Fatalf("ожидался один TP, open orders: %+v", client.openOrders())
+	}
+	if _, ok := client.fill(isTPLinkID, tps[0].Qty-tps[0].FilledQty); !ok {
+		t.Fatal("TP не исполнен")
+	}
+	// Цена для входа следующего цикла.
+	client.push(tickerEvent(time.Now().UnixNano()))
+}
+
+func TestCloseDealDriftExcludesDealPosition(t *testing.T) {
+	client := newFakeClient()
+	e := newTestEngine(t, client)
+	e.cfg.Bot.Reconcile.Mode = reconcileIgnore
+	startTestEngine(t, e, client)
+
+	e.mu.Lock()
+	dealID := e.state.DealID
+	e.mu.Unlock()
+
+	// Позиция сделки по исполнениям - пыль 0.0005, остальное на счёте стороннее.
+	e.apply(context.Background(), func() { e.state.Ledger.Carried = 0.0005 })
+	client.mu.Lock()
+	client.extraBase = 0.5005
+	client.mu.Unlock()
+	fillTP(t, client)
+
+	if !waitFor(10*time.Second, func() bool {
+		status := e.Status()
+		return status.Deal.Active && status.Deal.DealID != dealID
+	}) {
+		t.Fatalf("новый цикл не открыт: %+v", e.Status())
+	}
+	status := e.Status()
+	if math.Abs(status.ExternalBase-0.5) > 1e-9 {
+		t.Errorf("ExternalBase = %f, ожидалось 0.5", status.ExternalBase)
+	}
+	if dust := status.Dust.Qty + status.Deal.CarriedDust; math.Abs(dust-0.0005) > 1e-9 {
+		t.Errorf("пыль сделки = %f, ожидалось 0.0005", dust)
+	}
+}
+
+func TestHaltClearsWhenDriftResolved(t *testing.T) {
+	tests := []struct {
+		name    string
+		closing bool
+	}{
+		{name: "open deal"},
+		{name: "close stage", closing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient()
+			e := newTestEngine(t, client)
+			startTestEngine(t, e, client)
+			ctx := context.Background()
+
+			e.mu.Lock()
+			dealID := e.state.DealID
+			e.mu.Unlock()
+
+			client.mu.Lock()
+			client.extraBase = 0.5
+			client.mu.Unlock()
+			if tt.closing {
+				fillTP(t, client)
+			} else {
+				e.apply(ctx, func() { e.state.LastFillAt = time.Time{} })
+				if err := e.checkBalanceDrift(ctx); err != nil {
+					t.Fatalf("checkBalanceDrift: %v", err)
+				}
+			}
+			if !waitFor(10*time.Second, func() bool { return e.haltReason() == "balance_drift" }) {
+				t.Fatalf("торговля не остановлена: %+v", e.Status())
+			}
+
+			// Расхождение ещё есть - остановка держится.
+			if err := e.checkBalanceDrift(ctx); err != nil {
+				t.Fatalf("checkBalanceDrift: %v", err)
+			}
+			if e.haltReason() == "" {
+				t.Fatal("остановка снята при неустранённом расхождении")
+			}
+
+			client.mu.Lock()
+			client.extraBase = 0
+			client.mu.Unlock()
+			if err := e.checkBalanceDrift(ctx); err != nil {
+				t.Fatalf("checkBalanceDrift: %v", err)
+			}
+			if reason := e.haltReason(); reason != "" {
+				t.Fatalf("остановка не снята: %s", reason)
+			}
+			if tt.closing && !waitFor(10*time.Second, func() bool {
+				status := e.Status()
+				return status.Deal.Active && status.Deal.DealID != dealID
+			}) {
+				t.Fatalf("закрытие не продолжено после снятия остановки: %+v", e.Status())
+			}
+		})
+	}
+}
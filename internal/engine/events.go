@@ -4,18 +4,30 @@ import (
 	"context"
 	"dcabot/internal/exchange"
 	"dcabot/internal/models"
+	"math"
 	"time"
 )
 
 func (e *Engine) handleEvents(ctx context.Context, events <-chan exchange.Event) {
+	ctx = loopContext(ctx)
+	timer := time.NewTicker(strategyTimerInterval)
+	defer timer.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case now := <-timer.C:
+			e.dispatch(ctx, StrategyEvent{Type: StrategyEventTimer, At: now})
+		case <-e.inbox.ready:
+			for _, fn := range e.inbox.drain() {
+				fn(ctx)
+			}
 		case event, ok := <-events:
 			if !ok {
 				e.logEntry().Warn("Канал событий WS закрыт.")
-				return
+				events = nil
+				continue
 			}
 			switch event.Type {
 			case exchange.EventTypeFill:
@@ -31,44 +43,83 @@ func (e *Engine) handleEvents(ctx context.Context, events <-chan exchange.Event)
 					e.handleTicker(ctx, *event.Ticker)
 				}
 			case exchange.EventTypeReconnect:
-				e.logEntry().Info("Получен сигнал реконнекта WS, сверка ордеров.")
-				if err := e.syncOpenOrders(ctx); err != nil {
-					e.logEntry().WithError(err).Warn("Не удалось сверить ордера после реконнекта.")
-				}
+				e.logEntry().Info("Получен сигнал реконнекта WS, восстановление исполнений и сверка ордеров.")
+				e.handleReconnect()
 			}
 		}
 	}
 }
 
+// handleReconnect загружает пропущенные исполнения в пуле, применяет их в цикле событий
+// и только после этого запускает сверку ордеров.
+// Исполнения применяются через post из самой задачи: done может быть вызван и для
+// отброшенного повтора реконнекта с результатом ожидающей задачи.
+func (e *Engine) handleReconnect() {
+	e.submit(jobDeal, "recover", func(ctx context.Context) error {
+		missed, since, err := e.fetchMissedFills(ctx)
+		if err != nil {
+			return err
+		}
+		e.post(func(ctx context.Context) {
+			e.replayMissedFills(ctx, missed, since)
+		})
+		return nil
+	}, func(ctx context.Context, err error) {
+		if err != nil {
+			e.logEntry().WithError(err).Warn("Не удалось восстановить исполнения после реконнекта, сверка ордеров пропущена.")
+			e.dispatch(ctx, StrategyEvent{Type: StrategyEventReconnect})
+			return
+		}
+		e.submit(jobDeal, "sync", e.syncOpenOrders, func(ctx context.Context, err error) {
+			if err != nil {
+				e.logEntry().WithError(err).Warn("Не удалось сверить ордера после реконнекта.")
+			}
+			e.dispatch(ctx, StrategyEvent{Type: StrategyEventReconnect})
+		})
+	})
+}
+
 func (e *Engine) syncOpenOrders(ctx context.Context) error {
-	if !e.state.Active {
+	if e.strategy() == strategyGrid {
+		return e.syncGridOrders(ctx)
+	}
+	if !e.isActive() {
 		return nil
 	}
-	e.ensureStateMaps()
-	e.ensureDealID()
+
 	openOrders, err := e.withRetryOrders(ctx, e.cfg.Bot.Symbol)
 	if err != nil {
 		return err
 	}
 
+	var tpLinkID string
 	tpFound := false
 	safetyFound := make(map[string]bool)
-	for linkID := range e.state.SafetyOrders {
-		safetyFound[linkID] = false
-	}
+	filledByLink := make(map[string]float64)
+	e.apply(ctx, func() {
+		e.ensureStateMaps()
+		e.ensureDealID()
+		tpLinkID = e.state.TPlinkID
+		for linkID := range e.state.SafetyOrders {
+			safetyFound[linkID] = false
+		}
 
-	for _, order := range openOrders {
-		if order.LinkID == e.state.TPlinkID {
-			e.state.TPOrderID = order.ID
-			tpFound = true
+		for _, order := range openOrders {
+			if order.LinkID == tpLinkID {
+				e.state.TPOrderID = order.ID
+				tpFound = true
+			}
+			if _, ok := safetyFound[order.LinkID]; ok {
+				e.state.SafetyOrders[order.LinkID] = order.ID
+				safetyFound[order.LinkID] = true
+			}
 		}
-		if _, ok := safetyFound[order.LinkID]; ok {
-			e.state.SafetyOrders[order.LinkID] = order.ID
-			safetyFound[order.LinkID] = true
+		for linkID, qty := range e.state.FilledByLink {
+			filledByLink[linkID] = qty
 		}
-	}
+	})
 
-	if !tpFound && e.state.TPlinkID != "" {
+	if !tpFound && tpLinkID != "" {
 		e.logEntry().Warn("TP не найден. Перестановка.")
 		if err := e.rebuildTP(ctx); err != nil {
 			return err
@@ -76,9 +127,20 @@ func (e *Engine) syncOpenOrders(ctx context.Context) error {
 	}
 
 	for linkID, found := range safetyFound {
-		if !found {
-			e.logEntry().WithField("link_id", linkID).Warn("Страховочный ордер не найден, попытка перестановки.")
+		if found {
+			continue
 		}
+		if filledByLink[linkID] > 0 {
+			e.logEntry().WithField("link_id", linkID).Debug("Страховочный ордер исполнен, перестановка не нужна.")
+			continue
+		}
+		e.logEntry().WithField("link_id", linkID).Warn("Страховочный ордер не найден, попытка перестановки.")
+		e.resolveLostOrder(ctx, OrderRecord{
+			LinkID: linkID,
+			Kind:   models.OrderKindSafety,
+			Status: models.OrderStatusCanceled,
+			Reason: "not_found",
+		})
 	}
 
 	if err := e.rebuildMissingSafetyOrders(ctx); err != nil {
@@ -89,6 +151,19 @@ func (e *Engine) syncOpenOrders(ctx context.Context) error {
 }
 
 func (e *Engine) handleFill(ctx context.Context, fill models.Fill) {
+	switch e.strategy() {
+	case strategyAccumulate:
+		if isAccumulationLinkID(fill.LinkID) {
+			e.onAccumulationFill(fill)
+		}
+		return
+	case strategyGrid:
+		if isGridLinkID(fill.LinkID) {
+			e.onGridFill(ctx, fill)
+		}
+		return
+	}
+
 	e.mu.Lock()
 	e.ensureStateMaps()
 
@@ -115,24 +190,35 @@ func (e *Engine) handleFill(ctx context.Context, fill models.Fill) {
 	} else {
 		e.state.LastFillAt = time.Now()
 	}
+	isTP := isTPLinkID(fill.LinkID) || (e.state.TPlinkID != "" && e.state.TPlinkID == fill.LinkID) || (e.state.TPOrderID != "" && e.state.TPOrderID == fill.OrderID)
+	side := e.state.Side
 	e.mu.Unlock()
 
-	if isTPLinkID(fill.LinkID) || e.state.TPlinkID == fill.LinkID || (e.state.TPOrderID != "" && e.state.TPOrderID == fill.OrderID) {
-		e.onTPFill(ctx, fill)
+	if !isTP && isExternalFill(fill) {
+		e.onExternalFill(ctx, fill)
 		return
 	}
 
-	if fill.Side != e.state.Side {
+	if isTP {
+		e.onTPFill(ctx, fill)
+	} else if fill.Side == side {
+		e.onPositionIncrease(ctx, fill)
+	} else {
+		e.onPositionDecrease(ctx, fill)
 		return
 	}
 
-	e.onPositionIncrease(ctx, fill)
+	e.dispatch(ctx, StrategyEvent{Type: StrategyEventFill, Fill: &fill, At: fill.Timestamp})
 }
 
 func (e *Engine) handleOrder(ctx context.Context, order models.Order) {
+	if e.strategy() != strategyDCA {
+		return
+	}
 	e.mu.Lock()
 	if order.Status == models.OrderStatusCanceled && order.ID == e.state.TPOrderID {
 		e.state.TPOrderID = ""
+		e.state.TPFilledQty = 0
 	}
 	if order.Sequence > 0 && order.Sequence <= e.state.LastOrderSeq {
 		e.mu.Unlock()
@@ -149,6 +235,7 @@ func (e *Engine) handleOrder(ctx context.Context, order models.Order) {
 		} else {
 			e.state.TotalQty = 0
 		}
+		e.state.CarriedDust = 0
 		totalQty = e.state.TotalQty
 	}
 	e.mu.Unlock()
@@ -156,11 +243,13 @@ func (e *Engine) handleOrder(ctx context.Context, order models.Order) {
 	if isTP && order.Status == models.OrderStatusFilled {
 		if e.isQtyZero(totalQty) {
 			e.logEntry().WithField("order_id", order.ID).Info("TP полностью исполнен по статусу ордера.")
-			e.requestClose(ctx, "TP полностью исполнен (order status).")
 		} else {
 			e.logEntry().WithField("order_id", order.ID).Warn("TP отмечен как исполненный, но позиция ещё есть, делка продолжается.")
 		}
 	}
+
+	e.trackOrder(ctx, order)
+	e.dispatch(ctx, StrategyEvent{Type: StrategyEventOrder, Order: &order})
 }
 
 func (e *Engine) handleTicker(ctx context.Context, ticker models.Ticker) {
@@ -173,6 +262,9 @@ func (e *Engine) handleTicker(ctx context.Context, ticker models.Ticker) {
 	if ticker.Sequence > 0 {
 		e.state.LastTickerSeq = ticker.Sequence
 	}
+	if ticker.Timestamp.IsZero() {
+		ticker.Timestamp = now
+	}
 	e.state.LastTicker = ticker
 	e.state.UpdatedAt = now
 
@@ -185,17 +277,37 @@ func (e *Engine) handleTicker(ctx context.Context, ticker models.Ticker) {
 	totalQty := e.state.TotalQty
 	avgPrice := e.state.AvgPrice
 
-	if now.Sub(e.lastTickerLog) < 1*time.Second {
-		e.mu.Unlock()
-		return
+	throttled := now.Sub(e.lastTickerLog) < 1*time.Second
+	if !throttled {
+		e.lastTickerLog = now
 	}
-	e.lastTickerLog = now
 	e.mu.Unlock()
 
-	if !active || dealID == "" {
+	e.dispatch(ctx, StrategyEvent{Type: StrategyEventTicker, Ticker: &ticker, At: now})
+
+	if throttled || !active || dealID == "" {
 		return
 	}
 
+	fields := map[string]interface{}{
+		"symbol":      ticker.Symbol,
+		"price":       ticker.LastPrice,
+		"seq":         ticker.Sequence,
+		"ts":          ticker.Timestamp.UnixMilli(),
+		"tp_price":    tpPrice,
+		"tp_qty":      tpQty,
+		"tp_order_id": tpOrderID,
+		"tp_link_id":  tpLinkID,
+		"total_qty":   totalQty,
+		"avg_price":   avgPrice,
+	}
+	e.submit(jobBalances, "ticker", func(ctx context.Context) error {
+		e.logTickerBalances(ctx, fields)
+		return nil
+	}, nil)
+}
+
+func (e *Engine) logTickerBalances(ctx context.Context, fields map[string]interface{}) {
 	base := e.rules.BaseCoin
 	quote := e.rules.QuoteCoin
 	baseBal := 0.0
@@ -228,20 +340,8 @@ func (e *Engine) handleTicker(ctx context.Context, ticker models.Ticker) {
 		}
 	}
 
-	fields := map[string]interface{}{
-		"symbol":        ticker.Symbol,
-		"price":         ticker.LastPrice,
-		"seq":           ticker.Sequence,
-		"ts":            ticker.Timestamp.UnixMilli(),
-		"balance_base":  baseBal,
-		"balance_quote": quoteBal,
-		"tp_price":      tpPrice,
-		"tp_qty":        tpQty,
-		"tp_order_id":   tpOrderID,
-		"tp_link_id":    tpLinkID,
-		"total_qty":     totalQty,
-		"avg_price":     avgPrice,
-	}
+	fields["balance_base"] = baseBal
+	fields["balance_quote"] = quoteBal
 	if balanceErr != "" {
 		fields["balance_error"] = balanceErr
 	}
@@ -251,11 +351,17 @@ func (e *Engine) handleTicker(ctx context.Context, ticker models.Ticker) {
 func (e *Engine) onTPFill(ctx context.Context, fill models.Fill) {
 	e.mu.Lock()
 	e.ensureStateMaps()
-	e.state.TPFilledQty += fill.Qty
+	if fill.LinkID == e.state.TPlinkID {
+		e.state.TPFilledQty += fill.Qty
+	}
+	e.realizePnL(fill.Price, fill.Qty)
+	e.chargeFee(fill)
 	e.state.TotalQty -= fill.Qty
 	if e.state.TotalQty < 0 {
+		e.state.CarriedDust = math.Max(e.state.CarriedDust+e.state.TotalQty, 0)
 		e.state.TotalQty = 0
 	}
+	e.state.Ledger.Closed += fill.Qty
 	e.state.UpdatedAt = time.Now()
 	totalQty := e.state.TotalQty
 	e.mu.Unlock()
@@ -268,15 +374,43 @@ func (e *Engine) onTPFill(ctx context.Context, fill models.Fill) {
 		"total_qty": totalQty,
 	}).Info("fill TP")
 
-	if e.isQtyZero(totalQty) {
-		e.requestClose(ctx, "TP полностью исполнен.")
-	}
-
 	if totalQty > 0 {
 		e.logEntry().WithField("order_id", fill.OrderID).Info("Частичное исполнение TP/")
 	}
 }
 
+// onPositionDecrease учитывает встречное исполнение ордера сделки не по TP: позиция
+// уменьшается, а результат входит в PnL сделки так же, как исполнение TP.
+func (e *Engine) onPositionDecrease(ctx context.Context, fill models.Fill) {
+	e.mu.Lock()
+	if !e.state.Active {
+		e.mu.Unlock()
+		return
+	}
+	e.ensureStateMaps()
+	e.state.FilledByLink[fill.LinkID] += fill.Qty
+	closed := math.Min(fill.Qty, e.state.TotalQty)
+	e.realizePnL(fill.Price, closed)
+	e.chargeFee(fill)
+	e.state.TotalQty -= closed
+	e.state.Ledger.Closed += fill.Qty
+	e.state.UpdatedAt = time.Now()
+	totalQty := e.state.TotalQty
+	pnl := e.state.RealizedPnL
+	e.mu.Unlock()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"link_id":      fill.LinkID,
+		"order_id":     fill.OrderID,
+		"qty":          fill.Qty,
+		"price":        fill.Price,
+		"total_qty":    totalQty,
+		"realized_pnl": pnl,
+	}).Info("Позиция сделки уменьшена исполнением не по TP.")
+	e.saveState()
+	e.applyAdoptedQty(ctx, totalQty, "close_fill")
+}
+
 func (e *Engine) onPositionIncrease(ctx context.Context, fill models.Fill) {
 	e.mu.Lock()
 	e.ensureStateMaps()
@@ -285,6 +419,8 @@ func (e *Engine) onPositionIncrease(ctx context.Context, fill models.Fill) {
 	totalCost := e.state.AvgPrice*e.state.TotalQty + fill.Price*fill.Qty
 	e.state.TotalQty += fill.Qty
 	e.state.AvgPrice = CalcAvgPrice(totalCost, e.state.TotalQty)
+	e.state.Ledger.Opened += fill.Qty
+	e.chargeFee(fill)
 	e.state.UpdatedAt = time.Now()
 	newAvg := e.state.AvgPrice
 	totalQty := e.state.TotalQty
@@ -294,6 +430,18 @@ func (e *Engine) onPositionIncrease(ctx context.Context, fill models.Fill) {
 		e.state.CloseRequested = false
 		e.state.CloseReason = ""
 	}
+	prevAnchorLevel := e.state.GridAnchorLevel
+	reanchored := false
+	if level, ok := safetyLevelFromLinkID(fill.LinkID); ok && prevFilled == 0 && e.soAnchor() != soAnchorEntry && level > prevAnchorLevel {
+		e.state.GridAnchorLevel = level
+		e.state.GridAnchorPrice = fill.Price
+		if e.soAnchor() == soAnchorAvgPrice {
+			e.state.GridAnchorPrice = newAvg
+		}
+		reanchored = true
+	}
+	anchorLevel := e.state.GridAnchorLevel
+	anchorPrice := e.state.GridAnchorPrice
 	e.mu.Unlock()
 
 	if prevFilled > 0 {
@@ -326,5 +474,13 @@ func (e *Engine) onPositionIncrease(ctx context.Context, fill models.Fill) {
 		e.logEntry().Info("Закрытие отменено: поступило новое исполнение, сделка продолжается.")
 	}
 
-	e.scheduleTPRebuild(ctx)
+	if reanchored {
+		e.saveState()
+		e.logEntry().WithFields(map[string]interface{}{
+			"anchor":       e.soAnchor(),
+			"prev_level":   prevAnchorLevel,
+			"level":        anchorLevel,
+			"anchor_price": anchorPrice,
+		}).Info("Пересчёт оставшейся сетки страховочных ордеров.")
+	}
 }
@@ -0,0 +1,534 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const strategyGrid = "grid"
+
+type GridState struct {
+	ID               string             `json:"id"`
+	Levels           []float64          `json:"levels"`
+	Orders           map[int]GridOrder  `json:"orders"`
+	FilledByLink     map[string]float64 `json:"filled_by_link"`
+	ProcessedExecIDs map[string]bool    `json:"processed_exec_ids"`
+	RoundTrips       int                `json:"round_trips"`
+	RealizedProfit   float64            `json:"realized_profit"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+type GridOrder struct {
+	LinkID  string           `json:"link_id"`
+	OrderID string           `json:"order_id"`
+	Side    models.OrderSide `json:"side"`
+	Price   float64          `json:"price"`
+	Qty     float64          `json:"qty"`
+}
+
+func isGridLinkID(linkID string) bool {
+	return strings.Contains(linkID, "-gb-") || strings.Contains(linkID, "-gs-") || strings.Contains(linkID, "-gi-")
+}
+
+func gridLevelFromLinkID(linkID string) (int, models.OrderSide, bool) {
+	side := models.OrderSideBuy
+	idx := strings.LastIndex(linkID, "-gb-")
+	if idx == -1 {
+		side = models.OrderSideSell
+		idx = strings.LastIndex(linkID, "-gs-")
+	}
+	if idx == -1 {
+		return 0, "", false
+	}
+	raw := linkID[idx+len("-gb-"):]
+	if dash := strings.IndexByte(raw, '-'); dash != -1 {
+		raw = raw[:dash]
+	}
+	level, err := strconv.Atoi(raw)
+	if err != nil || level < 0 {
+		return 0, "", false
+	}
+	return level, side, true
+}
+
+func (e *Engine) gridLinkID(side models.OrderSide, level int) string {
+	e.mu.Lock()
+	e.tpSeq++
+	seq := e.tpSeq
+	gridID := e.grid.ID
+	e.mu.Unlock()
+
+	prefix := "gb"
+	if side == models.OrderSideSell {
+		prefix = "gs"
+	}
+	return fmt.Sprintf("%s-%s-%d-%d-%d", gridID, prefix, level, time.Now().Unix(), seq%1000)
+}
+
+func (e *Engine) startGrid(ctx context.Context) error {
+	gridCfg := e.cfg.Bot.Grid
+	if gridCfg.LowerPrice <= 0 || gridCfg.UpperPrice <= gridCfg.LowerPrice {
+		return fmt.Errorf("Некорректные границы сетки: lower=%f upper=%f", gridCfg.LowerPrice, gridCfg.UpperPrice)
+	}
+	if gridCfg.Levels < 2 {
+		return fmt.Errorf("Количество уровней сетки должно быть не меньше 2: %d", gridCfg.Levels)
+	}
+	if gridCfg.QtyPerLevel <= 0 {
+		return fmt.Errorf("Не задан объём уровня сетки grid.qty_per_level.")
+	}
+
+	raw := CalcGridLevels(gridCfg.LowerPrice, gridCfg.UpperPrice, gridCfg.Levels, strings.EqualFold(gridCfg.Spacing, "geometric"))
+	levels := make([]float64, len(raw))
+	for i, price := range raw {
+		levels[i] = e.roundPrice(price)
+	}
+
+	restored, err := e.restoreGrid(ctx, levels)
+	if err != nil {
+		return err
+	}
+
+	var gridID string
+	e.apply(ctx, func() {
+		if e.grid.ID == "" {
+			e.grid.ID = newDealID()
+		}
+		e.grid.Levels = levels
+		gridID = e.grid.ID
+	})
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"grid_id":  gridID,
+		"lower":    gridCfg.LowerPrice,
+		"upper":    gridCfg.UpperPrice,
+		"levels":   len(levels),
+		"spacing":  gridCfg.Spacing,
+		"qty":      gridCfg.QtyPerLevel,
+		"restored": restored,
+	}).Info("Запуск сеточной стратегии.")
+
+	if !restored && gridCfg.InitialBuy {
+		if err := e.gridInitialBuy(ctx); err != nil {
+			return err
+		}
+	}
+
+	return e.fillGridGaps(ctx)
+}
+
+func (e *Engine) restoreGrid(ctx context.Context, levels []float64) (bool, error) {
+	e.apply(ctx, e.ensureGridMaps)
+
+	openOrders, err := e.withRetryOrders(ctx, e.cfg.Bot.Symbol)
+	if err != nil {
+		return false, err
+	}
+	grids := make(map[string][]models.Order)
+	for _, ord := range openOrders {
+		if !isGridLinkID(ord.LinkID) {
+			continue
+		}
+		gridID, ok := dealIDFromLinkID(ord.LinkID)
+		if !ok {
+			continue
+		}
+		grids[gridID] = append(grids[gridID], ord)
+	}
+	if len(grids) == 0 {
+		return false, nil
+	}
+
+	gridID := pickDealID(grids)
+	orders := make(map[int]GridOrder)
+	for _, ord := range grids[gridID] {
+		level, side, ok := gridLevelFromLinkID(ord.LinkID)
+		if !ok || level >= len(levels) {
+			e.logEntry().WithField("link_id", ord.LinkID).Warn("Ордер сетки вне текущих уровней, пропуск.")
+			continue
+		}
+		orders[level] = GridOrder{
+			LinkID:  ord.LinkID,
+			OrderID: ord.ID,
+			Side:    side,
+			Price:   ord.Price,
+			Qty:     ord.Qty,
+		}
+	}
+
+	fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: e.cfg.Bot.Symbol})
+	if err != nil {
+		return false, err
+	}
+	filledByLink := map[string]float64{}
+	processedExecIDs := map[string]bool{}
+	for _, fill := range fills {
+		if !strings.HasPrefix(fill.LinkID, gridID+"-") {
+			continue
+		}
+		filledByLink[fill.LinkID] += fill.Qty
+		if fill.ExecID != "" {
+			processedExecIDs[fill.ExecID] = true
+		}
+	}
+
+	e.apply(ctx, func() {
+		e.grid.ID = gridID
+		e.grid.Orders = orders
+		e.grid.FilledByLink = filledByLink
+		e.grid.ProcessedExecIDs = processedExecIDs
+		e.grid.UpdatedAt = time.Now()
+	})
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"grid_id": gridID,
+		"orders":  len(orders),
+	}).Info("Восстановлены ордера сетки.")
+	return true, nil
+}
+
+func (e *Engine) gridLevelQty(price float64) float64 {
+	qty := e.cfg.Bot.Grid.QtyPerLevel
+	if strings.EqualFold(e.qtyUnit(), "quoteCoin") && price > 0 {
+		qty = qty / price
+	}
+	return e.roundQty(qty)
+}
+
+func (e *Engine) nearestGridLevel(price float64) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	nearest := -1
+	best := math.MaxFloat64
+	for i, level := range e.grid.Levels {
+		if diff := math.Abs(level - price); diff < best {
+			best = diff
+			nearest = i
+		}
+	}
+	return nearest
+}
+
+func (e *Engine) gridInitialBuy(ctx context.Context) error {
+	price, err := e.waitForTickerPrice(ctx, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	skip := e.nearestGridLevel(price)
+
+	e.mu.Lock()
+	levels := append([]float64(nil), e.grid.Levels...)
+	gridID := e.grid.ID
+	e.mu.Unlock()
+
+	need := 0.0
+	for i, level := range levels {
+		if i == skip || level <= price {
+			continue
+		}
+		need += e.gridLevelQty(level)
+	}
+	if have, err := e.baseAvailable(ctx); err == nil {
+		need -= have
+	}
+	need = e.roundQty(need)
+	if need < e.rules.MinQty {
+		return nil
+	}
+
+	linkID := fmt.Sprintf("%s-gi-%d", gridID, time.Now().Unix())
+	order := models.Order{
+		Symbol:      e.cfg.Bot.Symbol,
+		Side:        models.OrderSideBuy,
+		Type:        models.OrderTypeMarket,
+		Kind:        models.OrderKindEntry,
+		Qty:         need,
+		LinkID:      linkID,
+		TimeInForce: "IOC",
+		MarketUnit:  "baseCoin",
+		QtyStep:     e.rules.LotSize,
+	}
+	if err := e.validateMinNotional(order, price); err != nil {
+		return err
+	}
+	e.logEntry().WithFields(map[string]interface{}{
+		"link_id": linkID,
+		"qty":     need,
+		"price":   price,
+	}).Info("Начальная покупка базовой монеты для продаж сетки.")
+	if _, err := e.placeOrderIdempotent(ctx, order); err != nil {
+		return err
+	}
+	_, _, err = e.waitEntryFill(ctx, linkID)
+	return err
+}
+
+func (e *Engine) fillGridGaps(ctx context.Context) error {
+	price, err := e.waitForTickerPrice(ctx, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	skip := e.nearestGridLevel(price)
+
+	e.mu.Lock()
+	levels := append([]float64(nil), e.grid.Levels...)
+	occupied := make(map[int]bool, len(e.grid.Orders))
+	for level := range e.grid.Orders {
+		occupied[level] = true
+	}
+	e.mu.Unlock()
+
+	for i, level := range levels {
+		if i == skip || occupied[i] {
+			continue
+		}
+		side := models.OrderSideBuy
+		if level > price {
+			side = models.OrderSideSell
+		}
+		if err := e.placeGridOrder(ctx, i, side, e.gridLevelQty(level)); err != nil {
+			e.logEntry().WithError(err).WithFields(map[string]interface{}{
+				"level": i,
+				"side":  side,
+				"price": level,
+			}).Warn("Не удалось выставить ордер сетки.")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// placeGridOrder занимает уровень под e.mu до запроса к бирже: пока ордер выставляется,
+// уровень считается занятым, и повторная постановка на него пропускается.
+func (e *Engine) placeGridOrder(ctx context.Context, level int, side models.OrderSide, qty float64) error {
+	e.mu.Lock()
+	if level < 0 || level >= len(e.grid.Levels) {
+		e.mu.Unlock()
+		return nil
+	}
+	price := e.grid.Levels[level]
+	e.mu.Unlock()
+
+	if qty < e.rules.MinQty {
+		return fmt.Errorf("Объём ордера сетки меньше минимального: %f", qty)
+	}
+	if err := e.validateMinNotional(models.Order{Price: price, Qty: qty, Type: models.OrderTypeLimit}, price); err != nil {
+		return err
+	}
+
+	linkID := e.gridLinkID(side, level)
+	var occupiedBy string
+	e.apply(ctx, func() {
+		e.ensureGridMaps()
+		if current, occupied := e.grid.Orders[level]; occupied {
+			occupiedBy = current.LinkID
+			return
+		}
+		e.grid.Orders[level] = GridOrder{
+			LinkID: linkID,
+			Side:   side,
+			Price:  price,
+			Qty:    qty,
+		}
+	})
+	if occupiedBy != "" {
+		e.logEntry().WithFields(map[string]interface{}{
+			"level":   level,
+			"side":    side,
+			"link_id": occupiedBy,
+		}).Debug("Уровень сетки уже занят, пропуск постановки.")
+		return nil
+	}
+
+	order := models.Order{
+		Symbol:      e.cfg.Bot.Symbol,
+		Side:        side,
+		Type:        models.OrderTypeLimit,
+		Kind:        models.OrderKindSafety,
+		Price:       price,
+		Qty:         qty,
+		LinkID:      linkID,
+		TimeInForce: "GTC",
+		PriceStep:   e.rules.TickSize,
+		QtyStep:     e.rules.LotSize,
+	}
+	e.logEntry().WithFields(map[string]interface{}{
+		"level":   level,
+		"side":    side,
+		"link_id": linkID,
+		"price":   price,
+		"qty":     qty,
+	}).Info("Постановка ордера сетки.")
+	placed, err := e.placeOrderIdempotent(ctx, order)
+
+	e.apply(ctx, func() {
+		current, ok := e.grid.Orders[level]
+		ours := ok && current.LinkID == linkID
+		if err != nil {
+			if ours {
+				delete(e.grid.Orders, level)
+			}
+			return
+		}
+		// Ордер мог исполниться раньше ответа REST: тогда уровень уже освобождён onGridFill.
+		if ours {
+			current.OrderID = placed.ID
+			e.grid.Orders[level] = current
+		}
+		e.grid.UpdatedAt = time.Now()
+	})
+	if err != nil {
+		return err
+	}
+	e.log.WithOrderID(placed.ID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("Ордер сетки поставлен.")
+	return nil
+}
+
+func (e *Engine) onGridFill(ctx context.Context, fill models.Fill) {
+	level, side, ok := gridLevelFromLinkID(fill.LinkID)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	e.ensureGridMaps()
+	if fill.ExecID != "" {
+		if e.grid.ProcessedExecIDs[fill.ExecID] {
+			e.mu.Unlock()
+			return
+		}
+		e.grid.ProcessedExecIDs[fill.ExecID] = true
+	}
+	e.grid.FilledByLink[fill.LinkID] += fill.Qty
+	filled := e.grid.FilledByLink[fill.LinkID]
+	current, tracked := e.grid.Orders[level]
+	if !tracked || current.LinkID != fill.LinkID || filled < current.Qty-(e.rules.LotSize/2) {
+		e.mu.Unlock()
+		e.logEntry().WithFields(map[string]interface{}{
+			"level":   level,
+			"link_id": fill.LinkID,
+			"qty":     fill.Qty,
+			"filled":  filled,
+		}).Info("Частичное исполнение ордера сетки.")
+		return
+	}
+	delete(e.grid.Orders, level)
+	next := level + 1
+	nextSide := models.OrderSideSell
+	if side == models.OrderSideSell {
+		next = level - 1
+		nextSide = models.OrderSideBuy
+		if next >= 0 && next < len(e.grid.Levels) {
+			e.grid.RoundTrips++
+			e.grid.RealizedProfit += (current.Price - e.grid.Levels[next]) * current.Qty
+		}
+	}
+	roundTrips := e.grid.RoundTrips
+	profit := e.grid.RealizedProfit
+	e.grid.UpdatedAt = time.Now()
+	e.mu.Unlock()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"level":       level,
+		"side":        side,
+		"link_id":     fill.LinkID,
+		"price":       current.Price,
+		"qty":         current.Qty,
+		"round_trips": roundTrips,
+		"profit":      profit,
+	}).Info("Исполнен ордер сетки.")
+
+	qty := current.Qty
+	if nextSide == models.OrderSideBuy {
+		e.mu.Lock()
+		nextPrice := 0.0
+		if next >= 0 && next < len(e.grid.Levels) {
+			nextPrice = e.grid.Levels[next]
+		}
+		e.mu.Unlock()
+		qty = e.gridLevelQty(nextPrice)
+	}
+
+	e.submit(jobDeal, "", func(ctx context.Context) error {
+		return e.placeGridOrder(ctx, next, nextSide, qty)
+	}, func(ctx context.Context, err error) {
+		if err != nil {
+			e.logEntry().WithError(err).WithFields(map[string]interface{}{
+				"level": next,
+				"side":  nextSide,
+			}).Warn("Не удалось выставить встречный ордер сетки.")
+		}
+	})
+}
+
+func (e *Engine) syncGridOrders(ctx context.Context) error {
+	fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: e.cfg.Bot.Symbol})
+	if err != nil {
+		return err
+	}
+	e.call(ctx, func(ctx context.Context) {
+		e.mu.Lock()
+		prefix := e.grid.ID + "-"
+		e.mu.Unlock()
+		for _, fill := range fills {
+			if strings.HasPrefix(fill.LinkID, prefix) && isGridLinkID(fill.LinkID) {
+				e.onGridFill(ctx, fill)
+			}
+		}
+	})
+
+	// Сверяются только ордера, выставленные до запроса открытых ордеров: более новые
+	// могли не попасть в ответ биржи.
+	e.mu.Lock()
+	placed := make(map[string]bool, len(e.grid.Orders))
+	for _, ord := range e.grid.Orders {
+		if ord.OrderID != "" {
+			placed[ord.LinkID] = true
+		}
+	}
+	e.mu.Unlock()
+
+	openOrders, err := e.withRetryOrders(ctx, e.cfg.Bot.Symbol)
+	if err != nil {
+		return err
+	}
+	open := make(map[string]bool, len(openOrders))
+	for _, ord := range openOrders {
+		open[ord.LinkID] = true
+	}
+
+	e.apply(ctx, func() {
+		for level, ord := range e.grid.Orders {
+			if placed[ord.LinkID] && !open[ord.LinkID] {
+				e.logEntry().WithFields(map[string]interface{}{
+					"level":   level,
+					"link_id": ord.LinkID,
+				}).Warn("Ордер сетки не найден, будет переставлен.")
+				delete(e.grid.Orders, level)
+			}
+		}
+	})
+
+	return e.fillGridGaps(ctx)
+}
+
+func (e *Engine) ensureGridMaps() {
+	if e.grid.Orders == nil {
+		e.grid.Orders = map[int]GridOrder{}
+	}
+	if e.grid.FilledByLink == nil {
+		e.grid.FilledByLink = map[string]float64{}
+	}
+	if e.grid.ProcessedExecIDs == nil {
+		e.grid.ProcessedExecIDs = map[string]bool{}
+	}
+}
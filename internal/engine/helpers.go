@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"dcabot/internal/exchange"
 	"dcabot/internal/models"
 	"fmt"
 	"math"
@@ -11,10 +12,13 @@ import (
 	"github.com/google/uuid"
 )
 
+// retryAttempts - число попыток запроса к бирже во всех withRetry* помощниках.
+const retryAttempts = 5
+
 func (e *Engine) withRetry(ctx context.Context, fn func() (models.Order, error)) (models.Order, error) {
 	var lastErr error
 	var backoff time.Duration = 1 * time.Second
-	for i := 0; i < 5; i++ {
+	for i := 0; i < retryAttempts; i++ {
 		order, err := fn()
 		if err == nil {
 			return order, nil
@@ -35,12 +39,15 @@ func (e *Engine) withRetry(ctx context.Context, fn func() (models.Order, error))
 	return models.Order{}, lastErr
 }
 
+// withRetryVoid повторяет отмену ордера. Отсутствие ордера окончательно и не повторяется.
 func (e *Engine) withRetryVoid(ctx context.Context, fn func() error) error {
 	var lastErr error
 	var backoff time.Duration = 1 * time.Second
-	for i := 0; i < 5; i++ {
+	for i := 0; i < retryAttempts; i++ {
 		if err := fn(); err == nil {
 			return nil
+		} else if isOrderNotExistError(err) {
+			return err
 		} else {
 			lastErr = err
 		}
@@ -62,7 +69,7 @@ func (e *Engine) withRetryVoid(ctx context.Context, fn func() error) error {
 func (e *Engine) withRetryOrders(ctx context.Context, symbol string) ([]models.Order, error) {
 	var lastErr error
 	var backoff time.Duration = 1 * time.Second
-	for i := 0; i < 5; i++ {
+	for i := 0; i < retryAttempts; i++ {
 		orders, err := e.client.GetOpenOrders(ctx, symbol)
 		if err == nil {
 			return orders, nil
@@ -83,11 +90,11 @@ func (e *Engine) withRetryOrders(ctx context.Context, symbol string) ([]models.O
 	return nil, lastErr
 }
 
-func (e *Engine) withRetryFills(ctx context.Context, symbol string) ([]models.Fill, error) {
+func (e *Engine) withRetryFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
 	var lastErr error
 	var backoff time.Duration = 1 * time.Second
-	for i := 0; i < 5; i++ {
-		fills, err := e.client.GetFills(ctx, symbol)
+	for i := 0; i < retryAttempts; i++ {
+		fills, err := e.client.GetFills(ctx, query)
 		if err == nil {
 			return fills, nil
 		}
@@ -107,33 +114,93 @@ func (e *Engine) withRetryFills(ctx context.Context, symbol string) ([]models.Fi
 	return nil, lastErr
 }
 
+func (e *Engine) withRetryOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	var lastErr error
+	var backoff time.Duration = 1 * time.Second
+	for i := 0; i < retryAttempts; i++ {
+		orders, err := e.client.GetOrderHistory(ctx, query)
+		if err == nil {
+			return orders, nil
+		}
+		lastErr = err
+		wait := time.Duration(math.Min(float64(backoff), float64(backoff*30)))
+		if isRateLimitError(err) {
+			wait = time.Duration(math.Min(float64(backoff*4), float64(backoff*30)))
+		}
+		e.logEntry().WithError(lastErr).Warn("Ошибка, повторяем запрос.")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+	return nil, lastErr
+}
+
+func (e *Engine) withRetryKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	var lastErr error
+	var backoff time.Duration = 1 * time.Second
+	for i := 0; i < retryAttempts; i++ {
+		klines, err := e.client.GetKlines(ctx, symbol, interval, limit)
+		if err == nil {
+			return klines, nil
+		}
+		lastErr = err
+		wait := time.Duration(math.Min(float64(backoff), float64(backoff*30)))
+		if isRateLimitError(err) {
+			wait = time.Duration(math.Min(float64(backoff*4), float64(backoff*30)))
+		}
+		e.logEntry().WithError(lastErr).Warn("Ошибка, повторяем запрос.")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+	return nil, lastErr
+}
+
 func (e *Engine) placeOrderIdempotent(ctx context.Context, order models.Order) (models.Order, error) {
 	if order.LinkID == "" {
 		return models.Order{}, fmt.Errorf("Пстой orderLinkId.")
 	}
+	if err := e.errHalted(); err != nil {
+		return models.Order{}, err
+	}
 
 	if order.Type != models.OrderTypeMarket {
 		if existing, err := e.findOpenOrderByLinkID(ctx, order.Symbol, order.LinkID); err == nil && existing.ID != "" {
 			e.logEntry().WithField("link_id", order.LinkID).Info("Найден существующий ордер по link_id, повтор не нужен.")
+			e.trackPlaced(ctx, order, existing)
 			return existing, nil
 		}
 	}
 
+	if order.Type == models.OrderTypeMarket {
+		if err := e.waitFreshTicker(ctx); err != nil {
+			return models.Order{}, err
+		}
+	}
+
 	e.logOrderContext(ctx, order)
 	placed, err := e.withRetry(ctx, func() (models.Order, error) {
 		return e.client.PlaceOrder(ctx, order)
 	})
 	if err == nil {
+		e.trackPlaced(ctx, order, placed)
 		return placed, nil
 	}
 	if isDuplicateClientOrderID(err) {
 		if existing, ok := e.findOrderAfterDuplicate(ctx, order.Symbol, order.LinkID); ok {
+			e.trackPlaced(ctx, order, existing)
 			return existing, nil
 		}
 	}
 
 	if order.Type == models.OrderTypeMarket {
-		fills, fErr := e.withRetryFills(ctx, order.Symbol)
+		fills, fErr := e.withRetryFills(ctx, exchange.FillQuery{Symbol: order.Symbol, LinkID: order.LinkID})
 		if fErr == nil {
 			for _, fill := range fills {
 				if fill.LinkID == order.LinkID {
@@ -200,7 +267,10 @@ func (e *Engine) validateMinNotional(order models.Order, priceHint float64) erro
 }
 
 func (e *Engine) linkID(suffix string) string {
-	return fmt.Sprintf("%s-%s", e.state.DealID, suffix)
+	e.mu.Lock()
+	dealID := e.state.DealID
+	e.mu.Unlock()
+	return fmt.Sprintf("%s-%s", dealID, suffix)
 }
 
 func (e *Engine) nextTPSuffix() string {
@@ -211,6 +281,7 @@ func (e *Engine) nextTPSuffix() string {
 	return fmt.Sprintf("tp-%d-%d", time.Now().Unix(), seq%1000)
 }
 
+// ensureDealID и ensureStateMaps вызываются под e.mu.
 func (e *Engine) ensureDealID() {
 	if e.state.DealID == "" {
 		e.state.DealID = newDealID()
@@ -252,11 +323,7 @@ func normalizeSide(side string) (models.OrderSide, error) {
 }
 
 func (e *Engine) qtyUnit() string {
-	unit := strings.TrimSpace(e.cfg.Bot.QtyUnit)
-	if strings.EqualFold(unit, "quoteCoin") {
-		return "quoteCoin"
-	}
-	return "baseCoin"
+	return normalizeQtyUnit(e.cfg.Bot.QtyUnit)
 }
 
 func isOrderNotExistError(err error) bool {
@@ -264,7 +331,7 @@ func isOrderNotExistError(err error) bool {
 		return false
 	}
 	msg := err.Error()
-	return strings.Contains(msg, "170213") || strings.Contains(msg, "Order does not exist")
+	return strings.Contains(msg, "170213") || strings.Contains(msg, "Order does not exist") || strings.Contains(msg, "code=-2011") || strings.Contains(msg, "code=51400") || strings.Contains(msg, "code=51603")
 }
 
 func isDuplicateClientOrderID(err error) bool {
@@ -272,15 +339,11 @@ func isDuplicateClientOrderID(err error) bool {
 		return false
 	}
 	msg := err.Error()
-	return strings.Contains(msg, "170141") || strings.Contains(msg, "Duplicate clientOrderId")
+	return strings.Contains(msg, "170141") || strings.Contains(msg, "Duplicate clientOrderId") || strings.Contains(msg, "Duplicate order sent") || strings.Contains(msg, "code=51016")
 }
 
 func (e *Engine) isQtyZero(qty float64) bool {
-	threshold := e.rules.LotSize / 2
-	if threshold <= 0 {
-		threshold = 1e-9
-	}
-	return qty <= threshold
+	return isQtyZeroFor(qty, e.rules.LotSize)
 }
 
 func (e *Engine) hasOpenBotOrders(ctx context.Context) (bool, error) {
@@ -330,6 +393,27 @@ func (e *Engine) ensureStateMaps() {
 	if e.state.ProcessedExecIDs == nil {
 		e.state.ProcessedExecIDs = map[string]bool{}
 	}
+	if e.state.Orders == nil {
+		e.state.Orders = map[string]OrderRecord{}
+	}
+}
+
+func (e *Engine) isActive() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.Active
+}
+
+func (e *Engine) dealSide() models.OrderSide {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.Side
+}
+
+func (e *Engine) lastPrice() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.LastTicker.LastPrice
 }
 
 func (e *Engine) baseAvailable(ctx context.Context) (float64, error) {
@@ -345,10 +429,7 @@ func (e *Engine) baseAvailable(ctx context.Context) (float64, error) {
 	if !ok {
 		return 0, nil
 	}
-	if bal.Wallet > 0 {
-		return bal.Wallet, nil
-	}
-	return bal.Available, nil
+	return e.dealBase(bal.Wallet, bal.Available), nil
 }
 
 func (e *Engine) logOrderContext(ctx context.Context, order models.Order) {
@@ -384,7 +465,7 @@ func (e *Engine) logOrderContext(ctx context.Context, order models.Order) {
 	needQuote := 0.0
 	priceHint := order.Price
 	if priceHint == 0 {
-		priceHint = e.state.LastTicker.LastPrice
+		priceHint = e.lastPrice()
 	}
 
 	if order.Side == models.OrderSideBuy {
@@ -417,7 +498,7 @@ func isRateLimitError(err error) bool {
 		return false
 	}
 	msg := err.Error()
-	return strings.Contains(msg, "Too many visits!") || strings.Contains(msg, "429") || strings.Contains(msg, "10006")
+	return strings.Contains(msg, "Too many visits!") || strings.Contains(msg, "429") || strings.Contains(msg, "10006") || strings.Contains(msg, "code=-1003") || strings.Contains(msg, "code=50011")
 }
 
 func (e *Engine) findOrderAfterDuplicate(ctx context.Context, symbol, linkID string) (models.Order, bool) {
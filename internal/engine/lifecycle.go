@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"strings"
+	"time"
+)
+
+const (
+	orderPolicyRetry = "retry"
+	orderPolicySkip  = "skip"
+	orderPolicyAlert = "alert"
+)
+
+// OrderRecord - жизненный цикл ордера сделки по link id.
+type OrderRecord struct {
+	LinkID          string             `json:"link_id"`
+	OrderID         string             `json:"order_id"`
+	Kind            models.OrderKind   `json:"kind"`
+	Status          models.OrderStatus `json:"status"`
+	Price           float64            `json:"price"`
+	Qty             float64            `json:"qty"`
+	FilledQty       float64            `json:"filled_qty"`
+	Reason          string             `json:"reason,omitempty"`
+	Retries         int                `json:"retries"`
+	CancelRequested bool               `json:"cancel_requested"`
+	Skipped         bool               `json:"skipped"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+func orderKindFromLinkID(linkID string) models.OrderKind {
+	switch {
+	case isEntryLinkID(linkID):
+		return models.OrderKindEntry
+	case isTPLinkID(linkID):
+		return models.OrderKindTP
+	case isSafetyLinkID(linkID):
+		return models.OrderKindSafety
+	}
+	return ""
+}
+
+func normalizeOrderPolicy(policy string) string {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case orderPolicySkip:
+		return orderPolicySkip
+	case orderPolicyAlert:
+		return orderPolicyAlert
+	default:
+		return orderPolicyRetry
+	}
+}
+
+func (e *Engine) orderPolicy(status models.OrderStatus) string {
+	if status == models.OrderStatusRejected {
+		return normalizeOrderPolicy(e.cfg.Bot.OrderPolicy.OnReject)
+	}
+	return normalizeOrderPolicy(e.cfg.Bot.OrderPolicy.OnCancel)
+}
+
+// isDealOrder вызывается под e.mu.
+func (e *Engine) isDealOrder(linkID string) bool {
+	if e.strategy() != strategyDCA || !e.state.Active {
+		return false
+	}
+	dealID, ok := dealIDFromLinkID(linkID)
+	return ok && dealID == e.state.DealID
+}
+
+// trackPlaced регистрирует ордер, выставленный ботом, до прихода статуса по WS.
+func (e *Engine) trackPlaced(ctx context.Context, order, placed models.Order) {
+	e.apply(ctx, func() {
+		if !e.isDealOrder(order.LinkID) {
+			return
+		}
+		e.ensureStateMaps()
+		rec := e.state.Orders[order.LinkID]
+		if rec.OrderID == placed.ID && rec.Status != "" {
+			return
+		}
+		rec.LinkID = order.LinkID
+		rec.OrderID = placed.ID
+		rec.Kind = orderKindFromLinkID(order.LinkID)
+		rec.Status = models.OrderStatusNew
+		rec.Price = order.Price
+		rec.Qty = order.Qty
+		rec.FilledQty = 0
+		rec.Reason = ""
+		rec.CancelRequested = false
+		rec.Skipped = false
+		rec.UpdatedAt = time.Now()
+		e.state.Orders[order.LinkID] = rec
+	})
+}
+
+// expectCancel помечает отмену ордера ботом, чтобы не принять её за внешнюю.
+func (e *Engine) expectCancel(ctx context.Context, orderID string) {
+	if orderID == "" {
+		return
+	}
+	e.apply(ctx, func() {
+		for linkID, rec := range e.state.Orders {
+			if rec.OrderID == orderID {
+				rec.CancelRequested = true
+				e.state.Orders[linkID] = rec
+				return
+			}
+		}
+	})
+}
+
+func (e *Engine) isOrderSkipped(linkID string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.Orders[linkID].Skipped
+}
+
+func (e *Engine) trackOrder(ctx context.Context, order models.Order) {
+	e.mu.Lock()
+	if order.LinkID == "" || !e.isDealOrder(order.LinkID) {
+		e.mu.Unlock()
+		return
+	}
+	e.ensureStateMaps()
+	rec := e.state.Orders[order.LinkID]
+	if rec.LinkID == "" {
+		rec.LinkID = order.LinkID
+		rec.Kind = orderKindFromLinkID(order.LinkID)
+	}
+	if order.ID != "" && rec.OrderID != order.ID {
+		rec.OrderID = order.ID
+		rec.CancelRequested = false
+		rec.Reason = ""
+	}
+	prevStatus := rec.Status
+	if order.Status != "" {
+		rec.Status = order.Status
+	}
+	if order.Price > 0 {
+		rec.Price = order.Price
+	}
+	if order.Qty > 0 {
+		rec.Qty = order.Qty
+	}
+	rec.FilledQty = order.FilledQty
+	if order.RejectReason != "" {
+		rec.Reason = order.RejectReason
+	} else if order.CancelType != "" {
+		rec.Reason = order.CancelType
+	}
+	rec.UpdatedAt = time.Now()
+	e.state.Orders[order.LinkID] = rec
+	lost := prevStatus != rec.Status && (rec.Status == models.OrderStatusCanceled || rec.Status == models.OrderStatusRejected) && !rec.CancelRequested && !e.state.Closing
+	e.mu.Unlock()
+
+	if prevStatus != rec.Status {
+		e.logEntry().WithFields(map[string]interface{}{
+			"link_id":  rec.LinkID,
+			"order_id": rec.OrderID,
+			"kind":     rec.Kind,
+			"from":     prevStatus,
+			"to":       rec.Status,
+			"reason":   rec.Reason,
+		}).Info("Статус ордера изменён.")
+	}
+
+	if lost && e.resolveLostOrder(ctx, rec) {
+		e.replaceLostOrder(ctx, rec)
+	}
+}
+
+// resolveLostOrder применяет политику к ордеру, снятому не ботом (биржей, self-match,
+// вручную). Возвращает true, если ордер нужно переставить.
+func (e *Engine) resolveLostOrder(ctx context.Context, rec OrderRecord) bool {
+	policy := e.orderPolicy(rec.Status)
+	maxRetries := e.cfg.Bot.OrderPolicy.MaxRetries
+
+	var (
+		filled    float64
+		current   OrderRecord
+		exhausted bool
+	)
+	e.apply(ctx, func() {
+		filled = e.state.FilledByLink[rec.LinkID]
+		switch rec.Kind {
+		case models.OrderKindSafety:
+			if orderID, ok := e.state.SafetyOrders[rec.LinkID]; ok && (orderID == rec.OrderID || orderID == "" || rec.OrderID == "") {
+				delete(e.state.SafetyOrders, rec.LinkID)
+			}
+		case models.OrderKindTP:
+			if e.state.TPOrderID == rec.OrderID {
+				e.state.TPOrderID = ""
+			}
+		}
+		e.ensureStateMaps()
+		var ok bool
+		if current, ok = e.state.Orders[rec.LinkID]; !ok {
+			current = rec
+		}
+		current.Status = rec.Status
+		if rec.Reason != "" {
+			current.Reason = rec.Reason
+		}
+		current.UpdatedAt = time.Now()
+		exhausted = policy == orderPolicyRetry && current.Retries >= maxRetries
+		if exhausted {
+			policy = orderPolicyAlert
+		}
+		if policy == orderPolicyRetry {
+			current.Retries++
+		} else {
+			current.Skipped = true
+		}
+		e.state.Orders[rec.LinkID] = current
+	})
+
+	fields := map[string]interface{}{
+		"link_id":  rec.LinkID,
+		"order_id": rec.OrderID,
+		"kind":     rec.Kind,
+		"status":   rec.Status,
+		"reason":   rec.Reason,
+		"policy":   policy,
+		"retries":  current.Retries,
+	}
+	if rec.Kind == models.OrderKindSafety && filled > 0 {
+		e.logEntry().WithFields(fields).Info("Страховочный ордер снят после частичного исполнения, перестановка не нужна.")
+		return false
+	}
+
+	switch policy {
+	case orderPolicyRetry:
+		e.logEntry().WithFields(fields).Warn("Ордер снят не ботом, перестановка.")
+		return true
+	case orderPolicySkip:
+		e.logEntry().WithFields(fields).Warn("Ордер снят не ботом, пропуск по политике.")
+	default:
+		if exhausted {
+			fields["max_retries"] = maxRetries
+		}
+		e.logEntry().WithFields(fields).Error("Ордер снят не ботом, требуется вмешательство.")
+	}
+	return false
+}
+
+func (e *Engine) replaceLostOrder(ctx context.Context, rec OrderRecord) {
+	switch rec.Kind {
+	case models.OrderKindTP:
+		e.scheduleTPRebuild(ctx)
+	case models.OrderKindSafety:
+		e.submit(jobDeal, "so_replace", e.rebuildMissingSafetyOrders, func(ctx context.Context, err error) {
+			if err != nil {
+				e.logEntry().WithError(err).WithField("link_id", rec.LinkID).Warn("Не удалось переставить страховочный ордер.")
+			}
+		})
+	}
+}
@@ -3,38 +3,72 @@ package engine
 import (
 	"context"
 	"dcabot/internal/models"
-	"fmt"
 	"strings"
 	"sync"
 	"time"
 )
 
-func (e *Engine) placeSafetyOrders(ctx context.Context, entryPrice float64) error {
-	orders := CalcSafetyOrders(entryPrice, e.cfg.Bot.SOCount, e.cfg.Bot.SOStepPercent, e.cfg.Bot.SOStepMultiplier, e.cfg.Bot.SOBaseQty, e.cfg.Bot.SOQtyMultiplier, e.state.Side)
+func (e *Engine) placeSafetyOrders(ctx context.Context) error {
+	orders, anchorPrice := e.safetyGrid()
 	qtyUnit := e.qtyUnit()
+	lastFilled, liveTo := e.safetyWindow(e.cfg.Bot.SOCount)
 
-	e.logEntry().WithField("count", len(orders)).Info("План сетки страховочных ордеров.")
+	e.mu.Lock()
+	side := e.state.Side
+	stepPercent := e.state.SOStepPercent
+	realizedPnL := e.sizing.RealizedPnL
+	dealPnL := e.state.RealizedPnL
+	fees := e.state.Fees
+	unpricedFees := len(e.state.UnpricedFees) > 0
+	e.mu.Unlock()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"count":        len(orders),
+		"anchor":       e.soAnchor(),
+		"anchor_price": anchorPrice,
+		"step_mode":    e.soStepMode(),
+		"step_percent": stepPercent,
+		"size_mult":    e.dealSizeMultiplier(),
+		"realized_pnl": realizedPnL,
+		"deal_pnl":     dealPnL,
+		"deal_fees":    fees,
+		"unpriced_fee": unpricedFees,
+		"max_active":   e.cfg.Bot.MaxActiveSafetyOrders,
+		"last_filled":  lastFilled,
+		"live_to":      liveTo,
+		"pending_from": liveTo + 1,
+	}).Info("План сетки страховочных ордеров.")
+
+	for _, so := range orders {
+		linkID := e.safetyLinkID(so.Level)
+		if !e.isSafetyLevelLive(linkID, so.Level, liveTo) {
+			e.logEntry().WithFields(map[string]interface{}{
+				"index":   so.Level,
+				"link_id": linkID,
+				"live_to": liveTo,
+			}).Debug("Страховочный ордер не входит в активное окно сетки, пропуск.")
+			continue
+		}
 
-	for i, so := range orders {
 		price := e.roundPrice(so.Price)
 		qty := so.Qty
 
 		e.logEntry().WithFields(map[string]interface{}{
-			"index":         i + 1,
-			"entry_price":   entryPrice,
+			"index":         so.Level,
+			"anchor_price":  anchorPrice,
 			"total_percent": so.TotalPercent,
 			"price":         price,
 			"qty":           qty,
-			"so_count":      len(orders),
+			"so_count":      e.cfg.Bot.SOCount,
 		}).Debug("safety_order_raw")
 
 		if strings.EqualFold(qtyUnit, "quoteCoin") {
 			if price <= 0 {
 				e.logEntry().WithFields(map[string]interface{}{
-					"index":         i + 1,
-					"so_count":      len(orders),
+					"index":         so.Level,
+					"so_count":      e.cfg.Bot.SOCount,
 					"total_percent": so.TotalPercent,
-					"entry_price":   entryPrice,
+					"anchor_price":  anchorPrice,
 					"price":         price,
 				}).Warn("Страховочный ордер пропущен, нет цены для пересчёта объёма.")
 				continue
@@ -42,11 +76,10 @@ func (e *Engine) placeSafetyOrders(ctx context.Context, entryPrice float64) erro
 			qty = qty / price
 		}
 		qty = e.roundQty(qty)
-		linkID := e.linkID(fmt.Sprintf("so-%d", i+1))
 		notional := price * qty
 
 		e.logEntry().WithFields(map[string]interface{}{
-			"index":    i + 1,
+			"index":    so.Level,
 			"price":    price,
 			"qty":      qty,
 			"notional": notional,
@@ -60,16 +93,14 @@ func (e *Engine) placeSafetyOrders(ctx context.Context, entryPrice float64) erro
 			continue
 		}
 
-		if orderID, exists := e.state.SafetyOrders[linkID]; exists && orderID != "" {
+		if orderID := e.safetyOrderID(linkID); orderID != "" {
 			e.logEntry().WithField("link_id", linkID).Debug("Страховочный ордер уже зарегистрирован, пропуск.")
 			continue
 		}
 
 		if existing, err := e.findOpenOrderByLinkID(ctx, e.cfg.Bot.Symbol, linkID); err == nil && existing.ID != "" {
 			e.logEntry().WithField("link_id", linkID).Info("Страховочный ордер уже открыт, пропуск.")
-			e.mu.Lock()
-			e.state.SafetyOrders[linkID] = existing.ID
-			e.mu.Unlock()
+			e.setSafetyOrderID(ctx, linkID, existing.ID)
 			continue
 		}
 
@@ -80,7 +111,7 @@ func (e *Engine) placeSafetyOrders(ctx context.Context, entryPrice float64) erro
 
 		order := models.Order{
 			Symbol:      e.cfg.Bot.Symbol,
-			Side:        e.state.Side,
+			Side:        side,
 			Type:        models.OrderTypeLimit,
 			Kind:        models.OrderKindSafety,
 			Price:       price,
@@ -101,12 +132,10 @@ func (e *Engine) placeSafetyOrders(ctx context.Context, entryPrice float64) erro
 			return err
 		}
 
-		e.mu.Lock()
-		e.state.SafetyOrders[linkID] = placed.ID
-		e.mu.Unlock()
+		e.setSafetyOrderID(ctx, linkID, placed.ID)
 		e.log.WithOrderID(placed.ID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("Страховочный ордер поставлен.")
 
-		if i < len(orders)-1 {
+		if so.Level < liveTo {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -118,36 +147,56 @@ func (e *Engine) placeSafetyOrders(ctx context.Context, entryPrice float64) erro
 	return nil
 }
 
-func (e *Engine) buildSafetyOrders(entryPrice float64) map[string]models.Order {
-	orders := CalcSafetyOrders(entryPrice, e.cfg.Bot.SOCount, e.cfg.Bot.SOStepPercent, e.cfg.Bot.SOStepMultiplier, e.cfg.Bot.SOBaseQty, e.cfg.Bot.SOQtyMultiplier, e.state.Side)
+func (e *Engine) buildSafetyOrders() map[string]models.Order {
+	orders := PlanSafetyOrders(e.dealView(), e.cfg.Bot)
 	result := make(map[string]models.Order, len(orders))
-	for i, so := range orders {
-		price := e.roundPrice(so.Price)
-		qty := e.roundQty(so.Qty)
-		linkID := e.linkID(fmt.Sprintf("so-%d", i+1))
-		result[linkID] = models.Order{
-			Symbol:      e.cfg.Bot.Symbol,
-			Side:        e.state.Side,
-			Type:        models.OrderTypeLimit,
-			Kind:        models.OrderKindSafety,
-			Price:       price,
-			Qty:         qty,
-			LinkID:      linkID,
-			TimeInForce: "GTC",
-			PriceStep:   e.rules.TickSize,
-			QtyStep:     e.rules.LotSize,
+	for _, order := range orders {
+		if order.Price <= 0 {
+			continue
 		}
+		result[order.LinkID] = order
 	}
 	return result
 }
 
 func (e *Engine) rebuildMissingSafetyOrders(ctx context.Context) error {
-	if e.state.EntryPrice == 0 {
+	e.mu.Lock()
+	entryPrice := e.state.EntryPrice
+	e.mu.Unlock()
+	if entryPrice == 0 {
 		return nil
 	}
-	expected := e.buildSafetyOrders(e.state.EntryPrice)
+	expected := e.buildSafetyOrders()
+	_, liveTo := e.safetyWindow(e.cfg.Bot.SOCount)
+
+	e.mu.Lock()
+	stale := make(map[string]string)
+	for linkID, orderID := range e.state.SafetyOrders {
+		if _, ok := expected[linkID]; ok || orderID == "" || e.state.FilledByLink[linkID] > 0 {
+			continue
+		}
+		stale[linkID] = orderID
+	}
+	e.mu.Unlock()
+
+	for linkID, orderID := range stale {
+		e.logEntry().WithField("link_id", linkID).Info("Страховочный ордер не соответствует текущей сетке, отмена.")
+		e.expectCancel(ctx, orderID)
+		if err := e.withRetryVoid(ctx, func() error {
+			return e.client.CancelOrder(ctx, e.cfg.Bot.Symbol, orderID)
+		}); err != nil && !isOrderNotExistError(err) {
+			return err
+		}
+		e.apply(ctx, func() {
+			delete(e.state.SafetyOrders, linkID)
+		})
+	}
 	for linkID, order := range expected {
-		if orderID, exists := e.state.SafetyOrders[linkID]; exists && orderID != "" {
+		if e.safetyOrderID(linkID) != "" {
+			continue
+		}
+		level, _ := safetyLevelFromLinkID(linkID)
+		if !e.isSafetyLevelLive(linkID, level, liveTo) {
 			continue
 		}
 		if order.Qty < e.rules.MinQty {
@@ -160,12 +209,60 @@ func (e *Engine) rebuildMissingSafetyOrders(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		e.state.SafetyOrders[linkID] = placed.ID
+		e.setSafetyOrderID(ctx, linkID, placed.ID)
 		e.log.WithOrderID(placed.ID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("Страховочный ордер переставлен.")
 	}
 	return nil
 }
 
+func (e *Engine) safetyOrderID(linkID string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.SafetyOrders[linkID]
+}
+
+func (e *Engine) setSafetyOrderID(ctx context.Context, linkID, orderID string) {
+	e.apply(ctx, func() {
+		e.ensureStateMaps()
+		e.state.SafetyOrders[linkID] = orderID
+	})
+}
+
+func (e *Engine) safetyWindow(count int) (int, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return SafetyWindow(e.state.FilledByLink, count, e.cfg.Bot.MaxActiveSafetyOrders)
+}
+
+func (e *Engine) isSafetyLevelLive(linkID string, level, liveTo int) bool {
+	if level <= 0 || level > liveTo {
+		return false
+	}
+	e.mu.Lock()
+	filled := e.state.FilledByLink[linkID]
+	skipped := e.state.Orders[linkID].Skipped
+	e.mu.Unlock()
+	return filled <= 0 && !skipped
+}
+
+func (e *Engine) soAnchor() string {
+	return normalizeSOAnchor(e.cfg.Bot.SOAnchor)
+}
+
+func (e *Engine) safetyGrid() ([]SafetyOrder, float64) {
+	return safetyGridFor(e.dealView(), e.cfg.Bot)
+}
+
+func (e *Engine) safetyLinkID(level int) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	anchorLevel := e.state.GridAnchorLevel
+	if e.state.GridAnchorPrice <= 0 {
+		anchorLevel = 0
+	}
+	return safetyLinkIDFor(e.state.DealID, level, anchorLevel)
+}
+
 func (e *Engine) cancelSafetyOrders(ctx context.Context) error {
 	e.mu.Lock()
 	orderIDs := make([]string, 0, len(e.state.SafetyOrders))
@@ -207,6 +304,7 @@ func (e *Engine) cancelSafetyOrders(ctx context.Context) error {
 		go worker()
 	}
 	for _, orderID := range orderIDs {
+		e.expectCancel(ctx, orderID)
 		jobs <- orderID
 	}
 	close(jobs)
@@ -219,9 +317,9 @@ func (e *Engine) cancelSafetyOrders(ctx context.Context) error {
 		}
 	}
 
-	e.mu.Lock()
-	e.state.SafetyOrders = map[string]string{}
-	e.mu.Unlock()
+	e.apply(ctx, func() {
+		e.state.SafetyOrders = map[string]string{}
+	})
 	return nil
 }
 
@@ -270,6 +368,7 @@ func (e *Engine) cancelOpenBotOrders(ctx context.Context) (int, error) {
 		go worker()
 	}
 	for _, orderID := range orderIDs {
+		e.expectCancel(ctx, orderID)
 		jobs <- orderID
 	}
 	close(jobs)
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"context"
+	"math"
+)
+
+// PositionLedger - позиция сделки по собственным исполнениям бота, без учёта баланса кошелька.
+type PositionLedger struct {
+	Opened  float64 `json:"opened"`
+	Closed  float64 `json:"closed"`
+	Adopted float64 `json:"adopted"`
+	Carried float64 `json:"carried"`
+}
+
+func (l PositionLedger) Position() float64 {
+	return math.Max(l.Opened+l.Adopted+l.Carried-l.Closed, 0)
+}
+
+func (e *Engine) ledgerPosition() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.Ledger.Position()
+}
+
+// nonDealBase - объём базовой монеты на счёте, который не относится к сделке:
+// резерв из конфига, сторонние сделки в режиме сверки ignore и пыль, ещё не
+// перенесённая в сделку.
+func (e *Engine) nonDealBase() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cfg.Bot.ReservedBase + e.reconcile.ExternalBase + e.dust.Qty
+}
+
+// dealBase - баланс базовой монеты за вычетом резерва и стороннего объёма.
+func (e *Engine) dealBase(wallet, available float64) float64 {
+	balance := available
+	if wallet > 0 {
+		balance = wallet
+	}
+	return math.Max(balance-e.nonDealBase(), 0)
+}
+
+func (e *Engine) logReservedBase(ctx context.Context) {
+	reserved := e.cfg.Bot.ReservedBase
+	if reserved <= 0 || e.rules.BaseCoin == "" {
+		return
+	}
+	fields := map[string]interface{}{
+		"reserved":      reserved,
+		"non_deal_base": e.nonDealBase(),
+		"base_coin":     e.rules.BaseCoin,
+	}
+	if balances, err := e.client.GetBalances(ctx, []string{e.rules.BaseCoin}); err == nil {
+		bal := balances[e.rules.BaseCoin]
+		fields["wallet"] = bal.Wallet
+		fields["deal_base"] = e.dealBase(bal.Wallet, bal.Available)
+		if bal.Wallet < reserved && bal.Available < reserved {
+			e.logEntry().WithFields(fields).Warn("Баланс базовой монеты меньше резерва.")
+			return
+		}
+	}
+	e.logEntry().WithFields(fields).Info("Резерв базовой монеты исключён из проверок баланса.")
+}
@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	reconcileAdopt  = "adopt"
+	reconcileIgnore = "ignore"
+	reconcileHalt   = "halt"
+
+	reconcileSettleDelay = 5 * time.Second
+	// Комиссия в базовой монете уменьшает баланс относительно исполнений,
+	// поэтому расхождение в пределах этой доли позиции не считается вмешательством.
+	reconcileFeeShare = 0.01
+)
+
+// ReconcileState - объём базовой монеты на счёте, не относящийся к сделкам бота.
+type ReconcileState struct {
+	ExternalBase float64   `json:"external_base"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func normalizeReconcileMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case reconcileIgnore:
+		return reconcileIgnore
+	case reconcileAdopt:
+		return reconcileAdopt
+	default:
+		return reconcileHalt
+	}
+}
+
+func (e *Engine) reconcileMode() string {
+	return normalizeReconcileMode(e.cfg.Bot.Reconcile.Mode)
+}
+
+func (e *Engine) addExternalBase(ctx context.Context, delta float64) float64 {
+	var total float64
+	e.apply(ctx, func() {
+		e.reconcile.ExternalBase += delta
+		e.reconcile.UpdatedAt = time.Now()
+		total = e.reconcile.ExternalBase
+	})
+	e.saveState()
+	return total
+}
+
+func (e *Engine) haltReason() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.halted
+}
+
+// halt останавливает постановку новых ордеров, пока сверка не увидит, что баланс снова
+// совпадает с позицией, или до перезапуска бота.
+func (e *Engine) halt(ctx context.Context, reason string, fields map[string]interface{}) {
+	already := false
+	e.apply(ctx, func() {
+		if already = e.halted != ""; !already {
+			e.halted = reason
+		}
+	})
+	if already {
+		return
+	}
+	fields["reason"] = reason
+	e.logEntry().WithFields(fields).Error("Обнаружено ручное вмешательство, торговля остановлена до устранения расхождения или перезапуска бота.")
+}
+
+// checkHaltResolved снимает остановку, когда баланс базовой монеты снова совпадает с
+// позицией сделки, и продолжает прерванное: закрытие, TP или новый цикл.
+func (e *Engine) checkHaltResolved(ctx context.Context) error {
+	baseQty, err := e.baseAvailable(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	active := e.state.Active
+	closing := e.state.Closing
+	position := 0.0
+	if active {
+		position = e.state.Ledger.Position()
+	}
+	e.mu.Unlock()
+
+	drift := baseQty - position
+	if math.Abs(drift) > e.driftTolerance(position) && !e.isDustQty(math.Abs(drift), e.lastPrice()) {
+		return nil
+	}
+
+	reason := ""
+	e.apply(ctx, func() {
+		reason, e.halted = e.halted, ""
+	})
+	if reason == "" {
+		return nil
+	}
+	e.logEntry().WithFields(map[string]interface{}{
+		"reason":   reason,
+		"base_qty": baseQty,
+		"position": position,
+	}).Warn("Расхождение баланса устранено, торговля возобновлена.")
+
+	switch {
+	case active && closing:
+		e.submit(jobDeal, "close", e.closeDeal, nil)
+	case active:
+		e.scheduleTPRebuild(ctx)
+		e.submit(jobDeal, "so_replace", e.rebuildMissingSafetyOrders, nil)
+	default:
+		e.submit(jobDeal, "new_cycle", e.startNextCycle, nil)
+	}
+	return nil
+}
+
+func (e *Engine) errHalted() error {
+	if reason := e.haltReason(); reason != "" {
+		return fmt.Errorf("Торговля остановлена после ручного вмешательства: %s", reason)
+	}
+	return nil
+}
+
+func isExternalFill(fill models.Fill) bool {
+	_, ok := dealIDFromLinkID(fill.LinkID)
+	return !ok
+}
+
+// onExternalFill обрабатывает исполнение без link id бота: ручную сделку на том же счёте.
+func (e *Engine) onExternalFill(ctx context.Context, fill models.Fill) {
+	if fill.Symbol != "" && !strings.EqualFold(fill.Symbol, e.cfg.Bot.Symbol) {
+		return
+	}
+
+	baseDelta := fill.Qty
+	if fill.Side == models.OrderSideSell {
+		baseDelta = -fill.Qty
+	}
+	mode := e.reconcileMode()
+	fields := map[string]interface{}{
+		"exec_id":  fill.ExecID,
+		"order_id": fill.OrderID,
+		"side":     fill.Side,
+		"price":    fill.Price,
+		"qty":      fill.Qty,
+		"mode":     mode,
+	}
+
+	switch mode {
+	case reconcileHalt:
+		e.halt(ctx, "external_fill", fields)
+	case reconcileIgnore:
+		fields["external_base"] = e.addExternalBase(ctx, baseDelta)
+		e.logEntry().WithFields(fields).Warn("Стороннее исполнение, объём исключён из сделки.")
+	default:
+		if !e.isActive() {
+			e.logEntry().WithFields(fields).Warn("Стороннее исполнение без активной сделки, будет учтено сверкой баланса.")
+			return
+		}
+		e.adoptExternalFill(ctx, fill, fields)
+	}
+}
+
+func (e *Engine) adoptExternalFill(ctx context.Context, fill models.Fill, fields map[string]interface{}) {
+	e.mu.Lock()
+	if fill.Side == e.state.Side {
+		totalCost := e.state.AvgPrice*e.state.TotalQty + fill.Price*fill.Qty
+		e.state.TotalQty += fill.Qty
+		e.state.AvgPrice = CalcAvgPrice(totalCost, e.state.TotalQty)
+		e.state.Ledger.Adopted += fill.Qty
+	} else {
+		closed := math.Min(fill.Qty, e.state.TotalQty)
+		e.realizePnL(fill.Price, closed)
+		e.state.TotalQty -= closed
+		e.state.Ledger.Adopted -= fill.Qty
+	}
+	e.chargeFee(fill)
+	e.state.UpdatedAt = time.Now()
+	totalQty := e.state.TotalQty
+	fields["total_qty"] = totalQty
+	fields["avg"] = e.state.AvgPrice
+	e.mu.Unlock()
+
+	e.logEntry().WithFields(fields).Warn("Стороннее исполнение включено в сделку.")
+	e.saveState()
+	e.applyAdoptedQty(ctx, totalQty, "external_fill")
+}
+
+func (e *Engine) applyAdoptedQty(ctx context.Context, totalQty float64, reason string) {
+	if e.isQtyZero(e.roundQty(totalQty)) {
+		e.requestClose(ctx, reason)
+		return
+	}
+	e.scheduleTPRebuild(ctx)
+}
+
+func (e *Engine) driftTolerance(totalQty float64) float64 {
+	return math.Max(math.Max(e.rules.MinQty, e.rules.LotSize), totalQty*reconcileFeeShare)
+}
+
+// onBalanceDrift применяет режим ignore/halt к расхождению баланса и позиции.
+// Для adopt объём принимает вызывающий код.
+func (e *Engine) onBalanceDrift(ctx context.Context, drift float64, fields map[string]interface{}) string {
+	mode := e.reconcileMode()
+	fields["drift"] = drift
+	fields["mode"] = mode
+	switch mode {
+	case reconcileIgnore:
+		fields["external_base"] = e.addExternalBase(ctx, drift)
+		e.logEntry().WithFields(fields).Warn("Расхождение баланса исключено из сделки.")
+	case reconcileHalt:
+		e.halt(ctx, "balance_drift", fields)
+	}
+	return mode
+}
+
+func (e *Engine) watchBalanceDrift(ctx context.Context) {
+	interval := e.cfg.Bot.Reconcile.Interval
+	if interval <= 0 || e.strategy() != strategyDCA {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		e.submit(jobDeal, "reconcile", e.checkBalanceDrift, func(ctx context.Context, err error) {
+			if err != nil {
+				e.logEntry().WithError(err).Warn("Не удалось сверить баланс с позицией сделки.")
+			}
+		})
+	}
+}
+
+// checkBalanceDrift сравнивает баланс базовой монеты (без резерва) с позицией сделки
+// на покупку по собственным исполнениям бота.
+func (e *Engine) checkBalanceDrift(ctx context.Context) error {
+	if e.haltReason() != "" {
+		return e.checkHaltResolved(ctx)
+	}
+
+	e.mu.Lock()
+	skip := !e.state.Active || e.state.Closing || e.state.Side != models.OrderSideBuy
+	lastFillAt := e.state.LastFillAt
+	e.mu.Unlock()
+	if skip || (!lastFillAt.IsZero() && time.Since(lastFillAt) < reconcileSettleDelay) {
+		return nil
+	}
+
+	baseQty, err := e.baseAvailable(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	if !e.state.Active || e.state.Closing || !e.state.LastFillAt.Equal(lastFillAt) {
+		e.mu.Unlock()
+		return nil
+	}
+	position := e.state.Ledger.Position()
+	totalQty := e.state.TotalQty
+	e.mu.Unlock()
+
+	drift := baseQty - position
+	if math.Abs(drift) <= e.driftTolerance(position) {
+		return nil
+	}
+
+	fields := map[string]interface{}{
+		"base_qty":  baseQty,
+		"position":  position,
+		"total_qty": totalQty,
+		"base_coin": e.rules.BaseCoin,
+	}
+	if e.onBalanceDrift(ctx, drift, fields) == reconcileAdopt {
+		e.adoptBalance(ctx, baseQty, fields)
+	}
+	return nil
+}
+
+// adoptBalance принимает баланс как позицию сделки; разница с позицией учитывается по последней цене.
+func (e *Engine) adoptBalance(ctx context.Context, baseQty float64, fields map[string]interface{}) {
+	newQty := e.roundQty(baseQty)
+	e.apply(ctx, func() {
+		prevQty := e.state.TotalQty
+		if price := e.state.LastTicker.LastPrice; newQty > prevQty && price > 0 {
+			totalCost := e.state.AvgPrice*prevQty + price*(newQty-prevQty)
+			e.state.AvgPrice = CalcAvgPrice(totalCost, newQty)
+		} else if newQty < prevQty && price > 0 {
+			// Проданный вне бота объём закрыт по последней цене.
+			e.realizePnL(price, prevQty-newQty)
+		}
+		e.state.Ledger.Adopted += newQty - e.state.Ledger.Position()
+		e.state.TotalQty = newQty
+		e.state.UpdatedAt = time.Now()
+		fields["avg"] = e.state.AvgPrice
+	})
+
+	fields["new_total_qty"] = newQty
+	e.logEntry().WithFields(fields).Warn("Расхождение баланса включено в сделку.")
+	e.saveState()
+	e.applyAdoptedQty(ctx, newQty, "balance_drift")
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"sort"
+	"time"
+)
+
+const (
+	gapRecoveryOverlap  = 1 * time.Minute
+	gapRecoveryLookback = 1 * time.Hour
+)
+
+// fetchMissedFills догружает исполнения, пропущенные пока приватный WS был недоступен.
+// Выполняется в пуле, применяются исполнения в цикле событий через replayMissedFills.
+func (e *Engine) fetchMissedFills(ctx context.Context) ([]models.Fill, time.Time, error) {
+	e.mu.Lock()
+	since := e.state.LastFillAt
+	active := e.state.Active
+	e.mu.Unlock()
+
+	if e.strategy() == strategyDCA && !active {
+		return nil, time.Time{}, nil
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-gapRecoveryLookback)
+	}
+	since = since.Add(-gapRecoveryOverlap)
+
+	fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: e.cfg.Bot.Symbol, Since: since})
+	if err != nil {
+		return nil, since, err
+	}
+	sort.SliceStable(fills, func(i, j int) bool {
+		return fills[i].Timestamp.Before(fills[j].Timestamp)
+	})
+	return fills, since, nil
+}
+
+// replayMissedFills прогоняет новые исполнения через handleFill. Уже учтённые ExecID
+// отбрасываются самим handleFill.
+func (e *Engine) replayMissedFills(ctx context.Context, fills []models.Fill, since time.Time) {
+	if since.IsZero() {
+		return
+	}
+	e.mu.Lock()
+	dealID := e.state.DealID
+	e.mu.Unlock()
+
+	strategy := e.strategy()
+	replayed := 0
+	for _, fill := range fills {
+		if fill.ExecID == "" || e.isFillProcessed(fill) {
+			continue
+		}
+		if strategy == strategyDCA {
+			if fillDealID, ok := dealIDFromLinkID(fill.LinkID); !ok || fillDealID != dealID {
+				continue
+			}
+		}
+		e.logEntry().WithFields(map[string]interface{}{
+			"exec_id": fill.ExecID,
+			"link_id": fill.LinkID,
+			"side":    fill.Side,
+			"price":   fill.Price,
+			"qty":     fill.Qty,
+		}).Info("Восстановлено пропущенное исполнение.")
+		e.handleFill(ctx, fill)
+		replayed++
+	}
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"since":    since,
+		"fetched":  len(fills),
+		"replayed": replayed,
+	}).Info("Проверка пропущенных исполнений после реконнекта.")
+}
+
+func (e *Engine) isFillProcessed(fill models.Fill) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch e.strategy() {
+	case strategyGrid:
+		return e.grid.ProcessedExecIDs[fill.ExecID]
+	case strategyAccumulate:
+		return e.ledger.ProcessedExecIDs[fill.ExecID]
+	}
+	return e.state.ProcessedExecIDs[fill.ExecID]
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fillMissed исполняет ордер, как fill, но событий не отправляет: WS их пропустил.
+func (f *fakeClient) fillMissed(match func(linkID string) bool, qty float64) (models.Fill, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := f.events
+	f.events = make(chan exchange.Event, 4)
+	defer func() { f.events = events }()
+	for _, order := range f.orders {
+		if isOpenStatus(order.Status) && match(order.LinkID) {
+			return f.fillLocked(order, qty, order.Price)
+		}
+	}
+	return models.Fill{}, false
+}
+
+func TestReplayMissedFillsDedup(t *testing.T) {
+	client := newFakeClient()
+	e := newTestEngine(t, client)
+	startTestEngine(t, e, client)
+
+	e.mu.Lock()
+	dealID := e.state.DealID
+	e.mu.Unlock()
+	linkIs := func(linkID string) func(string) bool {
+		return func(l string) bool { return l == linkID }
+	}
+
+	if _, ok := client.fill(linkIs(dealID+"-so-1"), 1); !ok {
+		t.Fatal("so-1 не исполнен")
+	}
+	waitSingleTP(t, client, 2, func(models.Order) bool { return true })
+
+	// Пока WS лежал: so-2 исполнен, а в истории есть чужие исполнения и исполнение без ExecID.
+	missed, ok := client.fillMissed(linkIs(dealID+"-so-2"), 1)
+	if !ok {
+		t.Fatal("so-2 не исполнен")
+	}
+	client.mu.Lock()
+	client.fills = append(client.fills,
+		models.Fill{LinkID: "other-so-1", ExecID: "foreign-1", Symbol: fakeSymbol, Side: models.OrderSideBuy, Price: 95, Qty: 5, Timestamp: time.Now()},
+		models.Fill{LinkID: dealID + "-so-3", Symbol: fakeSymbol, Side: models.OrderSideBuy, Price: 97, Qty: 5, Timestamp: time.Now()},
+	)
+	client.mu.Unlock()
+
+	client.push(exchange.Event{Type: exchange.EventTypeReconnect})
+	waitSingleTP(t, client, 3, func(models.Order) bool { return true })
+
+	// WS после переподключения повторно присылает уже восстановленное исполнение,
+	// а повторный реконнект снова догружает всю историю.
+	client.push(exchange.Event{Type: exchange.EventTypeFill, Fill: &missed})
+	fetched, since, err := e.fetchMissedFills(context.Background())
+	if err != nil {
+		t.Fatalf("fetchMissedFills: %v", err)
+	}
+	e.call(context.Background(), func(ctx context.Context) {
+		e.replayMissedFills(ctx, fetched, since)
+	})
+	if _, ok := client.fill(linkIs(dealID+"-so-3"), 1); !ok {
+		t.Fatal("so-3 не исполнен")
+	}
+	waitSingleTP(t, client, 4, func(models.Order) bool { return true })
+
+	var wantFees float64
+	client.mu.Lock()
+	for _, fill := range client.fills {
+		if strings.HasPrefix(fill.LinkID, dealID) && fill.ExecID != "" {
+			wantFees += fill.Fee
+		}
+	}
+	client.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !approxEqual(e.state.TotalQty, 4) {
+		t.Fatalf("TotalQty %f, ожидалось 4: исполнения учтены повторно", e.state.TotalQty)
+	}
+	if qty := e.state.FilledByLink[dealID+"-so-2"]; !approxEqual(qty, 1) {
+		t.Fatalf("so-2 учтён на %f, ожидалось 1", qty)
+	}
+	if !e.state.ProcessedExecIDs[missed.ExecID] || e.state.ProcessedExecIDs["foreign-1"] {
+		t.Fatalf("ProcessedExecIDs %v: ожидался %s без foreign-1", e.state.ProcessedExecIDs, missed.ExecID)
+	}
+	if !approxEqual(e.state.Fees, wantFees) {
+		t.Fatalf("комиссия сделки %f, ожидалось %f", e.state.Fees, wantFees)
+	}
+}
@@ -2,15 +2,17 @@ package engine
 
 import (
 	"context"
+	"dcabot/internal/exchange"
 	"dcabot/internal/models"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
-	e.ensureStateMaps()
-
 	openOrders, err := e.withRetryOrders(ctx, e.cfg.Bot.Symbol)
 	if err != nil {
 		return false, err
@@ -85,20 +87,22 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 		side = parsedSide
 	}
 
-	fills, err := e.withRetryFills(ctx, e.cfg.Bot.Symbol)
+	fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: e.cfg.Bot.Symbol, Since: e.dealStartedAt(ctx, dealID, orders)})
 	if err != nil {
 		return false, err
 	}
 
-	var buyQty, buyCost, sellQty float64
+	var buyQty, buyCost, sellQty, sellCost float64
 	var entryPrice float64
 	var entryTS time.Time
 	filledByLink := map[string]float64{}
 	processedExecIDs := map[string]bool{}
+	var dealFills []models.Fill
 	for _, fill := range fills {
 		if !strings.HasPrefix(fill.LinkID, dealID+"-") {
 			continue
 		}
+		dealFills = append(dealFills, fill)
 		if fill.ExecID != "" {
 			processedExecIDs[fill.ExecID] = true
 		}
@@ -112,10 +116,23 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 			}
 		} else {
 			sellQty += fill.Qty
+			sellCost += fill.Qty * fill.Price
 		}
 	}
 
 	totalQty := buyQty - sellQty
+	ledger := PositionLedger{Opened: buyQty, Closed: sellQty}
+	if side == models.OrderSideSell {
+		ledger = PositionLedger{Opened: sellQty, Closed: buyQty}
+	}
+	// Принятый сверкой объём, перенесённая пыль и жизненный цикл ордеров не видны по
+	// исполнениям сделки и берутся из сохранённого состояния.
+	prev, hasPrev := e.savedDeal(dealID)
+	if hasPrev {
+		ledger.Adopted = prev.Ledger.Adopted
+		ledger.Carried = prev.Ledger.Carried
+		totalQty += prev.Ledger.Adopted
+	}
 	avgPrice := 0.0
 	if buyQty > 0 {
 		avgPrice = buyCost / buyQty
@@ -125,7 +142,8 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 	}
 
 	if tpOrder != nil && totalQty <= 0 {
-		totalQty = tpOrder.Qty
+		// В TP входит перенесённая пыль, которая не относится к позиции сделки.
+		totalQty = math.Max(tpOrder.Qty-prev.CarriedDust, 0)
 	}
 
 	if tpOrder == nil && totalQty <= 0 {
@@ -154,9 +172,13 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 	}
 
 	entryLinkID := fmt.Sprintf("%s-entry", dealID)
+	gridAnchorLevel, gridAnchorPrice := e.gridAnchorFromFills(dealFills, side)
+	soStepPercent := e.restoreSOStepPercent(dealID, entryPrice, side, orders)
+	sizeMultiplier, realizedPnL := e.restoreDealSizing(dealID, side, avgPrice, sellQty, sellCost)
 
 	plannedTPPrice := 0.0
 	plannedTPQty := 0.0
+	tpFilledQty := 0.0
 	tpOrderID := ""
 	tpLinkID := ""
 	if tpOrder != nil {
@@ -164,39 +186,58 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 		tpLinkID = tpOrder.LinkID
 		plannedTPPrice = tpOrder.Price
 		plannedTPQty = tpOrder.Qty
+		tpFilledQty = tpOrder.FilledQty
 	}
 
-	e.mu.Lock()
-	lastTicker := e.state.LastTicker
-	lastTickerSeq := e.state.LastTickerSeq
-	e.state = DealState{
-		Active:           true,
-		DealID:           dealID,
-		Symbol:           e.cfg.Bot.Symbol,
-		Side:             side,
-		EntryPrice:       entryPrice,
-		EntryLinkID:      entryLinkID,
-		AvgPrice:         avgPrice,
-		TotalQty:         totalQty,
-		FilledByLink:     filledByLink,
-		TPFilledQty:      0,
-		TPOrderID:        tpOrderID,
-		TPlinkID:         tpLinkID,
-		PlannedTPQty:     plannedTPQty,
-		PlannedTPPrice:   plannedTPPrice,
-		ProcessedExecIDs: processedExecIDs,
-		SafetyOrders:     safetyOrders,
-		LastTicker:       lastTicker,
-		LastTickerSeq:    lastTickerSeq,
-		UpdatedAt:        time.Now(),
-	}
-	e.mu.Unlock()
+	e.apply(ctx, func() {
+		lastTicker := e.state.LastTicker
+		lastTickerSeq := e.state.LastTickerSeq
+		e.state = DealState{
+			Active:           true,
+			DealID:           dealID,
+			Symbol:           e.cfg.Bot.Symbol,
+			Side:             side,
+			EntryPrice:       entryPrice,
+			EntryLinkID:      entryLinkID,
+			AvgPrice:         avgPrice,
+			TotalQty:         totalQty,
+			FilledByLink:     filledByLink,
+			TPFilledQty:      tpFilledQty,
+			TPOrderID:        tpOrderID,
+			TPlinkID:         tpLinkID,
+			PlannedTPQty:     plannedTPQty,
+			PlannedTPPrice:   plannedTPPrice,
+			ProcessedExecIDs: processedExecIDs,
+			SafetyOrders:     safetyOrders,
+			GridAnchorLevel:  gridAnchorLevel,
+			GridAnchorPrice:  gridAnchorPrice,
+			SOStepPercent:    soStepPercent,
+			SizeMultiplier:   sizeMultiplier,
+			RealizedPnL:      realizedPnL,
+			LastTicker:       lastTicker,
+			LastTickerSeq:    lastTickerSeq,
+			UpdatedAt:        time.Now(),
+			Orders:           prev.Orders,
+			Ledger:           ledger,
+			Dust:             prev.Dust,
+			CarriedDust:      prev.CarriedDust,
+			Fees:             prev.Fees,
+			UnpricedFees:     prev.UnpricedFees,
+		}
+	})
+	e.saveState()
 
+	lastFilled, liveTo := e.safetyWindow(e.cfg.Bot.SOCount)
 	e.logEntry().WithFields(map[string]interface{}{
 		"deal_id":     dealID,
 		"orders":      len(orders),
 		"tp_id":       tpOrderID,
 		"safety":      len(safetyOrders),
+		"so_filled":   lastFilled,
+		"so_live_to":  liveTo,
+		"so_anchor":   gridAnchorLevel,
+		"so_step":     soStepPercent,
+		"size_mult":   sizeMultiplier,
 		"qty":         totalQty,
 		"avg_price":   avgPrice,
 		"entry_price": entryPrice,
@@ -212,7 +253,7 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 		}
 		if tpBase > 0 {
 			tpPrice := CalcTPPrice(tpBase, e.cfg.Bot.TPPercent, side)
-			if err := e.placeTP(ctx, e.roundPrice(tpPrice), e.roundQty(totalQty), e.nextTPSuffix()); err != nil {
+			if err := e.placeTP(ctx, e.roundPrice(tpPrice), e.roundQty(totalQty+prev.CarriedDust), e.nextTPSuffix()); err != nil {
 				return true, err
 			}
 		}
@@ -225,6 +266,96 @@ func (e *Engine) restoreActiveOrders(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
+// savedDeal возвращает сохранённое состояние сделки dealID.
+func (e *Engine) savedDeal(dealID string) (DealState, bool) {
+	saved, ok := e.loadSavedState()
+	if !ok || saved.Deal.DealID != dealID {
+		return DealState{}, false
+	}
+	return saved.Deal, true
+}
+
+func (e *Engine) gridAnchorFromFills(fills []models.Fill, side models.OrderSide) (int, float64) {
+	if e.soAnchor() == soAnchorEntry {
+		return 0, 0
+	}
+	sorted := append([]models.Fill(nil), fills...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var qty, cost float64
+	anchorLevel := 0
+	anchorPrice := 0.0
+	for _, fill := range sorted {
+		if fill.Side != side {
+			continue
+		}
+		qty += fill.Qty
+		cost += fill.Qty * fill.Price
+		level, ok := safetyLevelFromLinkID(fill.LinkID)
+		if !ok || level <= anchorLevel {
+			continue
+		}
+		anchorLevel = level
+		anchorPrice = fill.Price
+		if e.soAnchor() == soAnchorAvgPrice {
+			anchorPrice = CalcAvgPrice(cost, qty)
+		}
+	}
+	return anchorLevel, anchorPrice
+}
+
+func (e *Engine) restoreSOStepPercent(dealID string, entryPrice float64, side models.OrderSide, orders []models.Order) float64 {
+	if saved, ok := e.loadSavedState(); ok && saved.Deal.DealID == dealID && saved.Deal.SOStepPercent > 0 {
+		return saved.Deal.SOStepPercent
+	}
+	if e.soStepMode() == soStepModeFixed {
+		return e.cfg.Bot.SOStepPercent
+	}
+
+	for _, ord := range orders {
+		if !isSafetyLinkID(ord.LinkID) || safetyAnchorFromLinkID(ord.LinkID) > 0 {
+			continue
+		}
+		level, ok := safetyLevelFromLinkID(ord.LinkID)
+		if !ok {
+			continue
+		}
+		if step := inferSOStepPercent(entryPrice, ord.Price, level, e.cfg.Bot.SOStepMultiplier, side); step > 0 {
+			e.logEntry().WithFields(map[string]interface{}{
+				"deal_id": dealID,
+				"link_id": ord.LinkID,
+				"step":    step,
+			}).Info("Шаг сетки восстановлен по открытому страховочному ордеру.")
+			return step
+		}
+	}
+
+	e.logEntry().WithField("deal_id", dealID).Warn("Не удалось восстановить шаг сетки, используется so_step_percent.")
+	return e.cfg.Bot.SOStepPercent
+}
+
+func (e *Engine) restoreDealSizing(dealID string, side models.OrderSide, avgPrice, sellQty, sellCost float64) (float64, float64) {
+	saved, ok := e.loadSavedState()
+	if ok && saved.Deal.DealID == dealID && saved.Deal.SizeMultiplier > 0 {
+		return saved.Deal.SizeMultiplier, saved.Deal.RealizedPnL
+	}
+
+	realizedPnL := 0.0
+	if side == models.OrderSideBuy && sellQty > 0 {
+		realizedPnL = sellCost - sellQty*avgPrice
+	}
+	sizeMultiplier := e.sizeMultiplier()
+	if e.cfg.Bot.Compounding.Enabled {
+		e.logEntry().WithFields(map[string]interface{}{
+			"deal_id":         dealID,
+			"size_multiplier": sizeMultiplier,
+		}).Warn("Множитель размера сделки не найден в сохранённом состоянии, используется текущий.")
+	}
+	return sizeMultiplier, realizedPnL
+}
+
 func pickDealID(deals map[string][]models.Order) string {
 	var best string
 	bestCount := -1
@@ -246,6 +377,40 @@ func pickDealID(deals map[string][]models.Order) string {
 	return best
 }
 
+// dealStartedAt оценивает начало сделки: по входному ордеру в истории, иначе по самому раннему
+// из открытых ордеров. Нулевое время - биржа отдаст исполнения за окно по умолчанию.
+func (e *Engine) dealStartedAt(ctx context.Context, dealID string, orders []models.Order) time.Time {
+	const margin = 1 * time.Minute
+
+	history, err := e.withRetryOrderHistory(ctx, exchange.OrderQuery{
+		Symbol: e.cfg.Bot.Symbol,
+		LinkID: dealID + "-entry",
+		Limit:  1,
+	})
+	if err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось найти входной ордер в истории.")
+	}
+	for _, ord := range history {
+		if ord.LinkID == dealID+"-entry" && ord.CreateTime.Unix() > 0 {
+			return ord.CreateTime.Add(-margin)
+		}
+	}
+
+	var started time.Time
+	for _, ord := range orders {
+		if ord.CreateTime.Unix() <= 0 {
+			continue
+		}
+		if started.IsZero() || ord.CreateTime.Before(started) {
+			started = ord.CreateTime
+		}
+	}
+	if started.IsZero() {
+		return started
+	}
+	return started.Add(-margin)
+}
+
 func dealIDFromLinkID(linkID string) (string, bool) {
 	if strings.HasSuffix(linkID, "-entry") {
 		return strings.TrimSuffix(linkID, "-entry"), true
@@ -259,6 +424,11 @@ func dealIDFromLinkID(linkID string) (string, bool) {
 	if idx := strings.LastIndex(linkID, "-so-"); idx != -1 {
 		return linkID[:idx], true
 	}
+	for _, marker := range []string{"-gb-", "-gs-", "-gi-"} {
+		if idx := strings.LastIndex(linkID, marker); idx != -1 {
+			return linkID[:idx], true
+		}
+	}
 	return "", false
 }
 
@@ -273,3 +443,35 @@ func isTPLinkID(linkID string) bool {
 func isSafetyLinkID(linkID string) bool {
 	return strings.Contains(linkID, "-so-")
 }
+
+func safetyLevelFromLinkID(linkID string) (int, bool) {
+	idx := strings.LastIndex(linkID, "-so-")
+	if idx == -1 {
+		return 0, false
+	}
+	raw := linkID[idx+len("-so-"):]
+	if dash := strings.IndexByte(raw, '-'); dash != -1 {
+		raw = raw[:dash]
+	}
+	level, err := strconv.Atoi(raw)
+	if err != nil || level <= 0 {
+		return 0, false
+	}
+	return level, true
+}
+
+func safetyAnchorFromLinkID(linkID string) int {
+	idx := strings.LastIndex(linkID, "-so-")
+	if idx == -1 {
+		return 0
+	}
+	parts := strings.Split(linkID[idx+len("-so-"):], "-")
+	if len(parts) < 2 || !strings.HasPrefix(parts[1], "a") {
+		return 0
+	}
+	anchor, err := strconv.Atoi(strings.TrimPrefix(parts[1], "a"))
+	if err != nil {
+		return 0
+	}
+	return anchor
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cronSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	anyDay   bool
+	anyWeek  bool
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Некорректное расписание %q: ожидается 5 полей (минута час день месяц день_недели).", spec)
+	}
+	minutes, err := parseCronField(fields[0], 0, 59)
+	if err != nil {
+		return nil, err
+	}
+	hours, err := parseCronField(fields[1], 0, 23)
+	if err != nil {
+		return nil, err
+	}
+	days, err := parseCronField(fields[2], 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	months, err := parseCronField(fields[3], 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	weekdays, err := parseCronField(fields[4], 0, 7)
+	if err != nil {
+		return nil, err
+	}
+	if weekdays[7] {
+		weekdays[0] = true
+	}
+	return &cronSchedule{
+		minutes:  minutes,
+		hours:    hours,
+		days:     days,
+		months:   months,
+		weekdays: weekdays,
+		anyDay:   fields[2] == "*",
+		anyWeek:  fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	result := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx != -1 {
+			parsed, err := strconv.Atoi(part[idx+1:])
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("Некорректный шаг в расписании: %q", part)
+			}
+			step = parsed
+			part = part[:idx]
+		}
+
+		from, to := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			lo, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("Некорректный диапазон в расписании: %q", part)
+			}
+			hi, err := strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("Некорректный диапазон в расписании: %q", part)
+			}
+			from, to = lo, hi
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("Некорректное значение в расписании: %q", part)
+			}
+			from, to = value, value
+			if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("Значение расписания вне диапазона %d-%d: %q", min, max, part)
+		}
+		for v := from; v <= to; v += step {
+			result[v] = true
+		}
+	}
+	return result, nil
+}
+
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) matchDay(t time.Time) bool {
+	dayOK := c.days[t.Day()]
+	weekOK := c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay && c.anyWeek:
+		return true
+	case c.anyDay:
+		return weekOK
+	case c.anyWeek:
+		return dayOK
+	default:
+		return dayOK || weekOK
+	}
+}
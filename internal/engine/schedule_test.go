@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{spec: "* * * *", wantErr: "ожидается 5 полей"},
+		{spec: "*/0 * * * *", wantErr: "Некорректный шаг"},
+		{spec: "*/x * * * *", wantErr: "Некорректный шаг"},
+		{spec: "60 * * * *", wantErr: "вне диапазона"},
+		{spec: "0 10-5 * * *", wantErr: "вне диапазона"},
+		{spec: "0 0 0 * *", wantErr: "вне диапазона"},
+		{spec: "0 0 * 1-x *", wantErr: "Некорректный диапазон"},
+		{spec: "0 0 * * mon", wantErr: "Некорректное значение"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseCron(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("parseCron(%q) error = %v, ожидалось %q", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{name: "every 15 minutes", spec: "*/15 * * * *", after: at(2024, 1, 1, 10, 7).Add(30 * time.Second), want: at(2024, 1, 1, 10, 15)},
+		{name: "strictly after", spec: "*/15 * * * *", after: at(2024, 1, 1, 10, 15), want: at(2024, 1, 1, 10, 30)},
+		{name: "range with step", spec: "5-10/5 * * * *", after: at(2024, 1, 1, 10, 10), want: at(2024, 1, 1, 11, 5)},
+		{name: "list", spec: "0 3,15 * * *", after: at(2024, 1, 1, 4, 0), want: at(2024, 1, 1, 15, 0)},
+		{name: "weekly on monday", spec: "0 9 * * 1", after: at(2024, 1, 1, 10, 0), want: at(2024, 1, 8, 9, 0)},
+		{name: "sunday as 7", spec: "0 0 * * 7", after: at(2024, 1, 1, 0, 0), want: at(2024, 1, 7, 0, 0)},
+		{name: "monthly", spec: "30 8 1 * *", after: at(2024, 1, 15, 0, 0), want: at(2024, 2, 1, 8, 30)},
+		{name: "day of month or weekday", spec: "0 12 15 * 5", after: at(2024, 1, 1, 0, 0), want: at(2024, 1, 5, 12, 0)},
+		{name: "leap day", spec: "0 0 29 2 *", after: at(2023, 3, 1, 0, 0), want: at(2024, 2, 29, 0, 0)},
+		{name: "never", spec: "0 0 30 2 *", after: at(2024, 1, 1, 0, 0), want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("parseCron(%q) error = %v", tt.spec, err)
+			}
+			if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, ожидалось %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
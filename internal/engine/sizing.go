@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type SizingState struct {
+	BaseCapital float64   `json:"base_capital"`
+	RealizedPnL float64   `json:"realized_pnl"`
+	ClosedDeals int       `json:"closed_deals"`
+	Multiplier  float64   `json:"multiplier"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (e *Engine) sizeMultiplier() float64 {
+	if !e.cfg.Bot.Compounding.Enabled {
+		return 1
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.sizing.Multiplier <= 0 {
+		return 1
+	}
+	return e.sizing.Multiplier
+}
+
+func (e *Engine) dealSizeMultiplier() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.SizeMultiplier <= 0 {
+		return 1
+	}
+	return e.state.SizeMultiplier
+}
+
+func (e *Engine) plannedCapital(entryPrice float64) float64 {
+	bot := e.cfg.Bot
+	quoteUnit := strings.EqualFold(e.qtyUnit(), "quoteCoin")
+
+	capital := bot.BaseOrderQty
+	if !quoteUnit {
+		capital = bot.BaseOrderQty * entryPrice
+	}
+	side := models.OrderSideBuy
+	if parsed, err := normalizeSide(bot.Side); err == nil {
+		side = parsed
+	}
+	for _, so := range CalcSafetyOrders(entryPrice, bot.SOCount, bot.SOStepPercent, bot.SOStepMultiplier, bot.SOBaseQty, bot.SOQtyMultiplier, side) {
+		if quoteUnit {
+			capital += so.Qty
+		} else if so.Price > 0 {
+			capital += so.Qty * so.Price
+		}
+	}
+	return capital
+}
+
+// feeInQuote переводит комиссию исполнения в quote монету. Для комиссии в сторонней
+// монете (например, BNB на binance) цены нет: возвращается false.
+func (e *Engine) feeInQuote(fill models.Fill) (float64, bool) {
+	switch {
+	case fill.Fee == 0:
+		return 0, true
+	case strings.EqualFold(fill.FeeCoin, e.rules.QuoteCoin):
+		return fill.Fee, true
+	case strings.EqualFold(fill.FeeCoin, e.rules.BaseCoin):
+		return fill.Fee * fill.Price, true
+	}
+	return 0, false
+}
+
+// chargeFee вызывается под e.mu: вычитает комиссию исполнения из результата сделки.
+// Комиссия в сторонней монете копится в UnpricedFees, пока convertUnpricedFees не
+// найдёт её цену в quote.
+func (e *Engine) chargeFee(fill models.Fill) {
+	fee, ok := e.feeInQuote(fill)
+	if ok {
+		e.state.RealizedPnL -= fee
+		e.state.Fees += fee
+		return
+	}
+	coin := strings.ToUpper(fill.FeeCoin)
+	if e.state.UnpricedFees == nil {
+		e.state.UnpricedFees = map[string]float64{}
+	}
+	e.state.UnpricedFees[coin] += fill.Fee
+	dealID := e.state.DealID
+	e.submit(jobBalances, "fee_price_"+coin, func(ctx context.Context) error {
+		return e.convertUnpricedFees(ctx, dealID, coin)
+	}, func(ctx context.Context, err error) {
+		if err != nil {
+			e.logEntry().WithError(err).WithField("fee_coin", coin).Warn("Не удалось пересчитать комиссию в сторонней монете, она не вошла в результат сделки.")
+		}
+	})
+}
+
+// convertUnpricedFees пересчитывает накопленную комиссию сделки в монете coin по
+// цене последней минутной свечи coin/quote.
+func (e *Engine) convertUnpricedFees(ctx context.Context, dealID, coin string) error {
+	symbol := coin + e.rules.QuoteCoin
+	klines, err := e.client.GetKlines(ctx, symbol, "1", 1)
+	if err != nil {
+		return err
+	}
+	if len(klines) == 0 || klines[len(klines)-1].Close <= 0 {
+		return fmt.Errorf("Нет цены %s для пересчёта комиссии.", symbol)
+	}
+	price := klines[len(klines)-1].Close
+
+	var fee float64
+	e.apply(ctx, func() {
+		if e.state.DealID != dealID {
+			return
+		}
+		fee = e.state.UnpricedFees[coin]
+		delete(e.state.UnpricedFees, coin)
+		e.state.RealizedPnL -= fee * price
+		e.state.Fees += fee * price
+	})
+	if fee == 0 {
+		return nil
+	}
+	e.saveState()
+	e.logEntry().WithFields(map[string]interface{}{
+		"deal_id":   dealID,
+		"fee_coin":  coin,
+		"fee":       fee,
+		"price":     price,
+		"fee_quote": fee * price,
+	}).Info("Комиссия в сторонней монете пересчитана в quote.")
+	return nil
+}
+
+// realizePnL вызывается под e.mu: фиксирует результат закрытия qty по price
+// относительно средней цены сделки.
+func (e *Engine) realizePnL(price, qty float64) {
+	if e.state.Side == models.OrderSideBuy {
+		e.state.RealizedPnL += (price - e.state.AvgPrice) * qty
+	} else {
+		e.state.RealizedPnL += (e.state.AvgPrice - price) * qty
+	}
+}
+
+func (e *Engine) applyDealResult(ctx context.Context, dealPnL, entryPrice float64) {
+	if !e.cfg.Bot.Compounding.Enabled {
+		return
+	}
+	compounding := e.cfg.Bot.Compounding
+
+	var sizing SizingState
+	e.apply(ctx, func() {
+		if e.sizing.BaseCapital <= 0 {
+			e.sizing.BaseCapital = compounding.BaseCapital
+			if e.sizing.BaseCapital <= 0 && entryPrice > 0 {
+				e.sizing.BaseCapital = e.plannedCapital(entryPrice)
+			}
+		}
+		e.sizing.RealizedPnL += dealPnL
+		e.sizing.ClosedDeals++
+		multiplier := 1.0
+		if e.sizing.BaseCapital > 0 {
+			multiplier = (e.sizing.BaseCapital + e.sizing.RealizedPnL) / e.sizing.BaseCapital
+		}
+		if multiplier < compounding.MinMultiplier {
+			multiplier = compounding.MinMultiplier
+		}
+		if compounding.MaxMultiplier > 0 && multiplier > compounding.MaxMultiplier {
+			multiplier = compounding.MaxMultiplier
+		}
+		e.sizing.Multiplier = multiplier
+		e.sizing.UpdatedAt = time.Now()
+		sizing = e.sizing
+	})
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"deal_pnl":        dealPnL,
+		"realized_pnl":    sizing.RealizedPnL,
+		"base_capital":    sizing.BaseCapital,
+		"closed_deals":    sizing.ClosedDeals,
+		"size_multiplier": sizing.Multiplier,
+	}).Info("Размер ордеров пересчитан по результату сделки.")
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// newFeeTestEngine возвращает движок с активной сделкой и запущенными циклом и пулом.
+func newFeeTestEngine(t *testing.T, client *fakeClient) (*Engine, context.Context) {
+	t.Helper()
+	e := newTestEngine(t, client)
+	rules, _ := client.GetInstrumentRules(context.Background(), fakeSymbol)
+	e.rules = rules
+	ctx := startLoop(t, e)
+	e.workers.start(ctx, 2, e.finishJob)
+	e.state = DealState{Active: true, DealID: "deal1", Side: models.OrderSideBuy}
+	return e, ctx
+}
+
+func TestFeeInQuote(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	e.rules.BaseCoin = "BTC"
+	e.rules.QuoteCoin = "USDT"
+
+	tests := []struct {
+		name   string
+		fill   models.Fill
+		want   float64
+		wantOK bool
+	}{
+		{name: "no fee", fill: models.Fill{Price: 100}, want: 0, wantOK: true},
+		{name: "quote", fill: models.Fill{Price: 100, Fee: 0.1, FeeCoin: "USDT"}, want: 0.1, wantOK: true},
+		{name: "base", fill: models.Fill{Price: 100, Fee: 0.001, FeeCoin: "BTC"}, want: 0.1, wantOK: true},
+		{name: "base lower case", fill: models.Fill{Price: 100, Fee: 0.001, FeeCoin: "btc"}, want: 0.1, wantOK: true},
+		{name: "third coin", fill: models.Fill{Price: 100, Fee: 0.0002, FeeCoin: "BNB"}, want: 0, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := e.feeInQuote(tt.fill)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-12 {
+				t.Fatalf("feeInQuote = %v, %v; ожидалось %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestChargeFeeThirdCoin(t *testing.T) {
+	tests := []struct {
+		name         string
+		klines       []models.Kline
+		wantPnL      float64
+		wantUnpriced float64
+	}{
+		{name: "priced by last candle", klines: []models.Kline{{Close: 300}, {Close: 500}}, wantPnL: -0.6},
+		{name: "no price", klines: nil, wantPnL: -0.1, wantUnpriced: 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient()
+			client.klines = map[string][]models.Kline{"BNBUSDT": tt.klines}
+			e, _ := newFeeTestEngine(t, client)
+
+			e.mu.Lock()
+			e.chargeFee(models.Fill{Price: 100, Fee: 0.1, FeeCoin: "USDT"})
+			e.chargeFee(models.Fill{Price: 100, Fee: 0.001, FeeCoin: "bnb"})
+			e.mu.Unlock()
+
+			done := waitFor(2*time.Second, func() bool {
+				e.mu.Lock()
+				defer e.mu.Unlock()
+				return math.Abs(e.state.RealizedPnL-tt.wantPnL) < 1e-9 &&
+					math.Abs(e.state.UnpricedFees["BNB"]-tt.wantUnpriced) < 1e-12
+			})
+			status := e.Status().Deal
+			if !done {
+				t.Fatalf("pnl=%v unpriced=%v, ожидалось pnl=%v unpriced=%v", status.RealizedPnL, status.UnpricedFees, tt.wantPnL, tt.wantUnpriced)
+			}
+			if math.Abs(status.GrossPnL) > 1e-9 {
+				t.Fatalf("gross pnl = %v, ожидался 0", status.GrossPnL)
+			}
+			if math.Abs(status.Fees+tt.wantPnL) > 1e-9 {
+				t.Fatalf("fees = %v, ожидалось %v", status.Fees, -tt.wantPnL)
+			}
+		})
+	}
+}
+
+func TestUnpricedFeeIgnoresNextDeal(t *testing.T) {
+	client := newFakeClient()
+	e, ctx := newFeeTestEngine(t, client)
+
+	e.mu.Lock()
+	e.chargeFee(models.Fill{Price: 100, Fee: 0.001, FeeCoin: "BNB"})
+	e.state = DealState{Active: true, DealID: "deal2", Side: models.OrderSideBuy}
+	e.mu.Unlock()
+
+	client.mu.Lock()
+	client.klines = map[string][]models.Kline{"BNBUSDT": {{Close: 500}}}
+	client.mu.Unlock()
+	if err := e.convertUnpricedFees(ctx, "deal1", "BNB"); err != nil {
+		t.Fatalf("convertUnpricedFees: %v", err)
+	}
+
+	status := e.Status().Deal
+	if status.RealizedPnL != 0 || status.Fees != 0 {
+		t.Fatalf("комиссия прошлой сделки попала в новую: pnl=%v fees=%v", status.RealizedPnL, status.Fees)
+	}
+}
+
+func TestApplyDealResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		enabled     bool
+		baseCapital float64
+		restored    SizingState
+		entryPrice  float64
+		deals       []float64
+		wantBase    float64
+		wantPnL     float64
+		wantClosed  int
+		want        float64
+	}{
+		{name: "disabled", baseCapital: 1000, deals: []float64{100}, want: 1},
+		{name: "profits compound", enabled: true, baseCapital: 1000, deals: []float64{100, 50}, wantBase: 1000, wantPnL: 150, wantClosed: 2, want: 1.15},
+		{name: "loss shrinks size", enabled: true, baseCapital: 1000, deals: []float64{-200}, wantBase: 1000, wantPnL: -200, wantClosed: 1, want: 0.8},
+		{name: "floor", enabled: true, baseCapital: 1000, deals: []float64{-700}, wantBase: 1000, wantPnL: -700, wantClosed: 1, want: 0.5},
+		{name: "cap", enabled: true, baseCapital: 1000, deals: []float64{1500}, wantBase: 1000, wantPnL: 1500, wantClosed: 1, want: 2},
+		{name: "base from planned capital", enabled: true, entryPrice: 100, deals: []float64{49}, wantBase: 490, wantPnL: 49, wantClosed: 1, want: 1.1},
+		{
+			name: "restored state keeps its base", enabled: true, baseCapital: 1000,
+			restored: SizingState{BaseCapital: 500, RealizedPnL: 50, ClosedDeals: 3, Multiplier: 1.1},
+			deals:    []float64{25}, wantBase: 500, wantPnL: 75, wantClosed: 4, want: 1.15,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(t, newFakeClient())
+			e.cfg.Bot.Compounding.Enabled = tt.enabled
+			e.cfg.Bot.Compounding.BaseCapital = tt.baseCapital
+			e.cfg.Bot.Compounding.MinMultiplier = 0.5
+			e.cfg.Bot.Compounding.MaxMultiplier = 2
+			e.sizing = tt.restored
+			ctx := startLoop(t, e)
+
+			for _, pnl := range tt.deals {
+				e.applyDealResult(ctx, pnl, tt.entryPrice)
+			}
+			if got := e.sizeMultiplier(); !approxEqual(got, tt.want) {
+				t.Fatalf("sizeMultiplier = %v, ожидалось %v", got, tt.want)
+			}
+			e.mu.Lock()
+			sizing := e.sizing
+			e.mu.Unlock()
+			if !tt.enabled {
+				if sizing.ClosedDeals != 0 {
+					t.Fatalf("при выключенном compounding учтено %d сделок", sizing.ClosedDeals)
+				}
+				return
+			}
+			if !approxEqual(sizing.BaseCapital, tt.wantBase) || !approxEqual(sizing.RealizedPnL, tt.wantPnL) || sizing.ClosedDeals != tt.wantClosed {
+				t.Fatalf("sizing = %+v, ожидалось base=%v pnl=%v closed=%d", sizing, tt.wantBase, tt.wantPnL, tt.wantClosed)
+			}
+		})
+	}
+}
@@ -32,4 +32,20 @@ type DealState struct {
 	UpdatedAt        time.Time          `json:"updated_at"`
 	ClosedAt         *time.Time         `json:"closed_at,omitempty"`
 	PlannedTPPrice   float64            `json:"planned_tp_price"`
+	GridAnchorLevel  int                `json:"grid_anchor_level"`
+	GridAnchorPrice  float64            `json:"grid_anchor_price"`
+	SOStepPercent    float64            `json:"so_step_percent"`
+	SizeMultiplier   float64            `json:"size_multiplier"`
+	RealizedPnL      float64            `json:"realized_pnl"`
+
+	Orders map[string]OrderRecord `json:"orders"`
+	Ledger PositionLedger         `json:"ledger"`
+
+	Dust        float64 `json:"dust"`
+	CarriedDust float64 `json:"carried_dust"`
+
+	// Fees - комиссии сделки в quote, уже вычтенные из RealizedPnL. UnpricedFees -
+	// комиссии в сторонней монете, для которых ещё нет цены.
+	Fees         float64            `json:"fees"`
+	UnpricedFees map[string]float64 `json:"unpriced_fees,omitempty"`
 }
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"dcabot/internal/models"
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// DealStatus - сводка текущей сделки для /status.
+type DealStatus struct {
+	Active      bool             `json:"active"`
+	Closing     bool             `json:"closing"`
+	DealID      string           `json:"deal_id"`
+	Side        models.OrderSide `json:"side"`
+	EntryPrice  float64          `json:"entry_price"`
+	AvgPrice    float64          `json:"avg_price"`
+	TotalQty    float64          `json:"total_qty"`
+	TPOrderID   string           `json:"tp_order_id"`
+	TPLinkID    string           `json:"tp_link_id"`
+	TPPrice     float64          `json:"tp_price"`
+	TPQty       float64          `json:"tp_qty"`
+	RealizedPnL float64          `json:"realized_pnl"`
+
+	Ledger   PositionLedger `json:"ledger"`
+	Position float64        `json:"position"`
+
+	Dust        float64 `json:"dust"`
+	CarriedDust float64 `json:"carried_dust"`
+
+	// RealizedPnL - результат за вычетом комиссий, GrossPnL - до вычета.
+	GrossPnL     float64            `json:"gross_pnl"`
+	Fees         float64            `json:"fees"`
+	UnpricedFees map[string]float64 `json:"unpriced_fees,omitempty"`
+}
+
+type Status struct {
+	Strategy string        `json:"strategy"`
+	Symbol   string        `json:"symbol"`
+	Deal     DealStatus    `json:"deal"`
+	Orders   []OrderRecord `json:"orders"`
+
+	Halted       string    `json:"halted,omitempty"`
+	ExternalBase float64   `json:"external_base"`
+	ReservedBase float64   `json:"reserved_base"`
+	Dust         DustState `json:"dust"`
+
+	Sizing         SizingState `json:"sizing"`
+	SizeMultiplier float64     `json:"size_multiplier"`
+}
+
+func (e *Engine) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	orders := make([]OrderRecord, 0, len(e.state.Orders))
+	for _, rec := range e.state.Orders {
+		orders = append(orders, rec)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].LinkID < orders[j].LinkID
+	})
+	var unpriced map[string]float64
+	if len(e.state.UnpricedFees) > 0 {
+		unpriced = make(map[string]float64, len(e.state.UnpricedFees))
+		for coin, fee := range e.state.UnpricedFees {
+			unpriced[coin] = fee
+		}
+	}
+	sizeMultiplier := 1.0
+	if e.cfg.Bot.Compounding.Enabled && e.sizing.Multiplier > 0 {
+		sizeMultiplier = e.sizing.Multiplier
+	}
+	dust := e.dust
+	dust.ByDeal = make(map[string]float64, len(e.dust.ByDeal))
+	for dealID, qty := range e.dust.ByDeal {
+		dust.ByDeal[dealID] = qty
+	}
+	return Status{
+		Strategy: e.strategy(),
+		Symbol:   e.cfg.Bot.Symbol,
+		Deal: DealStatus{
+			Active:      e.state.Active,
+			Closing:     e.state.Closing,
+			DealID:      e.state.DealID,
+			Side:        e.state.Side,
+			EntryPrice:  e.state.EntryPrice,
+			AvgPrice:    e.state.AvgPrice,
+			TotalQty:    e.state.TotalQty,
+			TPOrderID:   e.state.TPOrderID,
+			TPLinkID:    e.state.TPlinkID,
+			TPPrice:     e.state.PlannedTPPrice,
+			TPQty:       e.state.PlannedTPQty,
+			RealizedPnL: e.state.RealizedPnL,
+
+			Ledger:   e.state.Ledger,
+			Position: e.state.Ledger.Position(),
+
+			Dust:        e.state.Dust,
+			CarriedDust: e.state.CarriedDust,
+
+			GrossPnL:     e.state.RealizedPnL + e.state.Fees,
+			Fees:         e.state.Fees,
+			UnpricedFees: unpriced,
+		},
+		Orders:       orders,
+		Halted:       e.halted,
+		ExternalBase: e.reconcile.ExternalBase,
+		ReservedBase: e.cfg.Bot.ReservedBase,
+		Dust:         dust,
+
+		Sizing:         e.sizing,
+		SizeMultiplier: sizeMultiplier,
+	}
+}
+
+// ServeHTTP отдаёт Status в JSON.
+func (e *Engine) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(e.Status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"math"
+	"strings"
+)
+
+const (
+	soStepModeFixed      = "fixed"
+	soStepModeATR        = "atr"
+	soStepModeVolatility = "volatility"
+)
+
+func (e *Engine) soStepMode() string {
+	switch strings.ToLower(strings.TrimSpace(e.cfg.Bot.SOStepMode)) {
+	case soStepModeATR:
+		return soStepModeATR
+	case soStepModeVolatility:
+		return soStepModeVolatility
+	default:
+		return soStepModeFixed
+	}
+}
+
+func (e *Engine) resolveSOStepPercent(ctx context.Context) float64 {
+	mode := e.soStepMode()
+	fallback := e.cfg.Bot.SOStepPercent
+	if mode == soStepModeFixed {
+		return fallback
+	}
+
+	volCfg := e.cfg.Bot.SOStepVolatility
+	klines, err := e.withRetryKlines(ctx, e.cfg.Bot.Symbol, volCfg.Interval, volCfg.Period+1)
+	if err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось получить свечи для расчёта шага сетки, используется so_step_percent.")
+		return fallback
+	}
+
+	var metric float64
+	switch mode {
+	case soStepModeATR:
+		metric = CalcATRPercent(klines, volCfg.Period)
+	case soStepModeVolatility:
+		metric = CalcRealizedVolatilityPercent(klines, volCfg.Period)
+	}
+	if metric <= 0 {
+		e.logEntry().WithFields(map[string]interface{}{
+			"mode":    mode,
+			"klines":  len(klines),
+			"period":  volCfg.Period,
+			"percent": fallback,
+		}).Warn("Недостаточно данных для расчёта шага сетки, используется so_step_percent.")
+		return fallback
+	}
+
+	step := ClampPercent(metric*volCfg.Multiplier, volCfg.MinPercent, volCfg.MaxPercent)
+	e.logEntry().WithFields(map[string]interface{}{
+		"mode":        mode,
+		"interval":    volCfg.Interval,
+		"period":      volCfg.Period,
+		"metric":      metric,
+		"multiplier":  volCfg.Multiplier,
+		"min_percent": volCfg.MinPercent,
+		"max_percent": volCfg.MaxPercent,
+		"step":        step,
+	}).Info("Шаг сетки рассчитан по волатильности.")
+	return step
+}
+
+func inferSOStepPercent(entryPrice, price float64, level int, stepMultiplier float64, side models.OrderSide) float64 {
+	if entryPrice <= 0 || price <= 0 || level <= 0 {
+		return 0
+	}
+	totalPercent := (1 - price/entryPrice) * 100
+	if side == models.OrderSideSell {
+		totalPercent = (price/entryPrice - 1) * 100
+	}
+	var factor float64
+	for i := 0; i < level; i++ {
+		factor += math.Pow(stepMultiplier, float64(i))
+	}
+	if totalPercent <= 0 || factor <= 0 {
+		return 0
+	}
+	return totalPercent / factor
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type savedState struct {
+	Deal    DealState   `json:"deal"`
+	Sizing  SizingState `json:"sizing"`
+	SavedAt time.Time   `json:"saved_at"`
+
+	Reconcile ReconcileState `json:"reconcile"`
+	Dust      DustState      `json:"dust"`
+}
+
+// saveState снимает состояние под e.mu и передаёт его stateSaver, не дожидаясь записи.
+// До запуска движка состояние пишется сразу.
+func (e *Engine) saveState() {
+	path := e.cfg.Runtime.StateFile
+	if path == "" {
+		return
+	}
+
+	e.mu.Lock()
+	e.saveSeq++
+	seq := e.saveSeq
+	data, err := json.MarshalIndent(savedState{Deal: e.state, Sizing: e.sizing, SavedAt: time.Now(), Reconcile: e.reconcile, Dust: e.dust}, "", "  ")
+	e.mu.Unlock()
+	if err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось подготовить состояние для сохранения.")
+		return
+	}
+
+	if !e.saver.save(seq, data) {
+		e.writeState(data)
+	}
+}
+
+func (e *Engine) writeState(data []byte) {
+	if err := writeFileAtomic(e.cfg.Runtime.StateFile, data); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось сохранить состояние.")
+	}
+}
+
+// stateSaver пишет на диск последний снимок состояния в своей горутине. Более старый
+// снимок, пришедший позже нового, отбрасывается по номеру.
+type stateSaver struct {
+	mu      sync.Mutex
+	latest  []byte
+	seq     uint64
+	running bool
+	started bool
+	ready   chan struct{}
+	done    chan struct{}
+}
+
+func newStateSaver() *stateSaver {
+	return &stateSaver{ready: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+// save возвращает false, если горутина записи не запущена.
+func (s *stateSaver) save(seq uint64, data []byte) bool {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return false
+	}
+	if seq > s.seq {
+		s.seq = seq
+		s.latest = data
+	}
+	s.mu.Unlock()
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// wait ждёт выхода run, если она была запущена.
+func (s *stateSaver) wait() {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if started {
+		<-s.done
+	}
+}
+
+func (s *stateSaver) take() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := s.latest
+	s.latest = nil
+	return data
+}
+
+func (s *stateSaver) start() {
+	s.mu.Lock()
+	s.running = true
+	s.started = true
+	s.mu.Unlock()
+}
+
+// run пишет снимки до остановки ctx и перед выходом сохраняет последний.
+func (s *stateSaver) run(ctx context.Context, write func(data []byte)) {
+	defer close(s.done)
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+			if data := s.take(); data != nil {
+				write(data)
+			}
+			return
+		case <-s.ready:
+			if data := s.take(); data != nil {
+				write(data)
+			}
+		}
+	}
+}
+
+func (e *Engine) loadSavedState() (savedState, bool) {
+	path := e.cfg.Runtime.StateFile
+	if path == "" {
+		return savedState{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			e.logEntry().WithError(err).Warn("Не удалось прочитать сохранённое состояние.")
+		}
+		return savedState{}, false
+	}
+	var saved savedState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		e.logEntry().WithError(err).Warn("Не удалось разобрать сохранённое состояние.")
+		return savedState{}, false
+	}
+	return saved, true
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("Не удалось создать каталог состояния: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("Не удалось записать файл состояния: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("Не удалось заменить файл состояния: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package engine
+
+import (
+	"context"
+	"dcabot/internal/config"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const strategyTimerInterval = 5 * time.Second
+
+type StrategyEventType string
+
+const (
+	StrategyEventFill      StrategyEventType = "Fill"
+	StrategyEventOrder     StrategyEventType = "Order"
+	StrategyEventTicker    StrategyEventType = "Ticker"
+	StrategyEventReconnect StrategyEventType = "Reconnect"
+	StrategyEventTimer     StrategyEventType = "Timer"
+)
+
+type StrategyEvent struct {
+	Type   StrategyEventType
+	Fill   *models.Fill
+	Order  *models.Order
+	Ticker *models.Ticker
+	At     time.Time
+}
+
+type IntentType string
+
+const (
+	IntentPlace  IntentType = "Place"
+	IntentAmend  IntentType = "Amend"
+	IntentCancel IntentType = "Cancel"
+	IntentClose  IntentType = "Close"
+)
+
+type Intent struct {
+	Type   IntentType
+	Order  models.Order
+	Reason string
+}
+
+type DealView struct {
+	Active          bool
+	Closing         bool
+	DealID          string
+	Symbol          string
+	Side            models.OrderSide
+	EntryPrice      float64
+	AvgPrice        float64
+	TotalQty        float64
+	CarriedDust     float64
+	TPOrderID       string
+	TPLinkID        string
+	TPPrice         float64
+	TPQty           float64
+	SafetyOrders    map[string]string
+	FilledByLink    map[string]float64
+	GridAnchorLevel int
+	GridAnchorPrice float64
+	SOStepPercent   float64
+	SizeMultiplier  float64
+	LastTicker      models.Ticker
+	Rules           exchange.InstrumentRules
+}
+
+type Strategy interface {
+	Name() string
+	OnEvent(event StrategyEvent, deal DealView) []Intent
+}
+
+type StrategyFactory func(cfg *config.Config) Strategy
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]StrategyFactory{}
+)
+
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[strings.ToLower(strings.TrimSpace(name))] = factory
+}
+
+func lookupStrategy(name string) (StrategyFactory, bool) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	factory, ok := strategies[strings.ToLower(strings.TrimSpace(name))]
+	return factory, ok
+}
+
+func init() {
+	RegisterStrategy(strategyDCA, func(cfg *config.Config) Strategy {
+		return newDCAStrategy(cfg)
+	})
+}
+
+func resolveStrategy(cfg *config.Config) Strategy {
+	name := strings.ToLower(strings.TrimSpace(cfg.Bot.Strategy))
+	switch name {
+	case strategyAccumulate, strategyGrid:
+		return nil
+	}
+	if factory, ok := lookupStrategy(name); ok {
+		return factory(cfg)
+	}
+	return newDCAStrategy(cfg)
+}
+
+func (e *Engine) dealView() DealView {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	safetyOrders := make(map[string]string, len(e.state.SafetyOrders))
+	for linkID, orderID := range e.state.SafetyOrders {
+		safetyOrders[linkID] = orderID
+	}
+	filledByLink := make(map[string]float64, len(e.state.FilledByLink))
+	for linkID, qty := range e.state.FilledByLink {
+		filledByLink[linkID] = qty
+	}
+	return DealView{
+		Active:          e.state.Active,
+		Closing:         e.state.Closing,
+		DealID:          e.state.DealID,
+		Symbol:          e.cfg.Bot.Symbol,
+		Side:            e.state.Side,
+		EntryPrice:      e.state.EntryPrice,
+		AvgPrice:        e.state.AvgPrice,
+		TotalQty:        e.state.TotalQty,
+		CarriedDust:     e.state.CarriedDust,
+		TPOrderID:       e.state.TPOrderID,
+		TPLinkID:        e.state.TPlinkID,
+		TPPrice:         e.state.PlannedTPPrice,
+		TPQty:           e.state.PlannedTPQty,
+		SafetyOrders:    safetyOrders,
+		FilledByLink:    filledByLink,
+		GridAnchorLevel: e.state.GridAnchorLevel,
+		GridAnchorPrice: e.state.GridAnchorPrice,
+		SOStepPercent:   e.state.SOStepPercent,
+		SizeMultiplier:  e.state.SizeMultiplier,
+		LastTicker:      e.state.LastTicker,
+		Rules:           e.rules,
+	}
+}
+
+func (e *Engine) dispatch(ctx context.Context, event StrategyEvent) {
+	if e.strat == nil {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	intents := e.strat.OnEvent(event, e.dealView())
+	if len(intents) == 0 {
+		return
+	}
+	e.submit(jobDeal, "", func(ctx context.Context) error {
+		e.executeIntents(ctx, intents)
+		return nil
+	}, nil)
+}
+
+func (e *Engine) executeIntents(ctx context.Context, intents []Intent) {
+	if e.haltReason() != "" {
+		return
+	}
+	for _, intent := range intents {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := e.executeIntent(ctx, intent); err != nil {
+			e.logEntry().WithError(err).WithFields(map[string]interface{}{
+				"intent":  intent.Type,
+				"kind":    intent.Order.Kind,
+				"link_id": intent.Order.LinkID,
+				"reason":  intent.Reason,
+			}).Warn("Не удалось выполнить решение стратегии.")
+		}
+	}
+}
+
+func (e *Engine) executeIntent(ctx context.Context, intent Intent) error {
+	order := intent.Order
+	switch intent.Type {
+	case IntentClose:
+		e.requestClose(ctx, intent.Reason)
+		return nil
+	case IntentAmend:
+		if order.Kind == models.OrderKindTP {
+			e.apply(ctx, func() {
+				e.tpTarget = order.Price
+			})
+			e.scheduleTPRebuild(ctx)
+			return nil
+		}
+		if order.ID != "" && e.caps.Amend {
+			if order.Symbol == "" {
+				order.Symbol = e.cfg.Bot.Symbol
+			}
+			if order.PriceStep == 0 {
+				order.PriceStep = e.rules.TickSize
+			}
+			if order.QtyStep == 0 {
+				order.QtyStep = e.rules.LotSize
+			}
+			_, err := e.client.AmendOrder(ctx, order)
+			if err == nil {
+				return nil
+			}
+			e.logEntry().WithError(err).WithField("link_id", order.LinkID).Warn("Не удалось изменить ордер, перестановка через отмену.")
+		}
+		if order.ID != "" {
+			if err := e.cancelIntentOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+		order.ID = ""
+		return e.placeIntentOrder(ctx, order)
+	case IntentCancel:
+		return e.cancelIntentOrder(ctx, order)
+	case IntentPlace:
+		return e.placeIntentOrder(ctx, order)
+	default:
+		return fmt.Errorf("Неизвестный тип решения стратегии: %s", intent.Type)
+	}
+}
+
+func (e *Engine) placeIntentOrder(ctx context.Context, order models.Order) error {
+	if order.Symbol == "" {
+		order.Symbol = e.cfg.Bot.Symbol
+	}
+	if order.PriceStep == 0 {
+		order.PriceStep = e.rules.TickSize
+	}
+	if order.QtyStep == 0 {
+		order.QtyStep = e.rules.LotSize
+	}
+	if order.Qty < e.rules.MinQty {
+		return fmt.Errorf("Объём меньше минимального: %f", order.Qty)
+	}
+	if err := e.validateMinNotional(order, order.Price); err != nil {
+		return err
+	}
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"kind":    order.Kind,
+		"link_id": order.LinkID,
+		"price":   order.Price,
+		"qty":     order.Qty,
+	}).Info("Постановка ордера по решению стратегии.")
+	placed, err := e.placeOrderIdempotent(ctx, order)
+	if err != nil {
+		return err
+	}
+	if order.Kind == models.OrderKindSafety {
+		e.setSafetyOrderID(ctx, order.LinkID, placed.ID)
+	}
+	e.log.WithOrderID(placed.ID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("Ордер по решению стратегии поставлен.")
+	return nil
+}
+
+func (e *Engine) cancelIntentOrder(ctx context.Context, order models.Order) error {
+	orderID := order.ID
+	if orderID == "" && order.LinkID != "" {
+		existing, err := e.findOpenOrderByLinkID(ctx, e.cfg.Bot.Symbol, order.LinkID)
+		if err != nil {
+			return err
+		}
+		orderID = existing.ID
+	}
+	if orderID != "" {
+		e.expectCancel(ctx, orderID)
+		if err := e.withRetryVoid(ctx, func() error {
+			return e.client.CancelOrder(ctx, e.cfg.Bot.Symbol, orderID)
+		}); err != nil && !isOrderNotExistError(err) {
+			return err
+		}
+	}
+	if order.Kind == models.OrderKindSafety && order.LinkID != "" {
+		e.apply(ctx, func() {
+			delete(e.state.SafetyOrders, order.LinkID)
+		})
+	}
+	return nil
+}
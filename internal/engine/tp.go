@@ -2,13 +2,23 @@ package engine
 
 import (
 	"context"
+	"dcabot/internal/exchange"
 	"dcabot/internal/models"
 	"fmt"
+	"math"
 	"time"
 )
 
 func (e *Engine) placeTP(ctx context.Context, tpPrice, qty float64, linkSuffix string) error {
-	if qty < e.rules.MinQty {
+	if qty < e.rules.MinQty || e.isDustQty(qty, tpPrice) {
+		if dust := e.sweepPositionDust(ctx, tpPrice); dust > 0 {
+			e.logEntry().WithFields(map[string]interface{}{
+				"dust":    dust,
+				"min_qty": e.rules.MinQty,
+			}).Info("Остаток позиции меньше минимального ордера, учтён как пыль, закрытие сделки.")
+			e.requestClose(ctx, "dust")
+			return nil
+		}
 		e.logEntry().WithFields(map[string]interface{}{
 			"qty":     qty,
 			"min_qty": e.rules.MinQty,
@@ -29,23 +39,24 @@ func (e *Engine) placeTP(ctx context.Context, tpPrice, qty float64, linkSuffix s
 	}
 	tpOrder := models.Order{
 		Symbol:      e.cfg.Bot.Symbol,
-		Side:        oppositeSide(e.state.Side),
+		Side:        oppositeSide(e.dealSide()),
 		Type:        models.OrderTypeLimit,
 		Kind:        models.OrderKindTP,
 		Price:       tpPrice,
 		Qty:         qty,
 		LinkID:      tpLinkID,
 		TimeInForce: "GTC",
-		IsReduce:    true,
+		IsReduce:    e.caps.ReduceOnlySpot,
 		PriceStep:   e.rules.TickSize,
 		QtyStep:     e.rules.LotSize,
 	}
 
-	e.mu.Lock()
-	e.state.TPlinkID = tpLinkID
-	e.state.PlannedTPPrice = tpPrice
-	e.state.PlannedTPQty = qty
-	e.mu.Unlock()
+	e.apply(ctx, func() {
+		e.state.TPlinkID = tpLinkID
+		e.state.TPFilledQty = 0
+		e.state.PlannedTPPrice = tpPrice
+		e.state.PlannedTPQty = qty
+	})
 
 	e.logEntry().WithFields(map[string]interface{}{
 		"link_id": tpLinkID,
@@ -56,9 +67,9 @@ func (e *Engine) placeTP(ctx context.Context, tpPrice, qty float64, linkSuffix s
 	if err != nil {
 		return err
 	}
-	e.mu.Lock()
-	e.state.TPOrderID = order.ID
-	e.mu.Unlock()
+	e.apply(ctx, func() {
+		e.state.TPOrderID = order.ID
+	})
 	e.log.WithOrderID(order.ID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("TP поставлен.")
 	e.confirmTPStatus(ctx, tpOrder, order.ID)
 	return nil
@@ -70,13 +81,33 @@ func (e *Engine) rebuildTP(ctx context.Context) error {
 		e.mu.Unlock()
 		return nil
 	}
-	tpPrice := CalcTPPrice(e.state.AvgPrice, e.cfg.Bot.TPPercent, e.state.Side)
+	tpTarget := e.tpTarget
+	tpPrice := tpTarget
+	if tpPrice <= 0 {
+		tpPrice = CalcTPPrice(e.state.AvgPrice, e.cfg.Bot.TPPercent, e.state.Side)
+	}
 	tpPrice = e.roundPrice(tpPrice)
-	qty := e.roundQty(e.state.TotalQty)
+	qty := e.roundQty(e.state.TotalQty + e.state.CarriedDust)
 	oldOrderID := e.state.TPOrderID
 	oldTPPrice := e.state.PlannedTPPrice
+	tpFilled := e.state.TPFilledQty
 	e.mu.Unlock()
 
+	// Amend на bybit/okx задаёт полный объём ордера, а qty - остаток позиции. После
+	// частичного исполнения TP ордер переставляется через отмену.
+	if oldOrderID != "" && e.caps.Amend && tpFilled <= 0 {
+		err := e.amendTP(ctx, oldOrderID, tpPrice, qty)
+		if err == nil {
+			e.apply(ctx, func() {
+				if e.tpTarget == tpTarget {
+					e.tpTarget = 0
+				}
+			})
+			return nil
+		}
+		e.logEntry().WithError(err).Warn("Не удалось изменить TP, перестановка через отмену.")
+	}
+
 	if oldOrderID != "" {
 		e.logEntry().WithFields(map[string]interface{}{
 			"old_id":    oldOrderID,
@@ -84,35 +115,106 @@ func (e *Engine) rebuildTP(ctx context.Context) error {
 			"new_price": tpPrice,
 			"qty":       qty,
 		}).Info("Перестановка TP.")
+		e.expectCancel(ctx, oldOrderID)
 		if err := e.withRetryVoid(ctx, func() error {
 			return e.client.CancelOrder(ctx, e.cfg.Bot.Symbol, oldOrderID)
-		}); err != nil {
-			if !isOrderNotExistError(err) {
-				return err
-			}
-			e.mu.Lock()
-			e.state.TPOrderID = ""
-			e.mu.Unlock()
+		}); err != nil && !isOrderNotExistError(err) {
+			return err
 		}
+		// Старый TP снят: его исполнения больше не мешают amend нового.
+		e.apply(ctx, func() {
+			if e.state.TPOrderID == oldOrderID {
+				e.state.TPOrderID = ""
+				e.state.TPFilledQty = 0
+			}
+		})
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-time.After(500 * time.Millisecond):
 		}
 	}
-	return e.placeTP(ctx, tpPrice, qty, e.nextTPSuffix())
+	if err := e.placeTP(ctx, tpPrice, qty, e.nextTPSuffix()); err != nil {
+		return err
+	}
+	e.apply(ctx, func() {
+		if e.tpTarget == tpTarget {
+			e.tpTarget = 0
+		}
+	})
+	return nil
 }
 
+func (e *Engine) amendTP(ctx context.Context, orderID string, tpPrice, qty float64) error {
+	adjustedQty, err := e.resolveTPQty(ctx, qty)
+	if err != nil {
+		return err
+	}
+	if adjustedQty < e.rules.MinQty {
+		return fmt.Errorf("Объём TP меньше минимального: %f", adjustedQty)
+	}
+	qty = adjustedQty
+	if err := e.validateMinNotional(models.Order{Price: tpPrice, Qty: qty, Type: models.OrderTypeLimit}, tpPrice); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	oldTPPrice := e.state.PlannedTPPrice
+	order := models.Order{
+		ID:        orderID,
+		LinkID:    e.state.TPlinkID,
+		Symbol:    e.cfg.Bot.Symbol,
+		Side:      oppositeSide(e.state.Side),
+		Type:      models.OrderTypeLimit,
+		Kind:      models.OrderKindTP,
+		Price:     tpPrice,
+		Qty:       qty,
+		PriceStep: e.rules.TickSize,
+		QtyStep:   e.rules.LotSize,
+	}
+	e.mu.Unlock()
+
+	e.logEntry().WithFields(map[string]interface{}{
+		"order_id":  orderID,
+		"old_price": oldTPPrice,
+		"new_price": tpPrice,
+		"qty":       qty,
+	}).Info("Изменение TP.")
+	amended, err := e.client.AmendOrder(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	e.apply(ctx, func() {
+		if amended.ID != "" {
+			e.state.TPOrderID = amended.ID
+		}
+		e.state.PlannedTPPrice = tpPrice
+		e.state.PlannedTPQty = qty
+	})
+	e.log.WithOrderID(orderID).WithField("component", "engine").WithField("symbol", e.cfg.Bot.Symbol).Info("TP изменён.")
+	return nil
+}
+
+const (
+	tpRebuildDebounce   = 700 * time.Millisecond
+	tpRebuildRetryDelay = 1 * time.Second
+)
+
+// scheduleTPRebuild откладывает перестановку TP на tpRebuildDebounce, сдвигая срок при
+// каждом вызове. Сама перестановка идёт в пуле под jobDeal, поэтому не пересекается
+// с закрытием сделки и запуском нового цикла.
 func (e *Engine) scheduleTPRebuild(ctx context.Context) {
-	const debounce = 700 * time.Millisecond
-	const retryDelay = 1 * time.Second
+	e.scheduleTPRebuildAfter(tpRebuildDebounce)
+}
 
+func (e *Engine) scheduleTPRebuildAfter(delay time.Duration) {
 	e.mu.Lock()
-	if !e.state.Active {
+	if !e.state.Active || e.halted != "" {
 		e.mu.Unlock()
 		return
 	}
-	e.tpRebuildAt = time.Now().Add(debounce)
+	e.tpRebuildAt = time.Now().Add(delay)
 	if e.tpRebuildScheduled {
 		e.mu.Unlock()
 		return
@@ -120,47 +222,33 @@ func (e *Engine) scheduleTPRebuild(ctx context.Context) {
 	e.tpRebuildScheduled = true
 	e.mu.Unlock()
 
-	go func() {
-		for {
-			e.mu.Lock()
-			dueAt := e.tpRebuildAt
-			e.mu.Unlock()
-
-			wait := time.Until(dueAt)
-			if wait > 0 {
-				select {
-				case <-ctx.Done():
-					e.mu.Lock()
-					e.tpRebuildScheduled = false
-					e.mu.Unlock()
-					return
-				case <-time.After(wait):
-				}
-			}
+	time.AfterFunc(delay, func() {
+		e.post(e.fireTPRebuild)
+	})
+}
 
-			e.mu.Lock()
-			if time.Now().Before(e.tpRebuildAt) {
-				e.mu.Unlock()
-				continue
-			}
-			e.tpRebuildScheduled = false
-			e.mu.Unlock()
+func (e *Engine) fireTPRebuild(ctx context.Context) {
+	e.mu.Lock()
+	if wait := time.Until(e.tpRebuildAt); wait > 0 {
+		e.mu.Unlock()
+		time.AfterFunc(wait, func() {
+			e.post(e.fireTPRebuild)
+		})
+		return
+	}
+	e.tpRebuildScheduled = false
+	e.mu.Unlock()
 
-			if err := e.rebuildTP(ctx); err != nil {
-				e.logEntry().WithError(err).Warn("Не удалось переставить TP.")
-				e.mu.Lock()
-				e.tpRebuildAt = time.Now().Add(retryDelay)
-				e.tpRebuildScheduled = true
-				e.mu.Unlock()
-				continue
-			}
-			return
+	e.submit(jobDeal, "tp_rebuild", e.rebuildTP, func(ctx context.Context, err error) {
+		if err != nil {
+			e.logEntry().WithError(err).Warn("Не удалось переставить TP.")
+			e.scheduleTPRebuildAfter(tpRebuildRetryDelay)
 		}
-	}()
+	})
 }
 
 func (e *Engine) resolveTPQty(ctx context.Context, qty float64) (float64, error) {
-	if e.state.Side != models.OrderSideBuy {
+	if e.dealSide() != models.OrderSideBuy {
 		return qty, nil
 	}
 	base := e.rules.BaseCoin
@@ -203,11 +291,7 @@ func (e *Engine) resolveTPQty(ctx context.Context, qty float64) (float64, error)
 		} else if bal, ok := balances[base]; ok {
 			lastAvailable = bal.Available
 			lastWallet = bal.Wallet
-			balance := lastWallet
-			if balance <= 0 {
-				balance = lastAvailable
-			}
-			if balance >= minAvailable {
+			if e.dealBase(lastWallet, lastAvailable) >= minAvailable {
 				return qty, nil
 			}
 		}
@@ -232,14 +316,11 @@ func (e *Engine) resolveTPQty(ctx context.Context, qty float64) (float64, error)
 		return 0, fmt.Errorf("Не удалось получить баланс для TP: %w", lastErr)
 	}
 
-	balance := lastWallet
-	if balance <= 0 {
-		balance = lastAvailable
-	}
+	balance := e.dealBase(lastWallet, lastAvailable)
 	if balance > 0 && qty > 0 && (balance/qty) < fullRatioThreshold {
 		return 0, fmt.Errorf("Баланс для TP не обновился: need=%f available=%f wallet=%f", qty, lastAvailable, lastWallet)
 	}
-	adjusted := e.roundQty(balance)
+	adjusted := e.roundQty(math.Min(balance, qty))
 	if adjusted < e.rules.MinQty {
 		e.logEntry().WithFields(map[string]interface{}{
 			"need":      qty,
@@ -256,11 +337,11 @@ func (e *Engine) resolveTPQty(ctx context.Context, qty float64) (float64, error)
 			"wallet":    lastWallet,
 			"available": lastAvailable,
 		}).Info("Корректировка TP по балансу.")
-		e.mu.Lock()
-		if adjusted < e.state.TotalQty && (adjusted/qty) >= fullRatioThreshold {
-			e.state.TotalQty = adjusted
-		}
-		e.mu.Unlock()
+		e.apply(ctx, func() {
+			if adjusted < e.state.TotalQty && (adjusted/qty) >= fullRatioThreshold {
+				e.state.TotalQty = adjusted
+			}
+		})
 	}
 	return adjusted, nil
 }
@@ -318,7 +399,7 @@ func (e *Engine) confirmTPStatus(ctx context.Context, tpOrder models.Order, orde
 		}
 	}
 
-	fills, err := e.withRetryFills(ctx, tpOrder.Symbol)
+	fills, err := e.withRetryFills(ctx, exchange.FillQuery{Symbol: tpOrder.Symbol, LinkID: tpOrder.LinkID})
 	if err != nil {
 		e.logEntry().WithError(err).Warn("Не удалось проверить исполнения TP.")
 		return
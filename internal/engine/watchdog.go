@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+const stalenessCheckInterval = 1 * time.Second
+
+func (e *Engine) tickerAge() time.Duration {
+	e.mu.Lock()
+	at := e.state.LastTicker.Timestamp
+	e.mu.Unlock()
+	if at.IsZero() {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Since(at)
+}
+
+func (e *Engine) isTickerStale() bool {
+	if e.cfg.Runtime.TickerStaleAfter <= 0 {
+		return false
+	}
+	return e.tickerAge() > e.cfg.Runtime.TickerStaleAfter
+}
+
+func (e *Engine) watchTickerStaleness(ctx context.Context) {
+	if e.cfg.Runtime.TickerStaleAfter <= 0 {
+		return
+	}
+	ticker := time.NewTicker(stalenessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stale := e.isTickerStale()
+		e.mu.Lock()
+		changed := stale != e.tickerStale
+		e.tickerStale = stale
+		e.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if stale {
+			e.logEntry().WithFields(map[string]interface{}{
+				"stale_after": e.cfg.Runtime.TickerStaleAfter.String(),
+				"age":         e.tickerAge().Round(time.Second).String(),
+			}).Warn("Нет свежих тикеров, рыночные ордера приостановлены.")
+		} else {
+			e.logEntry().Info("Тикеры снова поступают, рыночные ордера разрешены.")
+		}
+	}
+}
+
+func (e *Engine) waitFreshTicker(ctx context.Context) error {
+	logged := false
+	for e.isTickerStale() {
+		if !logged {
+			e.logEntry().Warn("Данные тикера устарели, рыночный ордер отложен до поступления свежей цены.")
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(stalenessCheckInterval):
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"context"
+	"sync"
+)
+
+const engineWorkers = 4
+
+// Ключи задач пула. Задачи с одним ключом выполняются строго по очереди: всё, что
+// ставит или снимает ордера сделки (TP, закрытие, новый цикл, сверка), идёт через jobDeal.
+const (
+	jobDeal     = "deal"
+	jobBalances = "balances"
+)
+
+type job struct {
+	key  string
+	name string
+	run  func(ctx context.Context) error
+	done func(ctx context.Context, err error)
+	wait chan error
+
+	// merged - done отброшенных повторов: вызываются с результатом этой задачи.
+	merged []func(ctx context.Context, err error)
+}
+
+type lane struct {
+	pending []*job
+}
+
+// workerPool выполняет запросы к бирже вне цикла событий. Повторная задача с тем же
+// непустым name, пока предыдущая ещё ждёт в очереди ключа, отбрасывается, а её done
+// вызывается с результатом ожидающей задачи.
+type workerPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	ready  []*job
+	lanes  map[string]*lane
+	closed bool
+}
+
+func newWorkerPool() *workerPool {
+	p := &workerPool{lanes: map[string]*lane{}}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *workerPool) start(ctx context.Context, workers int, finish func(j *job, err error)) {
+	for i := 0; i < workers; i++ {
+		go p.work(ctx, finish)
+	}
+	go func() {
+		<-ctx.Done()
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		p.cond.Broadcast()
+	}()
+}
+
+func (p *workerPool) submit(j *job) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+
+	l, busy := p.lanes[j.key]
+	if !busy {
+		p.lanes[j.key] = &lane{}
+		p.ready = append(p.ready, j)
+		p.cond.Signal()
+		return true
+	}
+	if j.name != "" {
+		for _, queued := range l.pending {
+			if queued.name == j.name {
+				if j.done != nil {
+					queued.merged = append(queued.merged, j.done)
+				}
+				return true
+			}
+		}
+	}
+	l.pending = append(l.pending, j)
+	return true
+}
+
+func (p *workerPool) work(ctx context.Context, finish func(j *job, err error)) {
+	for {
+		p.mu.Lock()
+		for len(p.ready) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		j := p.ready[0]
+		p.ready[0] = nil
+		p.ready = p.ready[1:]
+		p.mu.Unlock()
+
+		err := j.run(ctx)
+
+		p.mu.Lock()
+		if l := p.lanes[j.key]; l != nil && len(l.pending) > 0 {
+			next := l.pending[0]
+			l.pending[0] = nil
+			l.pending = l.pending[1:]
+			p.ready = append(p.ready, next)
+			p.cond.Signal()
+		} else {
+			delete(p.lanes, j.key)
+		}
+		p.mu.Unlock()
+
+		finish(j, err)
+	}
+}
+
+// submit ставит запрос к бирже в пул. Результат done возвращается в цикл событий.
+func (e *Engine) submit(key, name string, run func(ctx context.Context) error, done func(ctx context.Context, err error)) {
+	e.workers.submit(&job{key: key, name: name, run: run, done: done})
+}
+
+// runExclusive выполняет fn в пуле под ключом key и ждёт результата.
+// Нельзя вызывать из задачи с тем же ключом.
+func (e *Engine) runExclusive(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	result := make(chan error, 1)
+	if !e.workers.submit(&job{key: key, run: fn, wait: result}) {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-result:
+		return err
+	}
+}
+
+func (e *Engine) finishJob(j *job, err error) {
+	if j.wait != nil {
+		j.wait <- err
+	}
+	if j.done == nil && len(j.merged) == 0 {
+		return
+	}
+	e.post(func(ctx context.Context) {
+		if j.done != nil {
+			j.done(ctx, err)
+		}
+		for _, done := range j.merged {
+			done(ctx, err)
+		}
+	})
+}
+
+// inbox - очередь функций для цикла событий. Очередь не ограничена, поэтому post
+// никогда не блокирует пул, даже если цикл занят разбором всплеска событий.
+type inbox struct {
+	mu    sync.Mutex
+	queue []func(ctx context.Context)
+	ready chan struct{}
+}
+
+func newInbox() *inbox {
+	return &inbox{ready: make(chan struct{}, 1)}
+}
+
+func (b *inbox) push(fn func(ctx context.Context)) {
+	b.mu.Lock()
+	b.queue = append(b.queue, fn)
+	b.mu.Unlock()
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (b *inbox) drain() []func(ctx context.Context) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue := b.queue
+	b.queue = nil
+	return queue
+}
+
+// post передаёт fn в цикл событий без ожидания.
+func (e *Engine) post(fn func(ctx context.Context)) {
+	e.inbox.push(fn)
+}
+
+type loopKey struct{}
+
+// loopContext помечает контекст цикла событий: apply из него выполняется сразу.
+func loopContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loopKey{}, true)
+}
+
+func onLoop(ctx context.Context) bool {
+	on, _ := ctx.Value(loopKey{}).(bool)
+	return on
+}
+
+// call выполняет fn в цикле событий и ждёт её завершения. Из самого цикла fn
+// выполняется сразу. Возвращает false, если движок остановлен и fn не выполнена.
+func (e *Engine) call(ctx context.Context, fn func(ctx context.Context)) bool {
+	if onLoop(ctx) {
+		fn(ctx)
+		return true
+	}
+	done := make(chan struct{})
+	e.post(func(ctx context.Context) {
+		fn(ctx)
+		close(done)
+	})
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-e.stopped:
+		return false
+	}
+}
+
+// apply применяет результат задачи пула к состоянию в цикле событий и ждёт этого.
+// Состояние меняет только цикл событий: задачи пула читают его под e.mu, а изменения
+// передают через apply. fn выполняется под e.mu.
+func (e *Engine) apply(ctx context.Context, fn func()) bool {
+	return e.call(ctx, func(context.Context) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		fn()
+	})
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startLoop запускает цикл событий движка без биржевого потока.
+func startLoop(t *testing.T, e *Engine) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	e.stopped = ctx.Done()
+	go e.handleEvents(ctx, nil)
+	return ctx
+}
+
+func TestPostDoesNotBlock(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10000; i++ {
+			e.post(func(ctx context.Context) {})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("post блокируется без цикла событий")
+	}
+	if got := len(e.inbox.drain()); got != 10000 {
+		t.Fatalf("в очереди %d функций, ожидалось 10000", got)
+	}
+}
+
+func TestCallRunsOnLoop(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	ctx := startLoop(t, e)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "from worker", ctx: ctx},
+		{name: "from loop", ctx: loopContext(ctx)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var onLoopCtx bool
+			if !e.call(tt.ctx, func(ctx context.Context) { onLoopCtx = onLoop(ctx) }) {
+				t.Fatal("call вернул false при работающем цикле")
+			}
+			if !onLoopCtx {
+				t.Fatal("fn выполнена вне цикла событий")
+			}
+		})
+	}
+}
+
+func TestApplyConcurrentWorkers(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	ctx := startLoop(t, e)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				e.apply(ctx, func() { e.state.TotalQty++ })
+			}
+		}()
+	}
+	wg.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.TotalQty != 800 {
+		t.Fatalf("TotalQty = %f, ожидалось 800", e.state.TotalQty)
+	}
+}
+
+func TestApplyStopped(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	ctx, cancel := context.WithCancel(context.Background())
+	e.stopped = ctx.Done()
+	cancel()
+
+	if e.apply(ctx, func() { e.state.TotalQty = 1 }) {
+		t.Fatal("apply без цикла событий вернул true")
+	}
+}
+
+func TestStateSaverKeepsNewest(t *testing.T) {
+	tests := []struct {
+		name  string
+		saves []uint64
+		want  string
+	}{
+		{name: "in order", saves: []uint64{1, 2, 3}, want: "3"},
+		{name: "stale after newer", saves: []uint64{2, 3, 1}, want: "3"},
+		{name: "single", saves: []uint64{5}, want: "5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saver := newStateSaver()
+			saver.start()
+			for _, seq := range tt.saves {
+				saver.save(seq, []byte{byte('0' + seq)})
+			}
+
+			var mu sync.Mutex
+			var last string
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			saver.run(ctx, func(data []byte) {
+				mu.Lock()
+				last = string(data)
+				mu.Unlock()
+			})
+			if last != tt.want {
+				t.Fatalf("записан снимок %q, ожидался %q", last, tt.want)
+			}
+			if saver.save(9, []byte("9")) {
+				t.Fatal("save после остановки должен вернуть false")
+			}
+		})
+	}
+}
+
+func TestWorkerPoolMergesDuplicateDone(t *testing.T) {
+	e := newTestEngine(t, newFakeClient())
+	ctx := startLoop(t, e)
+	e.workers.start(ctx, 2, e.finishJob)
+
+	release := make(chan struct{})
+	e.submit(jobDeal, "block", func(ctx context.Context) error {
+		<-release
+		return nil
+	}, nil)
+
+	var runs, dones atomic.Int32
+	allDone := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		e.submit(jobDeal, "sync", func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}, func(ctx context.Context, err error) {
+			if !onLoop(ctx) {
+				t.Error("done вызван вне цикла событий")
+			}
+			if dones.Add(1) == 3 {
+				close(allDone)
+			}
+		})
+	}
+	close(release)
+
+	select {
+	case <-allDone:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("вызвано %d из 3 done", dones.Load())
+	}
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("повторная задача выполнена %d раз, ожидался 1", got)
+	}
+}
@@ -0,0 +1,172 @@
+package eventbus
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"expvar"
+	"strings"
+	"sync"
+	"time"
+)
+
+var stats = expvar.NewMap("eventbus")
+
+type item struct {
+	event exchange.Event
+	at    time.Time
+}
+
+// Bus развязывает WS клиентов и движок: исполнения, ордера и реконнекты копятся в
+// неограниченной очереди и не теряются, тикеры схлопываются до последнего по символу.
+type Bus struct {
+	mu      sync.Mutex
+	queue   []item
+	tickers map[string]item
+	symbols []string
+	done    bool
+	signal  chan struct{}
+	out     chan exchange.Event
+
+	queueDepth  *expvar.Int
+	tickerDepth *expvar.Int
+	lagLast     *expvar.Int
+	lagMax      *expvar.Int
+}
+
+func New() *Bus {
+	b := &Bus{
+		tickers:     map[string]item{},
+		signal:      make(chan struct{}, 1),
+		out:         make(chan exchange.Event),
+		queueDepth:  new(expvar.Int),
+		tickerDepth: new(expvar.Int),
+		lagLast:     new(expvar.Int),
+		lagMax:      new(expvar.Int),
+	}
+	stats.Set("queue_depth", b.queueDepth)
+	stats.Set("ticker_pending", b.tickerDepth)
+	stats.Set("lag_ms_last", b.lagLast)
+	stats.Set("lag_ms_max", b.lagMax)
+	return b
+}
+
+func (b *Bus) Events() <-chan exchange.Event {
+	return b.out
+}
+
+// Run перекачивает события из src в шину, не блокируя источник, и доставляет их в Events.
+// Events закрывается после закрытия src и доставки оставшейся очереди или по ctx.
+func (b *Bus) Run(ctx context.Context, src <-chan exchange.Event) {
+	go b.deliver(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-src:
+			if !ok {
+				b.mu.Lock()
+				b.done = true
+				b.mu.Unlock()
+				b.notify()
+				return
+			}
+			b.Publish(event)
+		}
+	}
+}
+
+func (b *Bus) Publish(event exchange.Event) {
+	now := time.Now()
+	stats.Add("published_"+metricName(event.Type), 1)
+
+	b.mu.Lock()
+	if event.Type == exchange.EventTypeTicker && event.Ticker != nil {
+		// Схлопнутый тикер сохраняет время первого ожидающего, иначе задержка доставки
+		// под постоянным потоком тикеров выглядела бы нулевой.
+		symbol := event.Ticker.Symbol
+		at := now
+		if prev, pending := b.tickers[symbol]; pending {
+			at = prev.at
+			stats.Add("coalesced_"+metricName(event.Type), 1)
+		} else {
+			b.symbols = append(b.symbols, symbol)
+		}
+		b.tickers[symbol] = item{event: event, at: at}
+	} else {
+		b.queue = append(b.queue, item{event: event, at: now})
+	}
+	b.updateDepth()
+	b.mu.Unlock()
+
+	b.notify()
+}
+
+func (b *Bus) notify() {
+	select {
+	case b.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bus) next() (item, bool, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.updateDepth()
+
+	if len(b.queue) > 0 {
+		next := b.queue[0]
+		b.queue[0] = item{}
+		b.queue = b.queue[1:]
+		return next, true, false
+	}
+	if len(b.symbols) > 0 {
+		symbol := b.symbols[0]
+		b.symbols = b.symbols[1:]
+		next := b.tickers[symbol]
+		delete(b.tickers, symbol)
+		return next, true, false
+	}
+	return item{}, false, b.done
+}
+
+func (b *Bus) updateDepth() {
+	b.queueDepth.Set(int64(len(b.queue)))
+	b.tickerDepth.Set(int64(len(b.symbols)))
+}
+
+func (b *Bus) deliver(ctx context.Context) {
+	defer close(b.out)
+
+	for {
+		next, ok, done := b.next()
+		if !ok {
+			if done {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-b.signal:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case b.out <- next.event:
+		}
+
+		lag := time.Since(next.at).Milliseconds()
+		b.lagLast.Set(lag)
+		if lag > b.lagMax.Value() {
+			b.lagMax.Set(lag)
+		}
+		stats.Add("delivered_"+metricName(next.event.Type), 1)
+	}
+}
+
+func metricName(eventType exchange.EventType) string {
+	return strings.ToLower(string(eventType))
+}
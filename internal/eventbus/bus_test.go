@@ -0,0 +1,190 @@
+package eventbus
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"testing"
+	"time"
+)
+
+func fillEvent(seq int64) exchange.Event {
+	return exchange.Event{Type: exchange.EventTypeFill, Fill: &models.Fill{Symbol: "BTCUSDT", Sequence: seq}}
+}
+
+func orderEvent(seq int64) exchange.Event {
+	return exchange.Event{Type: exchange.EventTypeOrder, Order: &models.Order{Symbol: "BTCUSDT", Sequence: seq}}
+}
+
+func tickerEvent(symbol string, seq int64) exchange.Event {
+	return exchange.Event{Type: exchange.EventTypeTicker, Ticker: &models.Ticker{Symbol: symbol, Sequence: seq}}
+}
+
+// collect читает Events до закрытия канала.
+func collect(t *testing.T, b *Bus) []exchange.Event {
+	t.Helper()
+	var events []exchange.Event
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-b.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		case <-timeout:
+			t.Fatalf("Events не закрылся, получено %d событий", len(events))
+		}
+	}
+}
+
+func sequence(event exchange.Event) int64 {
+	switch {
+	case event.Fill != nil:
+		return event.Fill.Sequence
+	case event.Order != nil:
+		return event.Order.Sequence
+	case event.Ticker != nil:
+		return event.Ticker.Sequence
+	}
+	return 0
+}
+
+func TestFillsAndOrdersNeverDropped(t *testing.T) {
+	b := New()
+	src := make(chan exchange.Event)
+	go b.Run(context.Background(), src)
+
+	// Движок не читает, пока источник не отдаст всё: шина не должна блокировать src.
+	const total = 3000
+	for i := int64(1); i <= total; i++ {
+		switch i % 3 {
+		case 0:
+			src <- fillEvent(i)
+		case 1:
+			src <- orderEvent(i)
+		default:
+			src <- tickerEvent("BTCUSDT", i)
+		}
+	}
+	close(src)
+
+	var last int64
+	var delivered int
+	for _, event := range collect(t, b) {
+		if event.Type == exchange.EventTypeTicker {
+			continue
+		}
+		seq := sequence(event)
+		if seq <= last {
+			t.Fatalf("нарушен порядок: %d после %d", seq, last)
+		}
+		last = seq
+		delivered++
+	}
+	if want := total / 3 * 2; delivered != want {
+		t.Fatalf("доставлено %d исполнений и ордеров, ожидалось %d", delivered, want)
+	}
+}
+
+func TestTickersCoalescePerSymbol(t *testing.T) {
+	tests := []struct {
+		name    string
+		publish []exchange.Event
+		want    map[string]int64
+		order   []string
+	}{
+		{
+			name:    "single symbol",
+			publish: []exchange.Event{tickerEvent("BTCUSDT", 1), tickerEvent("BTCUSDT", 2), tickerEvent("BTCUSDT", 3)},
+			want:    map[string]int64{"BTCUSDT": 3},
+			order:   []string{"BTCUSDT"},
+		},
+		{
+			name: "interleaved symbols",
+			publish: []exchange.Event{
+				tickerEvent("BTCUSDT", 1), tickerEvent("ETHUSDT", 1), tickerEvent("BTCUSDT", 2),
+				tickerEvent("ETHUSDT", 2), tickerEvent("BTCUSDT", 3),
+			},
+			want:  map[string]int64{"BTCUSDT": 3, "ETHUSDT": 2},
+			order: []string{"BTCUSDT", "ETHUSDT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New()
+			for _, event := range tt.publish {
+				b.Publish(event)
+			}
+			src := make(chan exchange.Event)
+			close(src)
+			go b.Run(context.Background(), src)
+
+			events := collect(t, b)
+			if len(events) != len(tt.order) {
+				t.Fatalf("доставлено %d тикеров, ожидалось %d", len(events), len(tt.order))
+			}
+			for i, event := range events {
+				symbol := event.Ticker.Symbol
+				if symbol != tt.order[i] {
+					t.Fatalf("тикер %d: %s, ожидался %s", i, symbol, tt.order[i])
+				}
+				if event.Ticker.Sequence != tt.want[symbol] {
+					t.Fatalf("тикер %s: seq %d, ожидался последний %d", symbol, event.Ticker.Sequence, tt.want[symbol])
+				}
+			}
+		})
+	}
+}
+
+func TestCoalescedTickerKeepsFirstTimestamp(t *testing.T) {
+	b := New()
+	b.Publish(tickerEvent("BTCUSDT", 1))
+	first := time.Now()
+	time.Sleep(20 * time.Millisecond)
+	b.Publish(tickerEvent("BTCUSDT", 2))
+
+	next, ok, _ := b.next()
+	if !ok {
+		t.Fatal("тикер не в очереди")
+	}
+	if next.event.Ticker.Sequence != 2 {
+		t.Fatalf("seq %d, ожидался последний 2", next.event.Ticker.Sequence)
+	}
+	if next.at.After(first) {
+		t.Fatalf("время ожидания сдвинуто схлопыванием: %s после %s", next.at, first)
+	}
+}
+
+func TestEventsCloses(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel bool
+	}{
+		{name: "source closed"},
+		{name: "context cancelled", cancel: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			b := New()
+			src := make(chan exchange.Event)
+			go b.Run(ctx, src)
+
+			src <- fillEvent(1)
+			src <- orderEvent(2)
+			if tt.cancel {
+				cancel()
+				collect(t, b)
+				return
+			}
+			close(src)
+
+			events := collect(t, b)
+			if len(events) != 2 || sequence(events[0]) != 1 || sequence(events[1]) != 2 {
+				t.Fatalf("после закрытия источника доставлено %d событий, ожидалось 2 по порядку", len(events))
+			}
+		})
+	}
+}
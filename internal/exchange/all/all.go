@@ -0,0 +1,7 @@
+package all
+
+import (
+	_ "dcabot/internal/exchange/binance"
+	_ "dcabot/internal/exchange/bybit"
+	_ "dcabot/internal/exchange/okx"
+)
@@ -0,0 +1,786 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dcabot/internal/exchange"
+	"dcabot/internal/logger"
+	"dcabot/internal/models"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	stubAPIKey    = "stub-api-key"
+	stubSecret    = "stub-secret"
+	stubListenKey = "stub-listen-key"
+	stubSymbol    = "BTCUSDT"
+	stubFeeRate   = 0.001
+)
+
+type stubOrder struct {
+	ID       int64
+	LinkID   string
+	Side     string
+	Type     string
+	Price    float64
+	Qty      float64
+	Executed float64
+	Status   string
+	Time     int64
+}
+
+type stubTrade struct {
+	ID       int64
+	OrderID  int64
+	Price    float64
+	Qty      float64
+	Fee      float64
+	FeeAsset string
+	IsBuyer  bool
+	Time     int64
+}
+
+// stubBinance - REST и WS binance в памяти: market ордера исполняются сразу по
+// текущей цене, limit ордера ждут fill из теста.
+type stubBinance struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu      sync.Mutex
+	price   float64
+	nextID  int64
+	orders  map[int64]*stubOrder
+	trades  []stubTrade
+	conns   map[string]*websocket.Conn
+	connsCh chan string
+
+	// quoteFees - комиссия покупки в USDT, как при оплате комиссии не из базовой монеты.
+	quoteFees bool
+}
+
+func newStubBinance(t *testing.T) *stubBinance {
+	t.Helper()
+	s := &stubBinance{
+		t:       t,
+		price:   100,
+		nextID:  1000,
+		orders:  map[int64]*stubOrder{},
+		conns:   map[string]*websocket.Conn{},
+		connsCh: make(chan string, 4),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubBinance) wsURL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+func (s *stubBinance) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/stream" || r.URL.Path == "/ws/"+stubListenKey:
+		s.serveWS(w, r)
+		return
+	case r.URL.Path == "/api/v3/exchangeInfo":
+		writeJSON(w, map[string]any{"symbols": []map[string]any{{
+			"symbol":     stubSymbol,
+			"status":     "TRADING",
+			"baseAsset":  "BTC",
+			"quoteAsset": "USDT",
+			"filters": []map[string]string{
+				{"filterType": "PRICE_FILTER", "tickSize": "0.01"},
+				{"filterType": "LOT_SIZE", "stepSize": "0.00001", "minQty": "0.00001"},
+				{"filterType": "NOTIONAL", "minNotional": "5"},
+			},
+		}}})
+		return
+	}
+
+	if r.Header.Get("X-MBX-APIKEY") != stubAPIKey {
+		writeError(w, http.StatusUnauthorized, -2015, "Invalid API-key, IP, or permissions for action.")
+		return
+	}
+	if r.URL.Path == "/api/v3/userDataStream" {
+		writeJSON(w, map[string]string{"listenKey": stubListenKey})
+		return
+	}
+	if !validSignature(r.URL.RawQuery) {
+		writeError(w, http.StatusUnauthorized, -1022, "Signature for this request is not valid.")
+		return
+	}
+
+	query := r.URL.Query()
+	switch r.Method + " " + r.URL.Path {
+	case "POST /api/v3/order":
+		s.placeOrder(w, query.Get("newClientOrderId"), query.Get("side"), query.Get("type"), parseFloat(query.Get("price")), parseFloat(query.Get("quantity")))
+	case "DELETE /api/v3/order":
+		s.cancelOrder(w, parseInt(query.Get("orderId")))
+	case "GET /api/v3/order":
+		order, ok := s.findOrder(parseInt(query.Get("orderId")), query.Get("origClientOrderId"))
+		if !ok {
+			writeError(w, http.StatusBadRequest, -2013, "Order does not exist.")
+			return
+		}
+		writeJSON(w, order)
+	case "GET /api/v3/openOrders":
+		writeJSON(w, s.orderInfos(true))
+	case "GET /api/v3/allOrders":
+		writeJSON(w, s.orderInfos(false))
+	case "GET /api/v3/myTrades":
+		writeJSON(w, s.tradeInfos(parseInt(query.Get("orderId"))))
+	case "GET /api/v3/account":
+		writeJSON(w, s.account())
+	default:
+		writeError(w, http.StatusNotFound, -1000, "Unknown endpoint "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func (s *stubBinance) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Errorf("upgrade ws: %v", err)
+		return
+	}
+	name := "user"
+	if r.URL.Path == "/stream" {
+		name = "public"
+	}
+	s.mu.Lock()
+	s.conns[name] = conn
+	s.mu.Unlock()
+	s.connsCh <- name
+
+	// Читаем до закрытия, чтобы обрабатывались control frames.
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *stubBinance) waitConns(t *testing.T) {
+	t.Helper()
+	seen := map[string]bool{}
+	timeout := time.After(5 * time.Second)
+	for len(seen) < 2 {
+		select {
+		case name := <-s.connsCh:
+			seen[name] = true
+		case <-timeout:
+			t.Fatalf("ws connections: %v", seen)
+		}
+	}
+}
+
+func (s *stubBinance) push(name string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.t.Fatalf("marshal ws message: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn := s.conns[name]
+	if conn == nil {
+		s.t.Fatalf("no %s ws connection", name)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		s.t.Fatalf("write %s ws: %v", name, err)
+	}
+}
+
+func (s *stubBinance) pushTicker(price float64) {
+	s.mu.Lock()
+	s.price = price
+	s.mu.Unlock()
+	s.push("public", map[string]any{
+		"stream": strings.ToLower(stubSymbol) + "@miniTicker",
+		"data": map[string]any{
+			"e": "24hrMiniTicker",
+			"E": time.Now().UnixMilli(),
+			"s": stubSymbol,
+			"c": formatFloat(price),
+			"o": "90",
+			"h": "110",
+			"l": "80",
+			"v": "1234.5",
+			"q": "123450",
+		},
+	})
+}
+
+func (s *stubBinance) pushBookTicker(bid, ask float64) {
+	s.push("public", map[string]any{
+		"stream": strings.ToLower(stubSymbol) + "@bookTicker",
+		"data": map[string]any{
+			"u": time.Now().UnixNano(),
+			"s": stubSymbol,
+			"b": formatFloat(bid),
+			"B": "31.21",
+			"a": formatFloat(ask),
+			"A": "40.66",
+		},
+	})
+}
+
+func (s *stubBinance) placeOrder(w http.ResponseWriter, linkID, side, orderType string, price, qty float64) {
+	s.mu.Lock()
+	for _, order := range s.orders {
+		if order.LinkID == linkID && isOpenStatus(order.Status) {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, -2010, "Duplicate order sent.")
+			return
+		}
+	}
+	s.nextID++
+	order := &stubOrder{
+		ID:     s.nextID,
+		LinkID: linkID,
+		Side:   side,
+		Type:   orderType,
+		Price:  price,
+		Qty:    qty,
+		Status: "NEW",
+		Time:   time.Now().UnixMilli(),
+	}
+	s.orders[order.ID] = order
+	marketPrice := s.price
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]int64{"orderId": order.ID})
+	s.pushReport(order, "NEW", nil)
+	if orderType == "MARKET" {
+		s.execute(order.ID, marketPrice, qty)
+	}
+}
+
+func (s *stubBinance) cancelOrder(w http.ResponseWriter, orderID int64) {
+	s.mu.Lock()
+	order, ok := s.orders[orderID]
+	if !ok || !isOpenStatus(order.Status) {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, -2011, "Unknown order sent.")
+		return
+	}
+	order.Status = "CANCELED"
+	snapshot := *order
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"orderId": orderID, "status": "CANCELED"})
+	s.pushReport(&snapshot, "CANCELED", nil)
+}
+
+// fill исполняет qty лимитного ордера с link id по его цене.
+func (s *stubBinance) fill(linkID string, qty float64) {
+	s.mu.Lock()
+	var orderID int64
+	var price float64
+	for _, order := range s.orders {
+		if order.LinkID == linkID && isOpenStatus(order.Status) {
+			orderID, price = order.ID, order.Price
+		}
+	}
+	s.mu.Unlock()
+	if orderID == 0 {
+		s.t.Fatalf("no open order %s", linkID)
+	}
+	s.execute(orderID, price, qty)
+}
+
+func (s *stubBinance) execute(orderID int64, price, qty float64) {
+	s.mu.Lock()
+	order := s.orders[orderID]
+	order.Executed += qty
+	order.Status = "PARTIALLY_FILLED"
+	if order.Executed >= order.Qty-1e-12 {
+		order.Status = "FILLED"
+	}
+	// Комиссия списывается в получаемой монете.
+	trade := stubTrade{
+		ID:       int64(len(s.trades) + 1),
+		OrderID:  orderID,
+		Price:    price,
+		Qty:      qty,
+		Fee:      qty * stubFeeRate,
+		FeeAsset: "BTC",
+		IsBuyer:  order.Side == "BUY",
+		Time:     time.Now().UnixMilli(),
+	}
+	if !trade.IsBuyer || s.quoteFees {
+		trade.Fee, trade.FeeAsset = price*qty*stubFeeRate, "USDT"
+	}
+	s.trades = append(s.trades, trade)
+	snapshot := *order
+	s.mu.Unlock()
+
+	s.pushReport(&snapshot, "TRADE", &trade)
+}
+
+func (s *stubBinance) pushReport(order *stubOrder, execType string, trade *stubTrade) {
+	report := map[string]any{
+		"e": "executionReport",
+		"E": time.Now().UnixMilli(),
+		"s": stubSymbol,
+		"c": order.LinkID,
+		"C": "",
+		"S": order.Side,
+		"o": order.Type,
+		"q": formatFloat(order.Qty),
+		"p": formatFloat(order.Price),
+		"x": execType,
+		"X": order.Status,
+		"r": "NONE",
+		"i": order.ID,
+		"l": "0",
+		"z": formatFloat(order.Executed),
+		"L": "0",
+		"T": time.Now().UnixMilli(),
+		"t": -1,
+		"n": "0",
+		"N": nil,
+		// Ключи, отличающиеся от разбираемых только регистром.
+		"O": order.Time,
+		"Q": "0",
+		"P": "0",
+		"I": 8641984,
+		"Z": formatFloat(order.Executed * order.Price),
+		"Y": "0",
+		"F": "0",
+		"f": "GTC",
+		"g": -1,
+		"m": false,
+		"M": false,
+		"w": order.Status == "NEW",
+		"W": order.Time,
+		"V": "EXPIRE_MAKER",
+	}
+	if trade != nil {
+		report["l"] = formatFloat(trade.Qty)
+		report["L"] = formatFloat(trade.Price)
+		report["T"] = trade.Time
+		report["t"] = trade.ID
+		report["n"] = formatFloat(trade.Fee)
+		report["N"] = trade.FeeAsset
+	}
+	if execType == "CANCELED" {
+		// Для отмены binance передаёт исходный clientOrderId в C.
+		report["c"], report["C"] = "web_cancel", order.LinkID
+	}
+	s.push("user", report)
+}
+
+func (s *stubBinance) findOrder(orderID int64, linkID string) (map[string]any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, order := range s.orders {
+		if (orderID != 0 && order.ID == orderID) || (linkID != "" && order.LinkID == linkID) {
+			return orderJSON(order), true
+		}
+	}
+	return nil, false
+}
+
+func (s *stubBinance) orderInfos(openOnly bool) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := []map[string]any{}
+	for id := int64(1001); id <= s.nextID; id++ {
+		if order := s.orders[id]; order != nil && (!openOnly || isOpenStatus(order.Status)) {
+			items = append(items, orderJSON(order))
+		}
+	}
+	return items
+}
+
+func (s *stubBinance) tradeInfos(orderID int64) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := []map[string]any{}
+	for _, trade := range s.trades {
+		if orderID != 0 && trade.OrderID != orderID {
+			continue
+		}
+		items = append(items, map[string]any{
+			"id":              trade.ID,
+			"orderId":         trade.OrderID,
+			"symbol":          stubSymbol,
+			"price":           formatFloat(trade.Price),
+			"qty":             formatFloat(trade.Qty),
+			"time":            trade.Time,
+			"isBuyer":         trade.IsBuyer,
+			"commission":      formatFloat(trade.Fee),
+			"commissionAsset": trade.FeeAsset,
+		})
+	}
+	return items
+}
+
+// account считает балансы по сделкам: USDT с запасом, BTC - позиция за вычетом комиссий.
+func (s *stubBinance) account() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	base, quote := 0.0, 1e6
+	for _, trade := range s.trades {
+		if trade.IsBuyer {
+			base += trade.Qty
+			quote -= trade.Qty * trade.Price
+		} else {
+			base -= trade.Qty
+			quote += trade.Qty * trade.Price
+		}
+		if trade.FeeAsset == "BTC" {
+			base -= trade.Fee
+		} else {
+			quote -= trade.Fee
+		}
+	}
+	return map[string]any{"balances": []map[string]string{
+		{"asset": "BTC", "free": formatFloat(base), "locked": "0"},
+		{"asset": "USDT", "free": formatFloat(quote), "locked": "0"},
+	}}
+}
+
+func orderJSON(order *stubOrder) map[string]any {
+	return map[string]any{
+		"orderId":       order.ID,
+		"clientOrderId": order.LinkID,
+		"symbol":        stubSymbol,
+		"side":          order.Side,
+		"type":          order.Type,
+		"price":         formatFloat(order.Price),
+		"origQty":       formatFloat(order.Qty),
+		"executedQty":   formatFloat(order.Executed),
+		"status":        order.Status,
+		"time":          order.Time,
+		"updateTime":    order.Time,
+	}
+}
+
+func isOpenStatus(status string) bool {
+	return status == "NEW" || status == "PARTIALLY_FILLED"
+}
+
+func validSignature(rawQuery string) bool {
+	payload, signature, ok := strings.Cut(rawQuery, "&signature=")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(stubSecret))
+	mac.Write([]byte(payload))
+	return hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(signature))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": code, "msg": msg})
+}
+
+func parseFloat(value string) float64 {
+	parsed, _ := strconv.ParseFloat(value, 64)
+	return parsed
+}
+
+func parseInt(value string) int64 {
+	parsed, _ := strconv.ParseInt(value, 10, 64)
+	return parsed
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func newStubClient(t *testing.T) (*stubBinance, *Client, <-chan exchange.Event) {
+	t.Helper()
+	stub := newStubBinance(t)
+	log := logger.New(logger.Config{Level: "error"})
+	client := New(stub.server.URL, stub.wsURL(), stub.wsURL(), "", stubAPIKey, stubSecret, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.Subscribe(ctx, stubSymbol)
+	if err != nil {
+		cancel()
+		t.Fatalf("Subscribe: %v", err)
+	}
+	t.Cleanup(func() {
+		client.wsPublic.Close()
+		client.wsPrivate.Close()
+		cancel()
+	})
+	stub.waitConns(t)
+	return stub, client, events
+}
+
+func waitEvent(t *testing.T, events <-chan exchange.Event, desc string, match func(exchange.Event) bool) exchange.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s", desc)
+		}
+	}
+}
+
+func waitFill(t *testing.T, events <-chan exchange.Event, linkID string) models.Fill {
+	t.Helper()
+	event := waitEvent(t, events, "fill "+linkID, func(event exchange.Event) bool {
+		return event.Type == exchange.EventTypeFill && event.Fill.LinkID == linkID
+	})
+	return *event.Fill
+}
+
+func waitOrder(t *testing.T, events <-chan exchange.Event, linkID string, status models.OrderStatus) models.Order {
+	t.Helper()
+	event := waitEvent(t, events, fmt.Sprintf("order %s %s", linkID, status), func(event exchange.Event) bool {
+		return event.Type == exchange.EventTypeOrder && event.Order.LinkID == linkID && event.Order.Status == status
+	})
+	return *event.Order
+}
+
+func assertClose(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestStubEntryMarketOrder(t *testing.T) {
+	stub, client, events := newStubClient(t)
+	ctx := context.Background()
+
+	rules, err := client.GetInstrumentRules(ctx, stubSymbol)
+	if err != nil {
+		t.Fatalf("GetInstrumentRules: %v", err)
+	}
+	if rules.BaseCoin != "BTC" || rules.QuoteCoin != "USDT" || rules.TickSize != 0.01 || rules.LotSize != 0.00001 || rules.MinNotional != 5 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	stub.pushTicker(100.5)
+	ticker := waitEvent(t, events, "ticker", func(event exchange.Event) bool {
+		return event.Type == exchange.EventTypeTicker
+	})
+	assertClose(t, "ticker price", ticker.Ticker.LastPrice, 100.5)
+
+	stub.pushBookTicker(100.4, 100.6)
+	book := waitEvent(t, events, "book ticker", func(event exchange.Event) bool {
+		return event.Type == exchange.EventTypeTicker
+	})
+	assertClose(t, "book ticker price", book.Ticker.LastPrice, 100.5)
+
+	placed, err := client.PlaceOrder(ctx, models.Order{
+		Symbol:     stubSymbol,
+		Side:       models.OrderSideBuy,
+		Type:       models.OrderTypeMarket,
+		Qty:        0.1,
+		LinkID:     "deal1-entry",
+		MarketUnit: "baseCoin",
+		QtyStep:    rules.LotSize,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder entry: %v", err)
+	}
+	if placed.ID == "" {
+		t.Fatal("entry order id is empty")
+	}
+
+	fill := waitFill(t, events, "deal1-entry")
+	if fill.OrderID != placed.ID || fill.Side != models.OrderSideBuy || fill.ExecID == "" {
+		t.Fatalf("unexpected entry fill: %+v", fill)
+	}
+	assertClose(t, "entry price", fill.Price, 100.5)
+	assertClose(t, "entry qty", fill.Qty, 0.1)
+	assertClose(t, "entry fee", fill.Fee, 0.1*stubFeeRate)
+	if fill.FeeCoin != "BTC" {
+		t.Fatalf("entry fee coin = %q, want BTC", fill.FeeCoin)
+	}
+	waitOrder(t, events, "deal1-entry", models.OrderStatusFilled)
+
+	// Восстановление входа по REST: то же исполнение с link id.
+	fills, err := client.GetFills(ctx, exchange.FillQuery{Symbol: stubSymbol, LinkID: "deal1-entry"})
+	if err != nil {
+		t.Fatalf("GetFills entry: %v", err)
+	}
+	if len(fills) != 1 || fills[0].LinkID != "deal1-entry" || fills[0].ExecID != fill.ExecID {
+		t.Fatalf("unexpected entry fills: %+v", fills)
+	}
+	assertClose(t, "rest entry fee", fills[0].Fee, fill.Fee)
+
+	// Поиск ещё не выставленного ордера даёт пустой результат без ошибки.
+	missing, err := client.GetFills(ctx, exchange.FillQuery{Symbol: stubSymbol, LinkID: "deal2-entry"})
+	if err != nil || len(missing) != 0 {
+		t.Fatalf("GetFills missing = %v, %v; want empty", missing, err)
+	}
+	history, err := client.GetOrderHistory(ctx, exchange.OrderQuery{Symbol: stubSymbol, LinkID: "deal2-entry"})
+	if err != nil || len(history) != 0 {
+		t.Fatalf("GetOrderHistory missing = %v, %v; want empty", history, err)
+	}
+}
+
+func TestStubSafetyOrderFill(t *testing.T) {
+	stub, client, events := newStubClient(t)
+	ctx := context.Background()
+
+	so := models.Order{
+		Symbol:      stubSymbol,
+		Side:        models.OrderSideBuy,
+		Type:        models.OrderTypeLimit,
+		Kind:        models.OrderKindSafety,
+		Price:       95.123,
+		Qty:         0.2,
+		LinkID:      "deal1-so-1",
+		TimeInForce: "GTC",
+		PriceStep:   0.01,
+		QtyStep:     0.00001,
+	}
+	placed, err := client.PlaceOrder(ctx, so)
+	if err != nil {
+		t.Fatalf("PlaceOrder safety: %v", err)
+	}
+	created := waitOrder(t, events, "deal1-so-1", models.OrderStatusNew)
+	assertClose(t, "safety price", created.Price, 95.12)
+
+	if _, err := client.PlaceOrder(ctx, so); err == nil || !strings.Contains(err.Error(), "Duplicate order sent") {
+		t.Fatalf("duplicate safety order error = %v", err)
+	}
+
+	open, err := client.GetOpenOrders(ctx, stubSymbol)
+	if err != nil {
+		t.Fatalf("GetOpenOrders: %v", err)
+	}
+	if len(open) != 1 || open[0].ID != placed.ID || open[0].LinkID != "deal1-so-1" || open[0].Status != models.OrderStatusNew {
+		t.Fatalf("unexpected open orders: %+v", open)
+	}
+
+	stub.fill("deal1-so-1", 0.05)
+	partial := waitFill(t, events, "deal1-so-1")
+	assertClose(t, "partial qty", partial.Qty, 0.05)
+	assertClose(t, "partial price", partial.Price, 95.12)
+	order := waitOrder(t, events, "deal1-so-1", models.OrderStatusPartiallyFilled)
+	assertClose(t, "partial filled qty", order.FilledQty, 0.05)
+
+	stub.fill("deal1-so-1", 0.15)
+	rest := waitFill(t, events, "deal1-so-1")
+	assertClose(t, "rest qty", rest.Qty, 0.15)
+	order = waitOrder(t, events, "deal1-so-1", models.OrderStatusFilled)
+	assertClose(t, "filled qty", order.FilledQty, 0.2)
+
+	fills, err := client.GetFills(ctx, exchange.FillQuery{Symbol: stubSymbol, LinkID: "deal1-so-1"})
+	if err != nil {
+		t.Fatalf("GetFills safety: %v", err)
+	}
+	total := 0.0
+	for _, fill := range fills {
+		if fill.LinkID != "deal1-so-1" {
+			t.Fatalf("fill with foreign link id: %+v", fill)
+		}
+		total += fill.Qty
+	}
+	assertClose(t, "rest safety qty", total, 0.2)
+
+	open, err = client.GetOpenOrders(ctx, stubSymbol)
+	if err != nil || len(open) != 0 {
+		t.Fatalf("open orders after fill = %v, %v; want none", open, err)
+	}
+}
+
+func TestStubTakeProfitPartialFillAndCancel(t *testing.T) {
+	stub, client, events := newStubClient(t)
+	ctx := context.Background()
+
+	if _, err := client.AmendOrder(ctx, models.Order{}); err != exchange.ErrNotSupported {
+		t.Fatalf("AmendOrder error = %v, want ErrNotSupported", err)
+	}
+
+	placed, err := client.PlaceOrder(ctx, models.Order{
+		Symbol:      stubSymbol,
+		Side:        models.OrderSideSell,
+		Type:        models.OrderTypeLimit,
+		Kind:        models.OrderKindTP,
+		Price:       102,
+		Qty:         0.3,
+		LinkID:      "deal1-tp-1",
+		TimeInForce: "GTC",
+		PriceStep:   0.01,
+		QtyStep:     0.00001,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder tp: %v", err)
+	}
+	waitOrder(t, events, "deal1-tp-1", models.OrderStatusNew)
+
+	stub.fill("deal1-tp-1", 0.1)
+	fill := waitFill(t, events, "deal1-tp-1")
+	if fill.Side != models.OrderSideSell {
+		t.Fatalf("tp fill side = %s", fill.Side)
+	}
+	assertClose(t, "tp fee", fill.Fee, 102*0.1*stubFeeRate)
+	if fill.FeeCoin != "USDT" {
+		t.Fatalf("tp fee coin = %q, want USDT", fill.FeeCoin)
+	}
+	waitOrder(t, events, "deal1-tp-1", models.OrderStatusPartiallyFilled)
+
+	// Перестановка TP на binance - отмена и новый ордер.
+	if err := client.CancelOrder(ctx, stubSymbol, placed.ID); err != nil {
+		t.Fatalf("CancelOrder tp: %v", err)
+	}
+	canceled := waitOrder(t, events, "deal1-tp-1", models.OrderStatusCanceled)
+	assertClose(t, "canceled filled qty", canceled.FilledQty, 0.1)
+
+	err = client.CancelOrder(ctx, stubSymbol, placed.ID)
+	if err == nil || !strings.Contains(err.Error(), "code=-2011") {
+		t.Fatalf("repeated cancel error = %v, want code=-2011", err)
+	}
+
+	history, err := client.GetOrderHistory(ctx, exchange.OrderQuery{Symbol: stubSymbol, LinkID: "deal1-tp-1"})
+	if err != nil {
+		t.Fatalf("GetOrderHistory tp: %v", err)
+	}
+	if len(history) != 1 || history[0].Status != models.OrderStatusCanceled {
+		t.Fatalf("unexpected tp history: %+v", history)
+	}
+	assertClose(t, "history filled qty", history[0].FilledQty, 0.1)
+
+	if _, err := client.PlaceOrder(ctx, models.Order{
+		Symbol:    stubSymbol,
+		Side:      models.OrderSideSell,
+		Type:      models.OrderTypeLimit,
+		Kind:      models.OrderKindTP,
+		Price:     101.5,
+		Qty:       0.2,
+		LinkID:    "deal1-tp-2",
+		PriceStep: 0.01,
+		QtyStep:   0.00001,
+	}); err != nil {
+		t.Fatalf("PlaceOrder replacement tp: %v", err)
+	}
+	replaced := waitOrder(t, events, "deal1-tp-2", models.OrderStatusNew)
+	assertClose(t, "replacement qty", replaced.Qty, 0.2)
+
+	stub.fill("deal1-tp-2", 0.2)
+	waitFill(t, events, "deal1-tp-2")
+	waitOrder(t, events, "deal1-tp-2", models.OrderStatusFilled)
+}
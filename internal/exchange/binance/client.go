@@ -0,0 +1,178 @@
+package binance
+
+import (
+	"context"
+	"dcabot/internal/config"
+	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/binance/rest"
+	"dcabot/internal/exchange/binance/ws"
+	"dcabot/internal/logger"
+	"dcabot/internal/models"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const listenKeyKeepAlive = 30 * time.Minute
+
+type Client struct {
+	rest         *rest.Client
+	wsPublicURL  string
+	wsPrivateURL string
+	tickerStream string
+	wsPublic     *ws.Client
+	wsPrivate    *ws.Client
+	wsConnected  bool
+	mu           sync.Mutex
+	listenKey    string
+	log          *logger.Logger
+}
+
+func New(baseURL, wsPublicURL, wsPrivateURL, tickerStream, apiKey, secret string, log *logger.Logger) *Client {
+	return &Client{
+		rest:         rest.New(baseURL, apiKey, secret, log),
+		wsPublicURL:  strings.TrimRight(wsPublicURL, "/"),
+		wsPrivateURL: strings.TrimRight(wsPrivateURL, "/"),
+		tickerStream: tickerStream,
+		log:          log,
+	}
+}
+
+func init() {
+	exchange.Register("binance", func(cfg config.ExchangeConfig, log *logger.Logger) (exchange.Client, error) {
+		return New(cfg.BaseUrl, cfg.WSPublicURL, cfg.WSPrivateURL, cfg.TickerStream, cfg.ApiKey, cfg.Secret, log), nil
+	})
+}
+
+func (c *Client) Capabilities() exchange.Capabilities {
+	return exchange.Capabilities{
+		PostOnly:    true,
+		FeeCurrency: exchange.FeeCurrencyReceived,
+	}
+}
+
+func (c *Client) GetInstrumentRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
+	return c.rest.GetInstrumentRules(ctx, symbol)
+}
+
+func (c *Client) Subscribe(ctx context.Context, symbol string) (<-chan exchange.Event, error) {
+	c.log.WithSymbol(symbol).WithField("component", "binance").Info("Подписываемся на торговую пару.")
+
+	if c.wsConnected {
+		return nil, errors.New("Подписка уже создана.")
+	}
+
+	stream := strings.ToLower(symbol) + "@" + c.tickerStreamName()
+	publicURL := c.wsPublicURL + "/stream?streams=" + stream
+	c.wsPublic = ws.New("public", func(context.Context) (string, error) {
+		return publicURL, nil
+	}, c.log)
+	c.wsPrivate = ws.New("user_data", c.userDataURL, c.log)
+
+	if err := c.wsPublic.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := c.wsPrivate.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	merged := make(chan exchange.Event, 200)
+
+	c.log.WithSymbol(symbol).WithField("component", "binance").Debug("Запуск forwardEvents.")
+	go forwardEvents(c.wsPublic.Events(), merged)
+	go forwardEvents(c.wsPrivate.Events(), merged)
+	go c.keepAliveListenKey(ctx)
+
+	c.wsConnected = true
+
+	c.log.WithSymbol(symbol).WithField("component", "binance").Info("Подписки активированы.")
+
+	return merged, nil
+}
+
+func (c *Client) tickerStreamName() string {
+	if strings.EqualFold(c.tickerStream, "bookTicker") {
+		return "bookTicker"
+	}
+	return "miniTicker"
+}
+
+func (c *Client) userDataURL(ctx context.Context) (string, error) {
+	listenKey, err := c.rest.CreateListenKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.listenKey = listenKey
+	c.mu.Unlock()
+	return c.wsPrivateURL + "/ws/" + listenKey, nil
+}
+
+func (c *Client) keepAliveListenKey(ctx context.Context) {
+	ticker := time.NewTicker(listenKeyKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.wsPublic.Close()
+			c.wsPrivate.Close()
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			listenKey := c.listenKey
+			c.mu.Unlock()
+
+			if err := c.rest.KeepAliveListenKey(ctx, listenKey); err != nil {
+				c.log.WithComponent("binance").WithError(err).Warn("Не удалось продлить listenKey, переподключение user data stream.")
+				c.wsPrivate.Reconnect()
+				continue
+			}
+			c.log.WithComponent("binance").Debug("listenKey продлён.")
+		}
+	}
+}
+
+func (c *Client) CancelOrder(ctx context.Context, symbol, orderID string) error {
+	return c.rest.CancelOrder(ctx, symbol, orderID)
+}
+
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]models.Order, error) {
+	return c.rest.GetOpenOrders(ctx, symbol)
+}
+
+func (c *Client) PlaceOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	return c.rest.PlaceOrder(ctx, order)
+}
+
+func (c *Client) AmendOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	return models.Order{}, exchange.ErrNotSupported
+}
+
+func (c *Client) GetFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
+	return c.rest.GetFills(ctx, query)
+}
+
+func (c *Client) GetOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	return c.rest.GetOrderHistory(ctx, query)
+}
+
+func (c *Client) GetBalances(ctx context.Context, coins []string) (map[string]exchange.Balance, error) {
+	return c.rest.GetBalances(ctx, coins)
+}
+
+func (c *Client) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	return c.rest.GetKlines(ctx, symbol, interval, limit)
+}
+
+func (c *Client) Convert(ctx context.Context, fromCoin, toCoin string, amount float64) (exchange.ConvertResult, error) {
+	return exchange.ConvertResult{}, exchange.ErrNotSupported
+}
+
+func forwardEvents(src <-chan exchange.Event, dst chan<- exchange.Event) {
+	for event := range src {
+		dst <- event
+	}
+}
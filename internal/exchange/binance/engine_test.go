@@ -0,0 +1,154 @@
+package binance
+
+import (
+	"context"
+	"dcabot/internal/config"
+	"dcabot/internal/engine"
+	"dcabot/internal/logger"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStubEngine(t *testing.T, stub *stubBinance) (*engine.Engine, *Client) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Exchange.Name = "binance"
+	cfg.Bot = config.BotConfig{
+		Strategy:         "dca",
+		Symbol:           stubSymbol,
+		Side:             "Buy",
+		BaseOrderQty:     0.1,
+		QtyUnit:          "baseCoin",
+		TPPercent:        1,
+		SOCount:          2,
+		SOStepPercent:    1,
+		SOStepMultiplier: 1,
+		SOBaseQty:        0.1,
+		SOQtyMultiplier:  1,
+		SOAnchor:         "entry",
+		SOStepMode:       "fixed",
+		OrderPolicy:      config.OrderPolicyCfg{OnCancel: "retry", OnReject: "alert", MaxRetries: 3},
+		Reconcile:        config.ReconcileCfg{Mode: "halt", Interval: time.Minute},
+		Dust:             config.DustCfg{Mode: "carry", ConvertInterval: time.Hour},
+	}
+	cfg.Runtime.StateFile = filepath.Join(t.TempDir(), "state.json")
+	cfg.Runtime.TickerStaleAfter = 30 * time.Second
+
+	log := logger.New(logger.Config{Level: "panic"})
+	client := New(stub.server.URL, stub.wsURL(), stub.wsURL(), "", stubAPIKey, stubSecret, log)
+	return engine.New(cfg, client, log), client
+}
+
+// waitCond опрашивает cond до истечения 10 секунд.
+func waitCond(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", desc)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// openByLink возвращает открытые ордера стаба с заданной частью link id.
+func (s *stubBinance) openByLink(part string) []*stubOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var orders []*stubOrder
+	for _, order := range s.orders {
+		if isOpenStatus(order.Status) && strings.Contains(order.LinkID, part) {
+			snapshot := *order
+			orders = append(orders, &snapshot)
+		}
+	}
+	return orders
+}
+
+func TestStubEngineDealCycle(t *testing.T) {
+	stub := newStubBinance(t)
+	stub.quoteFees = true
+	eng, client := newStubEngine(t, stub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer eng.Wait()
+	defer cancel()
+	defer func() {
+		client.wsPublic.Close()
+		client.wsPrivate.Close()
+	}()
+
+	// Вход ждёт цену из тикера: шлём его, пока Start не вернётся.
+	started := make(chan error, 1)
+	go func() { started <- eng.Start(ctx) }()
+	stub.waitConns(t)
+	tick := time.NewTicker(50 * time.Millisecond)
+	defer tick.Stop()
+	for waiting := true; waiting; {
+		select {
+		case err := <-started:
+			if err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			waiting = false
+		case <-tick.C:
+			stub.pushTicker(100)
+		case <-time.After(20 * time.Second):
+			t.Fatal("Start не завершился")
+		}
+	}
+
+	deal := eng.Status().Deal
+	if !deal.Active || deal.DealID == "" {
+		t.Fatalf("сделка не открыта: %+v", deal)
+	}
+	assertClose(t, "entry qty", deal.TotalQty, 0.1)
+	assertClose(t, "entry price", deal.EntryPrice, 100)
+	dealID := deal.DealID
+
+	waitCond(t, "TP and safety orders", func() bool {
+		return len(stub.openByLink(dealID+"-tp-")) == 1 && len(stub.openByLink(dealID+"-so-")) == 2
+	})
+	firstTP := stub.openByLink(dealID + "-tp-")[0]
+	assertClose(t, "first tp qty", firstTP.Qty, 0.1)
+	assertClose(t, "first tp price", firstTP.Price, 101)
+
+	so := stub.openByLink(dealID + "-so-1")
+	if len(so) != 1 {
+		t.Fatalf("safety order 1 not open: %+v", so)
+	}
+	assertClose(t, "so-1 price", so[0].Price, 99)
+	stub.fill(so[0].LinkID, so[0].Qty)
+
+	// binance не умеет amend: TP переставляется отменой и новым ордером на всю позицию.
+	waitCond(t, "TP rebuilt after safety fill", func() bool {
+		tps := stub.openByLink(dealID + "-tp-")
+		return len(tps) == 1 && tps[0].LinkID != firstTP.LinkID && math.Abs(tps[0].Qty-0.2) < 1e-9
+	})
+	deal = eng.Status().Deal
+	assertClose(t, "total qty after so", deal.TotalQty, 0.2)
+	assertClose(t, "avg price after so", deal.AvgPrice, 99.5)
+	tp := stub.openByLink(dealID + "-tp-")[0]
+	assertClose(t, "rebuilt tp price", tp.Price, 100.49)
+
+	stub.fill(tp.LinkID, tp.Qty)
+
+	// Закрытие цикла: ордера старой сделки сняты, открыт следующий цикл.
+	waitCond(t, "next deal", func() bool {
+		next := eng.Status().Deal
+		return next.Active && next.DealID != "" && next.DealID != dealID
+	})
+	waitCond(t, "old deal orders cancelled", func() bool {
+		return len(stub.openByLink(dealID+"-")) == 0
+	})
+	next := eng.Status()
+	if next.Halted != "" {
+		t.Fatalf("торговля остановлена: %s", next.Halted)
+	}
+	waitCond(t, "TP of the next deal", func() bool {
+		return len(stub.openByLink(next.Deal.DealID+"-tp-")) == 1
+	})
+}
@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"net/http"
+)
+
+func (c *Client) GetBalances(ctx context.Context, coins []string) (map[string]exchange.Balance, error) {
+	var resp struct {
+		Balances []struct {
+			Asset  string `json:"asset"`
+			Free   string `json:"free"`
+			Locked string `json:"locked"`
+		} `json:"balances"`
+	}
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v3/account", nil, securitySigned, &resp); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(coins))
+	for _, coin := range coins {
+		wanted[coin] = true
+	}
+
+	balances := map[string]exchange.Balance{}
+	for _, item := range resp.Balances {
+		if len(wanted) > 0 && !wanted[item.Asset] {
+			continue
+		}
+		free, _ := parseFloatOrZero(item.Free)
+		locked, _ := parseFloatOrZero(item.Locked)
+
+		balances[item.Asset] = exchange.Balance{
+			Coin:      item.Asset,
+			Wallet:    free + locked,
+			Available: free,
+		}
+	}
+	return balances, nil
+}
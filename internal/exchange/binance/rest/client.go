@@ -0,0 +1,19 @@
+package rest
+
+import (
+	"dcabot/internal/logger"
+	"net/http"
+	"time"
+)
+
+func New(baseURL, apiKey, secret string, log *logger.Logger) *Client {
+	return &Client{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		secret:  secret,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		log: log,
+	}
+}
@@ -0,0 +1,285 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// myTrades и allOrders принимают startTime/endTime не шире 24 часов.
+	historyWindow = 24 * time.Hour
+	historyLimit  = 1000
+)
+
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+func historyWindows(since, until time.Time) []timeWindow {
+	if since.IsZero() {
+		return []timeWindow{{}}
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	var windows []timeWindow
+	for end := until; end.After(since); end = end.Add(-historyWindow) {
+		start := end.Add(-historyWindow)
+		if start.Before(since) {
+			start = since
+		}
+		windows = append(windows, timeWindow{start: start, end: end})
+	}
+	return windows
+}
+
+func (c *Client) GetFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
+	var trades []tradeInfo
+	if query.LinkID != "" {
+		order, err := c.orderByLinkID(ctx, query.Symbol, query.LinkID)
+		if isOrderNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		params := url.Values{}
+		params.Set("symbol", query.Symbol)
+		params.Set("orderId", strconv.FormatInt(order.OrderID, 10))
+		if err := c.doRequest(ctx, http.MethodGet, "/api/v3/myTrades", params, securitySigned, &trades); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		trades, err = c.listTrades(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fills, err := c.toFills(ctx, query.Symbol, trades)
+	if err != nil {
+		return nil, err
+	}
+	if query.Limit > 0 && len(fills) > query.Limit {
+		fills = fills[:query.Limit]
+	}
+	return fills, nil
+}
+
+func (c *Client) listTrades(ctx context.Context, query exchange.FillQuery) ([]tradeInfo, error) {
+	seen := map[int64]bool{}
+	var trades []tradeInfo
+	for _, window := range historyWindows(query.Since, query.Until) {
+		start := window.start
+		for {
+			params := url.Values{}
+			params.Set("symbol", query.Symbol)
+			params.Set("limit", strconv.Itoa(historyLimit))
+			if !start.IsZero() {
+				params.Set("startTime", strconv.FormatInt(start.UnixMilli(), 10))
+				params.Set("endTime", strconv.FormatInt(window.end.UnixMilli(), 10))
+			}
+
+			var page []tradeInfo
+			if err := c.doRequest(ctx, http.MethodGet, "/api/v3/myTrades", params, securitySigned, &page); err != nil {
+				return nil, err
+			}
+			for _, trade := range page {
+				if !seen[trade.ID] {
+					seen[trade.ID] = true
+					trades = append(trades, trade)
+				}
+			}
+
+			if start.IsZero() || len(page) < historyLimit {
+				break
+			}
+			next := time.UnixMilli(page[len(page)-1].Time)
+			if !next.After(start) {
+				break
+			}
+			start = next
+		}
+		if query.Limit > 0 && len(trades) >= query.Limit {
+			break
+		}
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time > trades[j].Time })
+	return trades, nil
+}
+
+func (c *Client) toFills(ctx context.Context, symbol string, trades []tradeInfo) ([]models.Fill, error) {
+	if len(trades) == 0 {
+		return nil, nil
+	}
+
+	var from time.Time
+	for _, trade := range trades {
+		at := time.UnixMilli(trade.Time)
+		if from.IsZero() || at.Before(from) {
+			from = at
+		}
+	}
+
+	linkIDs, err := c.orderLinkIDs(ctx, symbol, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var fills []models.Fill
+	for _, item := range trades {
+		price, _ := strconv.ParseFloat(item.Price, 64)
+		qty, _ := strconv.ParseFloat(item.Qty, 64)
+		fee, _ := strconv.ParseFloat(item.Commission, 64)
+		orderID := strconv.FormatInt(item.OrderID, 10)
+
+		linkID, ok := linkIDs[orderID]
+		if !ok {
+			order, err := c.orderByID(ctx, symbol, item.OrderID)
+			if err != nil {
+				return nil, err
+			}
+			linkID = order.ClientOrderID
+			linkIDs[orderID] = linkID
+		}
+
+		side := models.OrderSideSell
+		if item.IsBuyer {
+			side = models.OrderSideBuy
+		}
+
+		fills = append(fills, models.Fill{
+			OrderID:   orderID,
+			LinkID:    linkID,
+			ExecID:    strconv.FormatInt(item.ID, 10),
+			Symbol:    symbol,
+			Side:      side,
+			Price:     price,
+			Qty:       qty,
+			Timestamp: time.UnixMilli(item.Time),
+			Fee:       fee,
+			FeeCoin:   item.CommissionAsset,
+		})
+	}
+	return fills, nil
+}
+
+// orderLinkIDs собирает clientOrderId ордеров начиная с from (с запасом в сутки на ордера,
+// выставленные раньше исполнения). Без from берутся последние 1000 ордеров.
+func (c *Client) orderLinkIDs(ctx context.Context, symbol string, from time.Time) (map[string]string, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("limit", strconv.Itoa(historyLimit))
+	if !from.IsZero() {
+		params.Set("startTime", strconv.FormatInt(from.Add(-historyWindow).UnixMilli(), 10))
+	}
+
+	var resp []orderInfo
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v3/allOrders", params, securitySigned, &resp); err != nil {
+		return nil, err
+	}
+
+	linkIDs := make(map[string]string, len(resp))
+	for _, item := range resp {
+		linkIDs[strconv.FormatInt(item.OrderID, 10)] = item.ClientOrderID
+	}
+	return linkIDs, nil
+}
+
+func (c *Client) orderByID(ctx context.Context, symbol string, orderID int64) (orderInfo, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("orderId", strconv.FormatInt(orderID, 10))
+
+	var resp orderInfo
+	err := c.doRequest(ctx, http.MethodGet, "/api/v3/order", params, securitySigned, &resp)
+	return resp, err
+}
+
+func (c *Client) orderByLinkID(ctx context.Context, symbol, linkID string) (orderInfo, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("origClientOrderId", linkID)
+
+	var resp orderInfo
+	err := c.doRequest(ctx, http.MethodGet, "/api/v3/order", params, securitySigned, &resp)
+	return resp, err
+}
+
+// isOrderNotFound - ордера с таким id нет: запрос по link id ещё не выставленного
+// ордера возвращает пустой результат, а не ошибку.
+func isOrderNotFound(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "code=-2013")
+}
+
+func (c *Client) GetOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	if query.LinkID != "" {
+		order, err := c.orderByLinkID(ctx, query.Symbol, query.LinkID)
+		if isOrderNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.Order{toOrder(order)}, nil
+	}
+
+	seen := map[int64]bool{}
+	var items []orderInfo
+	for _, window := range historyWindows(query.Since, query.Until) {
+		start := window.start
+		for {
+			params := url.Values{}
+			params.Set("symbol", query.Symbol)
+			params.Set("limit", strconv.Itoa(historyLimit))
+			if !start.IsZero() {
+				params.Set("startTime", strconv.FormatInt(start.UnixMilli(), 10))
+				params.Set("endTime", strconv.FormatInt(window.end.UnixMilli(), 10))
+			}
+
+			var page []orderInfo
+			if err := c.doRequest(ctx, http.MethodGet, "/api/v3/allOrders", params, securitySigned, &page); err != nil {
+				return nil, err
+			}
+			for _, item := range page {
+				if !seen[item.OrderID] {
+					seen[item.OrderID] = true
+					items = append(items, item)
+				}
+			}
+
+			if start.IsZero() || len(page) < historyLimit {
+				break
+			}
+			next := time.UnixMilli(page[len(page)-1].Time)
+			if !next.After(start) {
+				break
+			}
+			start = next
+		}
+		if query.Limit > 0 && len(items) >= query.Limit {
+			break
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time > items[j].Time })
+	orders := make([]models.Order, 0, len(items))
+	for _, item := range items {
+		orders = append(orders, toOrder(item))
+		if query.Limit > 0 && len(orders) >= query.Limit {
+			break
+		}
+	}
+	return orders, nil
+}
@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) GetInstrumentRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+
+	var resp exchangeInfo
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v3/exchangeInfo", params, securityNone, &resp); err != nil {
+		return exchange.InstrumentRules{}, err
+	}
+
+	if len(resp.Symbols) == 0 {
+		return exchange.InstrumentRules{}, fmt.Errorf("Торговая пара не найдена: %s", symbol)
+	}
+
+	info := resp.Symbols[0]
+	rules := exchange.InstrumentRules{
+		BaseCoin:  info.BaseAsset,
+		QuoteCoin: info.QuoteAsset,
+	}
+
+	for _, filter := range info.Filters {
+		switch filter.FilterType {
+		case "PRICE_FILTER":
+			tick, err := strconv.ParseFloat(filter.TickSize, 64)
+			if err != nil {
+				return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение tickSize=%q: %w", filter.TickSize, err)
+			}
+			rules.TickSize = tick
+		case "LOT_SIZE":
+			lot, err := strconv.ParseFloat(filter.StepSize, 64)
+			if err != nil {
+				return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение stepSize=%q: %w", filter.StepSize, err)
+			}
+			minQty, err := parseFloatOrZero(filter.MinQty)
+			if err != nil {
+				return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение minQty=%q: %w", filter.MinQty, err)
+			}
+			rules.LotSize = lot
+			rules.MinQty = minQty
+		case "NOTIONAL", "MIN_NOTIONAL":
+			minNotional, err := parseFloatOrZero(filter.MinNotional)
+			if err != nil {
+				return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение minNotional=%q: %w", filter.MinNotional, err)
+			}
+			if minNotional > rules.MinNotional {
+				rules.MinNotional = minNotional
+			}
+		}
+	}
+
+	if rules.TickSize == 0 {
+		return exchange.InstrumentRules{}, fmt.Errorf("Не удалось определить tick size для торговой пары: %s", symbol)
+	}
+
+	if rules.LotSize == 0 {
+		return exchange.InstrumentRules{}, fmt.Errorf("Не удалось определить lot size для торговой пары: %s", symbol)
+	}
+
+	return rules, nil
+}
+
+func (c *Client) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("interval", klineInterval(interval))
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp [][]json.RawMessage
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v3/klines", params, securityNone, &resp); err != nil {
+		return nil, err
+	}
+
+	klines := make([]models.Kline, 0, len(resp))
+	for _, item := range resp {
+		if len(item) < 6 {
+			continue
+		}
+		var startMs int64
+		_ = json.Unmarshal(item[0], &startMs)
+
+		klines = append(klines, models.Kline{
+			Start:  time.UnixMilli(startMs),
+			Open:   parseRawFloat(item[1]),
+			High:   parseRawFloat(item[2]),
+			Low:    parseRawFloat(item[3]),
+			Close:  parseRawFloat(item[4]),
+			Volume: parseRawFloat(item[5]),
+		})
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].Start.Before(klines[j].Start)
+	})
+	return klines, nil
+}
+
+func klineInterval(interval string) string {
+	switch strings.ToUpper(strings.TrimSpace(interval)) {
+	case "1", "3", "5", "15", "30":
+		return interval + "m"
+	case "60":
+		return "1h"
+	case "120":
+		return "2h"
+	case "240":
+		return "4h"
+	case "360":
+		return "6h"
+	case "720":
+		return "12h"
+	case "D":
+		return "1d"
+	case "W":
+		return "1w"
+	case "M":
+		return "1M"
+	default:
+		return interval
+	}
+}
+
+func parseRawFloat(raw json.RawMessage) float64 {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		value, _ := strconv.ParseFloat(text, 64)
+		return value
+	}
+	var value float64
+	_ = json.Unmarshal(raw, &value)
+	return value
+}
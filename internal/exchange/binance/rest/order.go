@@ -0,0 +1,123 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) PlaceOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	params := url.Values{}
+	params.Set("symbol", order.Symbol)
+	params.Set("side", strings.ToUpper(string(order.Side)))
+	params.Set("type", strings.ToUpper(string(order.Type)))
+	params.Set("newClientOrderId", order.LinkID)
+	params.Set("newOrderRespType", "ACK")
+
+	if order.Type == models.OrderTypeMarket {
+		if strings.EqualFold(order.MarketUnit, "quoteCoin") {
+			params.Set("quoteOrderQty", formatWithStep(order.Qty, 0))
+		} else {
+			params.Set("quantity", formatWithStep(order.Qty, order.QtyStep))
+		}
+	} else {
+		timeInForce := order.TimeInForce
+		if timeInForce == "" {
+			timeInForce = "GTC"
+		}
+		params.Set("timeInForce", timeInForce)
+		params.Set("quantity", formatWithStep(order.Qty, order.QtyStep))
+		params.Set("price", formatWithStep(order.Price, order.PriceStep))
+	}
+
+	var resp struct {
+		OrderID int64 `json:"orderId"`
+	}
+
+	if err := c.doRequest(ctx, http.MethodPost, "/api/v3/order", params, securitySigned, &resp); err != nil {
+		return models.Order{}, err
+	}
+
+	order.ID = strconv.FormatInt(resp.OrderID, 10)
+	return order, nil
+}
+
+func (c *Client) CancelOrder(ctx context.Context, symbol, orderID string) error {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("orderId", orderID)
+
+	return c.doRequest(ctx, http.MethodDelete, "/api/v3/order", params, securitySigned, nil)
+}
+
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]models.Order, error) {
+	params := url.Values{}
+	params.Set("symbol", symbol)
+
+	var resp []orderInfo
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v3/openOrders", params, securitySigned, &resp); err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, item := range resp {
+		orders = append(orders, toOrder(item))
+	}
+	return orders, nil
+}
+
+func toOrder(item orderInfo) models.Order {
+	price, _ := strconv.ParseFloat(item.Price, 64)
+	qty, _ := strconv.ParseFloat(item.OrigQty, 64)
+	filled, _ := strconv.ParseFloat(item.ExecutedQty, 64)
+
+	return models.Order{
+		ID:         strconv.FormatInt(item.OrderID, 10),
+		LinkID:     item.ClientOrderID,
+		Symbol:     item.Symbol,
+		Side:       ToSide(item.Side),
+		Type:       ToOrderType(item.Type),
+		Price:      price,
+		Qty:        qty,
+		FilledQty:  filled,
+		Status:     ToOrderStatus(item.Status),
+		CreateTime: time.UnixMilli(item.Time),
+		UpdateTime: time.UnixMilli(item.UpdateTime),
+	}
+}
+
+func ToSide(side string) models.OrderSide {
+	if strings.EqualFold(side, "SELL") {
+		return models.OrderSideSell
+	}
+	return models.OrderSideBuy
+}
+
+func ToOrderType(orderType string) models.OrderType {
+	if strings.EqualFold(orderType, "MARKET") {
+		return models.OrderTypeMarket
+	}
+	return models.OrderTypeLimit
+}
+
+func ToOrderStatus(status string) models.OrderStatus {
+	switch strings.ToUpper(status) {
+	case "NEW", "PENDING_NEW":
+		return models.OrderStatusNew
+	case "PARTIALLY_FILLED":
+		return models.OrderStatusPartiallyFilled
+	case "FILLED":
+		return models.OrderStatusFilled
+	case "CANCELED", "PENDING_CANCEL", "EXPIRED", "EXPIRED_IN_MATCH":
+		return models.OrderStatusCanceled
+	case "REJECTED":
+		return models.OrderStatusRejected
+	default:
+		return models.OrderStatus(status)
+	}
+}
@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dcabot/internal/secrets"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func (c *Client) doRequest(ctx context.Context, method, path string, params url.Values, sec security, out any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	query := ""
+	if sec == securitySigned {
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+		params.Set("recvWindow", "5000")
+		query = params.Encode()
+		query += "&signature=" + sign(c.secret, query)
+	} else if len(params) > 0 {
+		query = params.Encode()
+	}
+
+	urlStr := c.baseURL + path
+	if query != "" {
+		urlStr += "?" + query
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, nil)
+	if err != nil {
+		return fmt.Errorf("Не удалось создать запрос: %w", err)
+	}
+
+	if sec != securityNone {
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return secrets.RedactError(fmt.Errorf("Ошибка запроса: %w", err))
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Не удалось прочитать ответ: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var apiErr binanceError
+		if err := json.Unmarshal(data, &apiErr); err == nil && apiErr.Code != 0 {
+			return secrets.RedactError(fmt.Errorf("Ошибка binance: %s (code=%d)", apiErr.Msg, apiErr.Code))
+		}
+		return fmt.Errorf("Неуспешный статус: %s", resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("Не удалось разобрать ответ: %w", err)
+	}
+
+	return nil
+}
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
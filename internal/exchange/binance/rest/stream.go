@@ -0,0 +1,30 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) CreateListenKey(ctx context.Context) (string, error) {
+	var resp struct {
+		ListenKey string `json:"listenKey"`
+	}
+
+	if err := c.doRequest(ctx, http.MethodPost, "/api/v3/userDataStream", nil, securityAPIKey, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.ListenKey == "" {
+		return "", fmt.Errorf("Пустой listenKey в ответе binance.")
+	}
+	return resp.ListenKey, nil
+}
+
+func (c *Client) KeepAliveListenKey(ctx context.Context, listenKey string) error {
+	params := url.Values{}
+	params.Set("listenKey", listenKey)
+
+	return c.doRequest(ctx, http.MethodPut, "/api/v3/userDataStream", params, securityAPIKey, nil)
+}
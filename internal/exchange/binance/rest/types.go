@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"dcabot/internal/logger"
+	"net/http"
+)
+
+type security int
+
+const (
+	securityNone security = iota
+	securityAPIKey
+	securitySigned
+)
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	secret     string
+	httpClient *http.Client
+	log        *logger.Logger
+}
+
+type binanceError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type exchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		Status     string `json:"status"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+		Filters    []struct {
+			FilterType  string `json:"filterType"`
+			TickSize    string `json:"tickSize"`
+			StepSize    string `json:"stepSize"`
+			MinQty      string `json:"minQty"`
+			MinNotional string `json:"minNotional"`
+		} `json:"filters"`
+	} `json:"symbols"`
+}
+
+type orderInfo struct {
+	OrderID       int64  `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	Price         string `json:"price"`
+	OrigQty       string `json:"origQty"`
+	ExecutedQty   string `json:"executedQty"`
+	Status        string `json:"status"`
+	Time          int64  `json:"time"`
+	UpdateTime    int64  `json:"updateTime"`
+}
+
+type tradeInfo struct {
+	ID      int64  `json:"id"`
+	OrderID int64  `json:"orderId"`
+	Symbol  string `json:"symbol"`
+	Price   string `json:"price"`
+	Qty     string `json:"qty"`
+	Time    int64  `json:"time"`
+	IsBuyer bool   `json:"isBuyer"`
+
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+}
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/logger"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+func New(name string, urlFn URLFunc, log *logger.Logger) *Client {
+	return &Client{
+		name:         name,
+		urlFn:        urlFn,
+		log:          log,
+		events:       make(chan exchange.Event, 100),
+		stopCh:       make(chan struct{}),
+		reconnectMin: 1 * time.Second,
+		reconnectMax: 30 * time.Second,
+	}
+}
+
+func (w *Client) Connect(ctx context.Context) error {
+	conn, err := w.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.conn = conn
+	w.mu.Unlock()
+
+	w.logEntry().Info("WS соединение установлено.")
+
+	go w.readLoop()
+
+	return nil
+}
+
+func (w *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	url, err := w.urlFn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось получить адрес WS: %w", err)
+	}
+
+	w.logEntry().Info("Подключение к WS.")
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось подключиться к WS: %w", err)
+	}
+	conn.SetReadLimit(2 << 20)
+	return conn, nil
+}
+
+func (w *Client) Reconnect() {
+	w.mu.Lock()
+	conn := w.conn
+	w.mu.Unlock()
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
+
+func (w *Client) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.Reconnect()
+	})
+}
+
+func (w *Client) logEntry() *logrus.Entry {
+	return w.log.WithComponent("binance_ws").WithField("stream", w.name)
+}
+
+func (w *Client) Events() <-chan exchange.Event {
+	return w.events
+}
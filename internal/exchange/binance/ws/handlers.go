@@ -0,0 +1,187 @@
+package ws
+
+import (
+	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/binance/rest"
+	"dcabot/internal/models"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (w *Client) handleExecutionReport(data []byte) {
+	var item struct {
+		EventTime       int64  `json:"E"`
+		Symbol          string `json:"s"`
+		ClientOrderID   string `json:"c"`
+		OrigClientOrder string `json:"C"`
+		Side            string `json:"S"`
+		OrderType       string `json:"o"`
+		Qty             string `json:"q"`
+		Price           string `json:"p"`
+		ExecType        string `json:"x"`
+		OrderStatus     string `json:"X"`
+		RejectReason    string `json:"r"`
+		OrderID         int64  `json:"i"`
+		LastQty         string `json:"l"`
+		CumQty          string `json:"z"`
+		LastPrice       string `json:"L"`
+		TradeTime       int64  `json:"T"`
+		TradeID         int64  `json:"t"`
+
+		Commission      string `json:"n"`
+		CommissionAsset string `json:"N"`
+
+		// encoding/json сопоставляет ключи без учёта регистра, поэтому парные ключи
+		// binance объявлены явно, иначе "Q" попадёт в Qty, "Z" в CumQty и т.д.
+		Event         string `json:"e"`
+		CreationTime  int64  `json:"O"`
+		QuoteOrderQty string `json:"Q"`
+		StopPrice     string `json:"P"`
+		Ignore        int64  `json:"I"`
+		CumQuoteQty   string `json:"Z"`
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		w.logEntry().WithError(err).Warn("Не удалось разобрать executionReport.")
+		return
+	}
+
+	linkID := item.ClientOrderID
+	if item.OrigClientOrder != "" {
+		linkID = item.OrigClientOrder
+	}
+	orderID := strconv.FormatInt(item.OrderID, 10)
+
+	w.logEntry().WithFields(map[string]interface{}{
+		"symbol":        item.Symbol,
+		"side":          item.Side,
+		"order_id":      orderID,
+		"order_link_id": linkID,
+		"type":          item.OrderType,
+		"exec_type":     item.ExecType,
+		"status":        item.OrderStatus,
+		"reject_reason": item.RejectReason,
+		"price":         item.Price,
+		"qty":           item.Qty,
+		"cum_qty":       item.CumQty,
+		"trade_id":      item.TradeID,
+	}).Debug("executionReport")
+
+	price, _ := strconv.ParseFloat(item.Price, 64)
+	qty, _ := strconv.ParseFloat(item.Qty, 64)
+	cumQty, _ := strconv.ParseFloat(item.CumQty, 64)
+
+	if strings.EqualFold(item.ExecType, "TRADE") {
+		lastPrice, _ := strconv.ParseFloat(item.LastPrice, 64)
+		lastQty, _ := strconv.ParseFloat(item.LastQty, 64)
+		fee, _ := strconv.ParseFloat(item.Commission, 64)
+
+		w.events <- exchange.Event{
+			Type: exchange.EventTypeFill,
+			Fill: &models.Fill{
+				OrderID:   orderID,
+				LinkID:    linkID,
+				ExecID:    strconv.FormatInt(item.TradeID, 10),
+				Symbol:    item.Symbol,
+				Side:      rest.ToSide(item.Side),
+				Price:     lastPrice,
+				Qty:       lastQty,
+				Timestamp: time.UnixMilli(item.TradeTime),
+				Sequence:  item.TradeID,
+				Fee:       fee,
+				FeeCoin:   item.CommissionAsset,
+			},
+		}
+	}
+
+	order := &models.Order{
+		ID:         orderID,
+		LinkID:     linkID,
+		Symbol:     item.Symbol,
+		Side:       rest.ToSide(item.Side),
+		Type:       rest.ToOrderType(item.OrderType),
+		Price:      price,
+		Qty:        qty,
+		FilledQty:  cumQty,
+		Status:     rest.ToOrderStatus(item.OrderStatus),
+		UpdateTime: time.UnixMilli(item.EventTime),
+	}
+	if item.RejectReason != "" && !strings.EqualFold(item.RejectReason, "NONE") {
+		order.RejectReason = item.RejectReason
+	}
+	if order.Status == models.OrderStatusCanceled {
+		order.CancelType = item.OrderStatus
+	}
+
+	w.events <- exchange.Event{
+		Type:  exchange.EventTypeOrder,
+		Order: order,
+	}
+}
+
+func (w *Client) handleMiniTicker(data []byte) {
+	var item struct {
+		EventTime int64  `json:"E"`
+		Symbol    string `json:"s"`
+		Close     string `json:"c"`
+
+		// Парные ключи объявлены явно, см. handleExecutionReport.
+		Event string `json:"e"`
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		w.logEntry().WithError(err).Warn("Не удалось разобрать miniTicker.")
+		return
+	}
+
+	price, _ := strconv.ParseFloat(item.Close, 64)
+
+	w.events <- exchange.Event{
+		Type: exchange.EventTypeTicker,
+		Ticker: &models.Ticker{
+			Symbol:    item.Symbol,
+			LastPrice: price,
+			Timestamp: time.UnixMilli(item.EventTime),
+			Sequence:  item.EventTime,
+		},
+	}
+}
+
+func (w *Client) handleBookTicker(data []byte) {
+	var item struct {
+		UpdateID int64  `json:"u"`
+		Symbol   string `json:"s"`
+		Bid      string `json:"b"`
+		Ask      string `json:"a"`
+
+		// Парные ключи объявлены явно, см. handleExecutionReport.
+		BidQty string `json:"B"`
+		AskQty string `json:"A"`
+	}
+
+	if err := json.Unmarshal(data, &item); err != nil {
+		w.logEntry().WithError(err).Warn("Не удалось разобрать bookTicker.")
+		return
+	}
+
+	bid, _ := strconv.ParseFloat(item.Bid, 64)
+	ask, _ := strconv.ParseFloat(item.Ask, 64)
+	price := bid
+	if bid > 0 && ask > 0 {
+		price = (bid + ask) / 2
+	} else if price == 0 {
+		price = ask
+	}
+
+	w.events <- exchange.Event{
+		Type: exchange.EventTypeTicker,
+		Ticker: &models.Ticker{
+			Symbol:    item.Symbol,
+			LastPrice: price,
+			Timestamp: time.Now(),
+			Sequence:  item.UpdateID,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"encoding/json"
+	"time"
+)
+
+func (w *Client) readLoop() {
+	w.logEntry().Debug("readLoop запущен.")
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		default:
+		}
+
+		w.mu.Lock()
+		conn := w.conn
+		w.mu.Unlock()
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			w.logEntry().WithError(err).Warn("Ошибка чтения WS.")
+
+			if !w.reconnect() {
+				return
+			}
+			continue
+		}
+
+		var stream StreamMessage
+		if err := json.Unmarshal(data, &stream); err == nil && stream.Stream != "" {
+			data = stream.Data
+		}
+
+		var header EventHeader
+		if err := json.Unmarshal(data, &header); err != nil {
+			w.logEntry().WithError(err).Warn("Не удалось разобрать WS сообщение.")
+			continue
+		}
+
+		switch {
+		case header.Event == "executionReport":
+			w.handleExecutionReport(data)
+		case header.Event == "24hrMiniTicker":
+			w.handleMiniTicker(data)
+		case header.Event == "" && header.UpdateID > 0:
+			w.handleBookTicker(data)
+		case header.Event == "listenKeyExpired":
+			w.logEntry().Warn("listenKey истёк, переподключение.")
+			w.Reconnect()
+		default:
+			continue
+		}
+	}
+}
+
+func (w *Client) reconnect() bool {
+	backoff := w.reconnectMin
+
+	for {
+		select {
+		case <-w.stopCh:
+			return false
+		default:
+		}
+
+		w.logEntry().Info("Попытка переподключения к WS.")
+
+		time.Sleep(backoff)
+
+		conn, err := w.dial(context.Background())
+		if err != nil {
+			w.logEntry().WithError(err).Warn("Не удалось переподключиться к WS.")
+			backoff = w.nextBackoff(backoff)
+			continue
+		}
+
+		w.mu.Lock()
+		if w.conn != nil {
+			_ = w.conn.Close()
+		}
+		w.conn = conn
+		w.mu.Unlock()
+
+		w.events <- exchange.Event{Type: exchange.EventTypeReconnect}
+		w.logEntry().Info("WS переподключён.")
+		return true
+	}
+}
+
+func (w *Client) nextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next > w.reconnectMax {
+		return w.reconnectMax
+	}
+	return next
+}
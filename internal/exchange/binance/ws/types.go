@@ -0,0 +1,38 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/logger"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type URLFunc func(ctx context.Context) (string, error)
+
+type Client struct {
+	name         string
+	urlFn        URLFunc
+	log          *logger.Logger
+	mu           sync.Mutex
+	conn         *websocket.Conn
+	events       chan exchange.Event
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+}
+
+type StreamMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type EventHeader struct {
+	Event     string `json:"e"`
+	EventTime int64  `json:"E"`
+	UpdateID  int64  `json:"u"`
+}
@@ -2,12 +2,16 @@ package bybit
 
 import (
 	"context"
+	"dcabot/internal/config"
 	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/bybit/clock"
 	"dcabot/internal/exchange/bybit/rest"
+	"dcabot/internal/exchange/bybit/sign"
 	"dcabot/internal/exchange/bybit/ws"
 	"dcabot/internal/logger"
 	"dcabot/internal/models"
 	"errors"
+	"time"
 )
 
 type Client struct {
@@ -18,20 +22,66 @@ type Client struct {
 	log         *logger.Logger
 }
 
-func New(baseURL, wsPublicURL, wsPrivateURL, accountType, apiKey, secret string, log *logger.Logger) *Client {
+func New(baseURL, wsPublicURL, wsPrivateURL, accountType, apiKey string, signer sign.Signer, recvWindow int, syncInterval time.Duration, log *logger.Logger) *Client {
+	restClient := rest.New(baseURL, apiKey, signer, accountType, recvWindow, syncInterval, log)
 	return &Client{
-		rest:      rest.New(baseURL, apiKey, secret, accountType, log),
-		wsPublic:  newWSClient(wsPublicURL, "", "", log),
-		wsPrivate: newWSClient(wsPrivateURL, apiKey, secret, log),
+		rest:      restClient,
+		wsPublic:  newWSClient(wsPublicURL, "", nil, nil, log),
+		wsPrivate: newWSClient(wsPrivateURL, apiKey, signer, restClient.Clock(), log),
 		log:       log,
 	}
 }
 
-func newWSClient(url, apiKey, secret string, log *logger.Logger) *ws.Client {
-	client, _ := ws.New(url, apiKey, secret, log)
+func init() {
+	exchange.Register("bybit", func(cfg config.ExchangeConfig, log *logger.Logger) (exchange.Client, error) {
+		preset, err := EndpointsFor(cfg.Environment)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.BaseUrl == "" {
+			cfg.BaseUrl = preset.BaseURL
+		}
+		if cfg.WSPublicURL == "" {
+			cfg.WSPublicURL = preset.WSPublicURL
+		}
+		if cfg.WSPrivateURL == "" {
+			cfg.WSPrivateURL = preset.WSPrivateURL
+		}
+		if err := CheckEndpoints(cfg.Environment, Endpoints{
+			BaseURL:      cfg.BaseUrl,
+			WSPublicURL:  cfg.WSPublicURL,
+			WSPrivateURL: cfg.WSPrivateURL,
+		}); err != nil {
+			return nil, err
+		}
+		log.WithComponent("bybit").WithFields(map[string]interface{}{
+			"base_url":       cfg.BaseUrl,
+			"ws_public_url":  cfg.WSPublicURL,
+			"ws_private_url": cfg.WSPrivateURL,
+		}).Info("Адреса bybit.")
+		signer, err := sign.New(cfg.SignMethod, cfg.Secret, cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg.BaseUrl, cfg.WSPublicURL, cfg.WSPrivateURL, cfg.AccountType, cfg.ApiKey, signer, cfg.RecvWindow, cfg.TimeSyncInterval, log), nil
+	})
+}
+
+func newWSClient(url, apiKey string, signer sign.Signer, clk *clock.Clock, log *logger.Logger) *ws.Client {
+	client, _ := ws.New(url, apiKey, signer, clk, log)
 	return client
 }
 
+func (c *Client) Capabilities() exchange.Capabilities {
+	return exchange.Capabilities{
+		Amend:       true,
+		BatchOrders: true,
+		PostOnly:    true,
+		FeeCurrency: exchange.FeeCurrencyReceived,
+		Convert:     true,
+	}
+}
+
 func (c *Client) GetInstrumentRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
 	return c.rest.GetInstrumentRules(ctx, symbol)
 }
@@ -43,24 +93,9 @@ func (c *Client) Subscribe(ctx context.Context, symbol string) (<-chan exchange.
 		return nil, errors.New("Подписка уже создана.")
 	}
 
-	if err := c.wsPublic.Connect(ctx); err != nil {
-		return nil, err
-	}
-
-	if err := c.wsPrivate.Connect(ctx); err != nil {
-		return nil, err
-	}
-
-	if err := c.wsPublic.SubscribeToTopics(ctx, symbol, []string{
-		"tickers." + symbol,
-	}); err != nil {
-		return nil, err
-	}
-
-	if err := c.wsPrivate.SubscribeToTopics(ctx, symbol, []string{
-		"order",
-		"execution",
-	}); err != nil {
+	if err := c.connect(ctx, symbol); err != nil {
+		c.wsPublic.Close()
+		c.wsPrivate.Close()
 		return nil, err
 	}
 
@@ -77,6 +112,28 @@ func (c *Client) Subscribe(ctx context.Context, symbol string) (<-chan exchange.
 	return merged, nil
 }
 
+func (c *Client) connect(ctx context.Context, symbol string) error {
+	if err := c.wsPublic.Connect(ctx); err != nil {
+		return err
+	}
+
+	c.rest.Clock().EnsureSynced(ctx)
+	if err := c.wsPrivate.Connect(ctx); err != nil {
+		return err
+	}
+
+	if err := c.wsPublic.SubscribeToTopics(ctx, symbol, []string{
+		"tickers." + symbol,
+	}); err != nil {
+		return err
+	}
+
+	return c.wsPrivate.SubscribeToTopics(ctx, symbol, []string{
+		"order",
+		"execution",
+	})
+}
+
 func (c *Client) CancelOrder(ctx context.Context, symbol, orderID string) error {
 	return c.rest.CancelOrder(ctx, symbol, orderID)
 }
@@ -89,14 +146,30 @@ func (c *Client) PlaceOrder(ctx context.Context, order models.Order) (models.Ord
 	return c.rest.PlaceOrder(ctx, order)
 }
 
-func (c *Client) GetFills(ctx context.Context, symbol string) ([]models.Fill, error) {
-	return c.rest.GetFills(ctx, symbol)
+func (c *Client) AmendOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	return c.rest.AmendOrder(ctx, order)
+}
+
+func (c *Client) GetFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
+	return c.rest.GetFills(ctx, query)
+}
+
+func (c *Client) GetOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	return c.rest.GetOrderHistory(ctx, query)
 }
 
 func (c *Client) GetBalances(ctx context.Context, coins []string) (map[string]exchange.Balance, error) {
 	return c.rest.GetBalances(ctx, coins)
 }
 
+func (c *Client) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	return c.rest.GetKlines(ctx, symbol, interval, limit)
+}
+
+func (c *Client) Convert(ctx context.Context, fromCoin, toCoin string, amount float64) (exchange.ConvertResult, error) {
+	return c.rest.Convert(ctx, fromCoin, toCoin, amount)
+}
+
 func forwardEvents(src <-chan exchange.Event, dst chan<- exchange.Event) {
 	for event := range src {
 		dst <- event
@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"context"
+	"dcabot/internal/logger"
+	"sync"
+	"time"
+)
+
+type FetchFunc func(ctx context.Context) (time.Time, error)
+
+type Clock struct {
+	fetch    FetchFunc
+	interval time.Duration
+	log      *logger.Logger
+
+	mu       sync.RWMutex
+	offset   time.Duration
+	syncedAt time.Time
+	syncMu   sync.Mutex
+}
+
+func New(fetch FetchFunc, interval time.Duration, log *logger.Logger) *Clock {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &Clock{fetch: fetch, interval: interval, log: log}
+}
+
+func (c *Clock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+func (c *Clock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
+
+// EnsureSynced синхронизирует время, если с последней синхронизации прошло больше interval.
+func (c *Clock) EnsureSynced(ctx context.Context) {
+	if c == nil || c.fresh() {
+		return
+	}
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	if c.fresh() {
+		return
+	}
+	if err := c.sync(ctx); err != nil {
+		c.log.WithComponent("bybit_clock").WithError(err).Warn("Не удалось синхронизировать время с сервером, используется прежнее смещение.")
+	}
+}
+
+func (c *Clock) Sync(ctx context.Context) error {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	return c.sync(ctx)
+}
+
+func (c *Clock) fresh() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.syncedAt.IsZero() && time.Since(c.syncedAt) < c.interval
+}
+
+func (c *Clock) sync(ctx context.Context) error {
+	sent := time.Now()
+	server, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	received := time.Now()
+	rtt := received.Sub(sent)
+	offset := server.Sub(sent.Add(rtt / 2))
+
+	c.mu.Lock()
+	prev := c.offset
+	c.offset = offset
+	c.syncedAt = received
+	c.mu.Unlock()
+
+	c.log.WithComponent("bybit_clock").WithFields(map[string]interface{}{
+		"offset_ms":      offset.Milliseconds(),
+		"prev_offset_ms": prev.Milliseconds(),
+		"rtt_ms":         rtt.Milliseconds(),
+	}).Debug("Время синхронизировано с сервером.")
+	return nil
+}
@@ -0,0 +1,106 @@
+package clock
+
+import (
+	"context"
+	"dcabot/internal/logger"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLogger() *logger.Logger {
+	return logger.New(logger.Config{Level: "panic"})
+}
+
+func TestSyncOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		rtt    time.Duration
+	}{
+		{name: "in sync", offset: 0},
+		{name: "server ahead", offset: 3 * time.Second},
+		{name: "server behind", offset: -2 * time.Second},
+		{name: "slow round trip", offset: 3 * time.Second, rtt: 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Сервер отвечает временем середины запроса: смещение не должно включать половину rtt.
+			fetch := func(ctx context.Context) (time.Time, error) {
+				time.Sleep(tt.rtt / 2)
+				server := time.Now().Add(tt.offset)
+				time.Sleep(tt.rtt / 2)
+				return server, nil
+			}
+			c := New(fetch, time.Hour, testLogger())
+			if err := c.Sync(context.Background()); err != nil {
+				t.Fatalf("Sync error = %v", err)
+			}
+			if diff := c.Offset() - tt.offset; diff < -40*time.Millisecond || diff > 40*time.Millisecond {
+				t.Fatalf("Offset = %s, want %s", c.Offset(), tt.offset)
+			}
+			if diff := c.Now().Sub(time.Now().Add(tt.offset)); diff < -40*time.Millisecond || diff > 40*time.Millisecond {
+				t.Fatalf("Now differs from server time by %s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncErrorKeepsOffset(t *testing.T) {
+	fail := errors.New("time endpoint unavailable")
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) (time.Time, error) {
+		if calls.Add(1) > 1 {
+			return time.Time{}, fail
+		}
+		return time.Now().Add(time.Second), nil
+	}
+	c := New(fetch, time.Hour, testLogger())
+	if err := c.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync error = %v", err)
+	}
+	offset := c.Offset()
+	if err := c.Sync(context.Background()); !errors.Is(err, fail) {
+		t.Fatalf("Sync error = %v, want %v", err, fail)
+	}
+	if c.Offset() != offset {
+		t.Fatalf("Offset after failed sync = %s, want %s", c.Offset(), offset)
+	}
+}
+
+func TestEnsureSynced(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		wait      time.Duration
+		wantCalls int32
+	}{
+		{name: "fresh offset reused", interval: time.Hour, wantCalls: 1},
+		{name: "stale offset resynced", interval: 10 * time.Millisecond, wait: 20 * time.Millisecond, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			fetch := func(ctx context.Context) (time.Time, error) {
+				calls.Add(1)
+				return time.Now(), nil
+			}
+			c := New(fetch, tt.interval, testLogger())
+			c.EnsureSynced(context.Background())
+			time.Sleep(tt.wait)
+			c.EnsureSynced(context.Background())
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("fetch called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNilClock(t *testing.T) {
+	var c *Clock
+	c.EnsureSynced(context.Background())
+	if diff := time.Since(c.Now()); diff < 0 || diff > time.Second {
+		t.Fatalf("nil Clock.Now differs from local time by %s", diff)
+	}
+}
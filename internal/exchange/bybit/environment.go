@@ -0,0 +1,114 @@
+package bybit
+
+import (
+	"dcabot/internal/config"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type Endpoints struct {
+	BaseURL      string
+	WSPublicURL  string
+	WSPrivateURL string
+}
+
+var environments = map[string]Endpoints{
+	config.EnvironmentMainnet: {
+		BaseURL:      "https://api.bybit.com",
+		WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+		WSPrivateURL: "wss://stream.bybit.com/v5/private",
+	},
+	config.EnvironmentTestnet: {
+		BaseURL:      "https://api-testnet.bybit.com",
+		WSPublicURL:  "wss://stream-testnet.bybit.com/v5/public/spot",
+		WSPrivateURL: "wss://stream-testnet.bybit.com/v5/private",
+	},
+	config.EnvironmentDemo: {
+		BaseURL:      "https://api-demo.bybit.com",
+		WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+		WSPrivateURL: "wss://stream-demo.bybit.com/v5/private",
+	},
+}
+
+func EndpointsFor(environment string) (Endpoints, error) {
+	if environment == "" {
+		environment = config.EnvironmentMainnet
+	}
+	endpoints, ok := environments[environment]
+	if !ok {
+		return Endpoints{}, fmt.Errorf("Неизвестное окружение bybit: %s", environment)
+	}
+	return endpoints, nil
+}
+
+// endpointHosts - документированные хосты bybit для окружения, включая альтернативные
+// (bytick) и региональные домены.
+type endpointHosts struct {
+	base      []string
+	wsPublic  []string
+	wsPrivate []string
+}
+
+var environmentHosts = map[string]endpointHosts{
+	config.EnvironmentMainnet: {
+		base: []string{
+			"api.bybit.com", "api.bytick.com",
+			"api.bybit.nl", "api.bybit-tr.com", "api.bybit.kz", "api.byhkbit.com",
+			"api.bybitgeorgia.ge", "api.bybit.eu", "api.bybit.ae",
+		},
+		wsPublic:  []string{"stream.bybit.com", "stream.bytick.com"},
+		wsPrivate: []string{"stream.bybit.com", "stream.bytick.com"},
+	},
+	config.EnvironmentTestnet: {
+		base:      []string{"api-testnet.bybit.com"},
+		wsPublic:  []string{"stream-testnet.bybit.com"},
+		wsPrivate: []string{"stream-testnet.bybit.com"},
+	},
+	config.EnvironmentDemo: {
+		base:      []string{"api-demo.bybit.com"},
+		wsPublic:  []string{"stream.bybit.com", "stream.bytick.com"},
+		wsPrivate: []string{"stream-demo.bybit.com"},
+	},
+}
+
+// CheckEndpoints не даёт явно заданным адресам увести бота в другое окружение:
+// хост каждого адреса должен входить в список хостов выбранного окружения.
+func CheckEndpoints(environment string, endpoints Endpoints) error {
+	if environment == "" {
+		environment = config.EnvironmentMainnet
+	}
+	hosts, ok := environmentHosts[environment]
+	if !ok {
+		return fmt.Errorf("Неизвестное окружение bybit: %s", environment)
+	}
+	for _, item := range []struct {
+		key, value string
+		allowed    []string
+	}{
+		{"base_url", endpoints.BaseURL, hosts.base},
+		{"ws_public_url", endpoints.WSPublicURL, hosts.wsPublic},
+		{"ws_private_url", endpoints.WSPrivateURL, hosts.wsPrivate},
+	} {
+		host, err := endpointHost(item.value)
+		if err != nil {
+			return fmt.Errorf("Некорректный адрес bybit %s: %w", item.key, err)
+		}
+		if !slices.Contains(item.allowed, host) {
+			return fmt.Errorf("Адрес bybit %s=%s не соответствует окружению %s: ожидается один из хостов %s", item.key, item.value, environment, strings.Join(item.allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func endpointHost(raw string) (string, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("нет хоста в адресе %s", raw)
+	}
+	return strings.ToLower(parsed.Hostname()), nil
+}
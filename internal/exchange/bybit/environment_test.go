@@ -0,0 +1,118 @@
+package bybit
+
+import (
+	"dcabot/internal/config"
+	"testing"
+)
+
+func TestCheckEndpoints(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		endpoints   Endpoints
+		wantErr     bool
+	}{
+		{
+			name:        "mainnet preset",
+			environment: config.EnvironmentMainnet,
+			endpoints:   environments[config.EnvironmentMainnet],
+		},
+		{
+			name:        "empty environment is mainnet",
+			environment: "",
+			endpoints:   environments[config.EnvironmentMainnet],
+		},
+		{
+			name:        "mainnet bytick hosts",
+			environment: config.EnvironmentMainnet,
+			endpoints: Endpoints{
+				BaseURL:      "https://api.bytick.com",
+				WSPublicURL:  "wss://stream.bytick.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bytick.com/v5/private",
+			},
+		},
+		{
+			name:        "mainnet regional rest host",
+			environment: config.EnvironmentMainnet,
+			endpoints: Endpoints{
+				BaseURL:      "https://API.BYBIT.NL",
+				WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bybit.com/v5/private",
+			},
+		},
+		{
+			name:        "testnet preset",
+			environment: config.EnvironmentTestnet,
+			endpoints:   environments[config.EnvironmentTestnet],
+		},
+		{
+			name:        "demo preset with mainnet public stream",
+			environment: config.EnvironmentDemo,
+			endpoints:   environments[config.EnvironmentDemo],
+		},
+		{
+			name:        "mainnet with testnet rest",
+			environment: config.EnvironmentMainnet,
+			endpoints: Endpoints{
+				BaseURL:      "https://api-testnet.bybit.com",
+				WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bybit.com/v5/private",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "testnet with mainnet private stream",
+			environment: config.EnvironmentTestnet,
+			endpoints: Endpoints{
+				BaseURL:      "https://api-testnet.bybit.com",
+				WSPublicURL:  "wss://stream-testnet.bybit.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bybit.com/v5/private",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "demo with bytick private stream",
+			environment: config.EnvironmentDemo,
+			endpoints: Endpoints{
+				BaseURL:      "https://api-demo.bybit.com",
+				WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bytick.com/v5/private",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "foreign host",
+			environment: config.EnvironmentMainnet,
+			endpoints: Endpoints{
+				BaseURL:      "https://api.bybit.com.example.org",
+				WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bybit.com/v5/private",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "url without host",
+			environment: config.EnvironmentMainnet,
+			endpoints: Endpoints{
+				BaseURL:      "api.bybit.com",
+				WSPublicURL:  "wss://stream.bybit.com/v5/public/spot",
+				WSPrivateURL: "wss://stream.bybit.com/v5/private",
+			},
+			wantErr: true,
+		},
+		{
+			name:        "unknown environment",
+			environment: "staging",
+			endpoints:   environments[config.EnvironmentMainnet],
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckEndpoints(tt.environment, tt.endpoints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckEndpoints() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
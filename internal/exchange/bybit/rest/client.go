@@ -1,20 +1,33 @@
 package rest
 
 import (
+	"dcabot/internal/exchange/bybit/clock"
+	"dcabot/internal/exchange/bybit/sign"
 	"dcabot/internal/logger"
 	"net/http"
+	"strconv"
 	"time"
 )
 
-func New(baseURL, apiKey, secret, accountType string, log *logger.Logger) *Client {
-	return &Client{
+func New(baseURL, apiKey string, signer sign.Signer, accountType string, recvWindow int, syncInterval time.Duration, log *logger.Logger) *Client {
+	if recvWindow <= 0 {
+		recvWindow = 5000
+	}
+	c := &Client{
 		baseURL:     baseURL,
 		accountType: accountType,
 		apiKey:      apiKey,
-		secret:      secret,
+		signer:      signer,
+		recvWindow:  strconv.Itoa(recvWindow),
 		httpClient: &http.Client{
 			Timeout: 15 * time.Second,
 		},
 		log: log,
 	}
+	c.clock = clock.New(c.ServerTime, syncInterval, log)
+	return c
+}
+
+func (c *Client) Clock() *clock.Clock {
+	return c.clock
 }
@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// convertAccountType - тип счёта для /v5/asset/exchange по типу торгового счёта.
+func convertAccountType(accountType string) string {
+	switch strings.ToUpper(accountType) {
+	case "SPOT":
+		return "eb_convert_spot"
+	case "FUND":
+		return "eb_convert_funding"
+	default:
+		return "eb_convert_uta"
+	}
+}
+
+// checkConvertLimits сверяет объём с лимитами монеты из списка доступных для конвертации.
+func (c *Client) checkConvertLimits(ctx context.Context, accountType, fromCoin string, amount float64) error {
+	params := url.Values{}
+	params.Set("accountType", accountType)
+	params.Set("coin", fromCoin)
+	params.Set("side", "0")
+
+	var resp bybitResponse[struct {
+		Coins []struct {
+			Coin               string `json:"coin"`
+			DisableFrom        bool   `json:"disableFrom"`
+			SingleFromMinLimit string `json:"singleFromMinLimit"`
+			SingleFromMaxLimit string `json:"singleFromMaxLimit"`
+		} `json:"coins"`
+	}]
+	if err := c.doRequest(ctx, http.MethodGet, "/v5/asset/exchange/query-coin-list", params, nil, true, &resp); err != nil {
+		return err
+	}
+	for _, coin := range resp.Result.Coins {
+		if !strings.EqualFold(coin.Coin, fromCoin) {
+			continue
+		}
+		if coin.DisableFrom {
+			return fmt.Errorf("%w: %s недоступна для конвертации", exchange.ErrConvertUnavailable, fromCoin)
+		}
+		minAmount, _ := parseFloatOrZero(coin.SingleFromMinLimit)
+		if amount < minAmount {
+			return fmt.Errorf("%w: %s %s меньше минимума %s", exchange.ErrConvertUnavailable, strconv.FormatFloat(amount, 'f', -1, 64), fromCoin, coin.SingleFromMinLimit)
+		}
+		if maxAmount, _ := parseFloatOrZero(coin.SingleFromMaxLimit); maxAmount > 0 && amount > maxAmount {
+			return fmt.Errorf("%w: %s %s больше максимума %s", exchange.ErrConvertUnavailable, strconv.FormatFloat(amount, 'f', -1, 64), fromCoin, coin.SingleFromMaxLimit)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s нет в списке монет для конвертации", exchange.ErrConvertUnavailable, fromCoin)
+}
+
+// Convert проверяет лимиты, запрашивает котировку и сразу исполняет конвертацию fromCoin в toCoin.
+func (c *Client) Convert(ctx context.Context, fromCoin, toCoin string, amount float64) (exchange.ConvertResult, error) {
+	accountType := convertAccountType(c.accountType)
+	if err := c.checkConvertLimits(ctx, accountType, fromCoin, amount); err != nil {
+		return exchange.ConvertResult{}, err
+	}
+
+	body := map[string]any{
+		"fromCoin":      fromCoin,
+		"toCoin":        toCoin,
+		"requestCoin":   fromCoin,
+		"requestAmount": strconv.FormatFloat(amount, 'f', -1, 64),
+		"accountType":   accountType,
+	}
+
+	var quote bybitResponse[struct {
+		QuoteTxID  string `json:"quoteTxId"`
+		FromAmount string `json:"fromAmount"`
+		ToAmount   string `json:"toAmount"`
+	}]
+	if err := c.doRequest(ctx, http.MethodPost, "/v5/asset/exchange/quote-apply", nil, body, true, &quote); err != nil {
+		return exchange.ConvertResult{}, err
+	}
+	if quote.Result.QuoteTxID == "" {
+		return exchange.ConvertResult{}, fmt.Errorf("Пустой quoteTxId в ответе на запрос котировки конвертации.")
+	}
+
+	var exec bybitResponse[struct {
+		QuoteTxID      string `json:"quoteTxId"`
+		ExchangeStatus string `json:"exchangeStatus"`
+	}]
+	if err := c.doRequest(ctx, http.MethodPost, "/v5/asset/exchange/convert-execute", nil, map[string]any{
+		"quoteTxId": quote.Result.QuoteTxID,
+	}, true, &exec); err != nil {
+		return exchange.ConvertResult{}, err
+	}
+
+	fromAmount, _ := parseFloatOrZero(quote.Result.FromAmount)
+	toAmount, _ := parseFloatOrZero(quote.Result.ToAmount)
+	return exchange.ConvertResult{
+		ID:         quote.Result.QuoteTxID,
+		Status:     exec.Result.ExchangeStatus,
+		FromAmount: fromAmount,
+		ToAmount:   toAmount,
+	}, nil
+}
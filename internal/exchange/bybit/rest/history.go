@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Bybit отдаёт историю исполнений и ордеров окнами не длиннее 7 дней.
+const historyWindow = 7 * 24 * time.Hour
+
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// historyWindows режет [since, until] на окна по span, от новых к старым.
+// Без since возвращается одно пустое окно - биржа сама берёт последние 7 дней.
+func historyWindows(since, until time.Time, span time.Duration) []timeWindow {
+	if since.IsZero() {
+		return []timeWindow{{end: until}}
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if !since.Before(until) {
+		return []timeWindow{{start: since, end: since}}
+	}
+
+	var windows []timeWindow
+	for end := until; end.After(since); end = end.Add(-span) {
+		start := end.Add(-span)
+		if start.Before(since) {
+			start = since
+		}
+		windows = append(windows, timeWindow{start: start, end: end})
+	}
+	return windows
+}
+
+func (w timeWindow) apply(params url.Values) {
+	params.Del("startTime")
+	params.Del("endTime")
+	if !w.start.IsZero() {
+		params.Set("startTime", strconv.FormatInt(w.start.UnixMilli(), 10))
+	}
+	if !w.end.IsZero() {
+		params.Set("endTime", strconv.FormatInt(w.end.UnixMilli(), 10))
+	}
+}
+
+func (c *Client) GetFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
+	params := url.Values{}
+	params.Set("category", "spot")
+	params.Set("symbol", query.Symbol)
+	params.Set("limit", "100")
+	if query.LinkID != "" {
+		params.Set("orderLinkId", query.LinkID)
+	}
+
+	var fills []models.Fill
+	for _, window := range historyWindows(query.Since, query.Until, historyWindow) {
+		window.apply(params)
+		params.Del("cursor")
+		for {
+			var resp bybitResponse[executionList]
+
+			if err := c.doRequest(ctx, http.MethodGet, "/v5/execution/list", params, nil, true, &resp); err != nil {
+				return nil, err
+			}
+
+			for _, item := range resp.Result.List {
+				price, _ := strconv.ParseFloat(item.ExecPrice, 64)
+				qty, _ := strconv.ParseFloat(item.ExecQty, 64)
+				tsMs, _ := strconv.ParseInt(item.ExecTime, 10, 64)
+				fee, _ := strconv.ParseFloat(item.ExecFee, 64)
+
+				fills = append(fills, models.Fill{
+					OrderID:   item.OrderID,
+					LinkID:    item.OrderLink,
+					ExecID:    item.ExecID,
+					Symbol:    query.Symbol,
+					Side:      models.OrderSide(item.Side),
+					Price:     price,
+					Qty:       qty,
+					Timestamp: time.UnixMilli(tsMs),
+					Fee:       fee,
+					FeeCoin:   item.FeeCurrency,
+				})
+				if query.Limit > 0 && len(fills) >= query.Limit {
+					return fills, nil
+				}
+			}
+
+			if !nextPage(params, resp.Result.NextPageCursor, len(resp.Result.List)) {
+				break
+			}
+		}
+	}
+	return fills, nil
+}
+
+func (c *Client) GetOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	params := url.Values{}
+	params.Set("category", "spot")
+	params.Set("symbol", query.Symbol)
+	params.Set("limit", "50")
+	if query.LinkID != "" {
+		params.Set("orderLinkId", query.LinkID)
+	}
+
+	var orders []models.Order
+	for _, window := range historyWindows(query.Since, query.Until, historyWindow) {
+		window.apply(params)
+		params.Del("cursor")
+		for {
+			var resp bybitResponse[orderList]
+
+			if err := c.doRequest(ctx, http.MethodGet, "/v5/order/history", params, nil, true, &resp); err != nil {
+				return nil, err
+			}
+
+			for _, item := range resp.Result.List {
+				orders = append(orders, toOrder(item, query.Symbol))
+				if query.Limit > 0 && len(orders) >= query.Limit {
+					return orders, nil
+				}
+			}
+
+			if !nextPage(params, resp.Result.NextPageCursor, len(resp.Result.List)) {
+				break
+			}
+		}
+	}
+	return orders, nil
+}
+
+func nextPage(params url.Values, cursor string, count int) bool {
+	if cursor == "" || count == 0 || cursor == params.Get("cursor") {
+		return false
+	}
+	params.Set("cursor", cursor)
+	return true
+}
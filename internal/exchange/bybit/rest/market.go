@@ -3,10 +3,13 @@ package rest
 import (
 	"context"
 	"dcabot/internal/exchange"
+	"dcabot/internal/models"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"time"
 )
 
 func (c *Client) GetInstrumentRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
@@ -66,3 +69,62 @@ func (c *Client) GetInstrumentRules(ctx context.Context, symbol string) (exchang
 		QuoteCoin:   info.QuoteCoin,
 	}, nil
 }
+
+func (c *Client) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	params := url.Values{}
+	params.Set("category", "spot")
+	params.Set("symbol", symbol)
+	params.Set("interval", interval)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp bybitResponse[struct {
+		List [][]string `json:"list"`
+	}]
+
+	if err := c.doRequest(ctx, http.MethodGet, "/v5/market/kline", params, nil, false, &resp); err != nil {
+		return nil, err
+	}
+
+	klines := make([]models.Kline, 0, len(resp.Result.List))
+	for _, item := range resp.Result.List {
+		if len(item) < 6 {
+			continue
+		}
+		startMs, _ := strconv.ParseInt(item[0], 10, 64)
+		open, _ := strconv.ParseFloat(item[1], 64)
+		high, _ := strconv.ParseFloat(item[2], 64)
+		low, _ := strconv.ParseFloat(item[3], 64)
+		closePrice, _ := strconv.ParseFloat(item[4], 64)
+		volume, _ := strconv.ParseFloat(item[5], 64)
+
+		klines = append(klines, models.Kline{
+			Start:  time.UnixMilli(startMs),
+			Open:   open,
+			High:   high,
+			Low:    low,
+			Close:  closePrice,
+			Volume: volume,
+		})
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].Start.Before(klines[j].Start)
+	})
+	return klines, nil
+}
+
+func (c *Client) ServerTime(ctx context.Context) (time.Time, error) {
+	var resp bybitResponse[serverTime]
+	if err := c.doRequest(ctx, http.MethodGet, "/v5/market/time", nil, nil, false, &resp); err != nil {
+		return time.Time{}, err
+	}
+	if nanos, err := strconv.ParseInt(resp.Result.TimeNano, 10, 64); err == nil && nanos > 0 {
+		return time.Unix(0, nanos), nil
+	}
+	if resp.Time > 0 {
+		return time.UnixMilli(resp.Time), nil
+	}
+	return time.Time{}, fmt.Errorf("Некорректное время сервера: %q", resp.Result.TimeSecond)
+}
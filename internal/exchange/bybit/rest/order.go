@@ -2,6 +2,7 @@ package rest
 
 import (
 	"context"
+	"dcabot/internal/exchange/bybit/ws"
 	"dcabot/internal/models"
 	"net/http"
 	"net/url"
@@ -41,6 +42,29 @@ func (c *Client) PlaceOrder(ctx context.Context, order models.Order) (models.Ord
 	return order, nil
 }
 
+func (c *Client) AmendOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	body := map[string]any{
+		"category": "spot",
+		"symbol":   order.Symbol,
+		"orderId":  order.ID,
+		"qty":      formatWithStep(order.Qty, order.QtyStep),
+		"price":    formatWithStep(order.Price, order.PriceStep),
+	}
+
+	var resp bybitResponse[struct {
+		OrderID string `json:"orderId"`
+	}]
+
+	if err := c.doRequest(ctx, http.MethodPost, "/v5/order/amend", nil, body, true, &resp); err != nil {
+		return models.Order{}, err
+	}
+
+	if resp.Result.OrderID != "" {
+		order.ID = resp.Result.OrderID
+	}
+	return order, nil
+}
+
 func (c *Client) CancelOrder(ctx context.Context, symbol, orderID string) error {
 	body := map[string]any{
 		"category": "spot",
@@ -58,19 +82,7 @@ func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]models.Ord
 	params.Set("category", "spot")
 	params.Set("symbol", symbol)
 
-	var resp bybitResponse[struct {
-		List []struct {
-			OrderID      string `json:"orderId"`
-			OrderLink    string `json:"orderLinkId"`
-			Side         string `json:"side"`
-			OrderType    string `json:"orderType"`
-			Price        string `json:"price"`
-			Qty          string `json:"qty"`
-			LeavesQty    string `json:"leavesQty"`
-			OrderStatus  string `json:"orderStatus"`
-			IsReduceOnly bool   `json:"reduceOnly"`
-		} `json:"list"`
-	}]
+	var resp bybitResponse[orderList]
 
 	if err := c.doRequest(ctx, http.MethodGet, "/v5/order/realtime", params, nil, true, &resp); err != nil {
 		return nil, err
@@ -78,65 +90,38 @@ func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]models.Ord
 
 	var orders []models.Order
 	for _, item := range resp.Result.List {
-		price, _ := strconv.ParseFloat(item.Price, 64)
-		qty, _ := strconv.ParseFloat(item.Qty, 64)
-		leaves, _ := strconv.ParseFloat(item.LeavesQty, 64)
-
-		filled := qty - leaves
-
-		orders = append(orders, models.Order{
-			ID:        item.OrderID,
-			LinkID:    item.OrderLink,
-			Symbol:    symbol,
-			Side:      models.OrderSide(item.Side),
-			Type:      models.OrderType(item.OrderType),
-			Price:     price,
-			Qty:       qty,
-			FilledQty: filled,
-			Status:    models.OrderStatus(item.OrderStatus),
-			IsReduce:  item.IsReduceOnly,
-		})
+		orders = append(orders, toOrder(item, symbol))
 	}
 	return orders, nil
 }
 
-func (c *Client) GetFills(ctx context.Context, symbol string) ([]models.Fill, error) {
-	params := url.Values{}
-	params.Set("category", "spot")
-	params.Set("symbol", symbol)
-
-	var resp bybitResponse[struct {
-		List []struct {
-			OrderID   string `json:"orderId"`
-			OrderLink string `json:"orderLinkId"`
-			ExecID    string `json:"execId"`
-			Side      string `json:"side"`
-			ExecPrice string `json:"execPrice"`
-			ExecQty   string `json:"execQty"`
-			ExecTime  string `json:"execTime"`
-		} `json:"list"`
-	}]
+func toOrder(item orderItem, symbol string) models.Order {
+	price, _ := strconv.ParseFloat(item.Price, 64)
+	qty, _ := strconv.ParseFloat(item.Qty, 64)
+	leaves, _ := strconv.ParseFloat(item.LeavesQty, 64)
+	created, _ := strconv.ParseInt(item.CreatedTime, 10, 64)
+	updated, _ := strconv.ParseInt(item.UpdatedTime, 10, 64)
 
-	if err := c.doRequest(ctx, http.MethodGet, "/v5/execution/list", params, nil, true, &resp); err != nil {
-		return nil, err
+	filled := qty - leaves
+	if cum, err := strconv.ParseFloat(item.CumExecQty, 64); err == nil && item.CumExecQty != "" {
+		filled = cum
 	}
 
-	var fills []models.Fill
-	for _, item := range resp.Result.List {
-		price, _ := strconv.ParseFloat(item.ExecPrice, 64)
-		qty, _ := strconv.ParseFloat(item.ExecQty, 64)
-		tsMs, _ := strconv.ParseInt(item.ExecTime, 10, 64)
-
-		fills = append(fills, models.Fill{
-			OrderID:   item.OrderID,
-			LinkID:    item.OrderLink,
-			ExecID:    item.ExecID,
-			Symbol:    symbol,
-			Side:      models.OrderSide(item.Side),
-			Price:     price,
-			Qty:       qty,
-			Timestamp: time.UnixMilli(tsMs),
-		})
+	return models.Order{
+		ID:         item.OrderID,
+		LinkID:     item.OrderLink,
+		Symbol:     symbol,
+		Side:       models.OrderSide(item.Side),
+		Type:       models.OrderType(item.OrderType),
+		Price:      price,
+		Qty:        qty,
+		FilledQty:  filled,
+		Status:     ws.ToOrderStatus(item.OrderStatus),
+		CreateTime: time.UnixMilli(created),
+		UpdateTime: time.UnixMilli(updated),
+		IsReduce:   item.IsReduceOnly,
+
+		RejectReason: ws.ToRejectReason(item.RejectReason),
+		CancelType:   ws.ToCancelType(item.CancelType),
 	}
-	return fills, nil
 }
@@ -3,20 +3,48 @@ package rest
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"dcabot/internal/secrets"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
-	"time"
 )
 
+const retCodeTimestamp = 10002
+
+type apiError struct {
+	Code int
+	Msg  string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Ошибка bybit: %s (code=%d)", e.Msg, e.Code)
+}
+
 func (c *Client) doRequest(ctx context.Context, method, path string, params url.Values, body any, auth bool, out any) error {
+	if auth {
+		c.clock.EnsureSynced(ctx)
+	}
+
+	err := c.send(ctx, method, path, params, body, auth, out)
+	var apiErr *apiError
+	if !auth || !errors.As(err, &apiErr) || apiErr.Code != retCodeTimestamp {
+		return err
+	}
+
+	c.log.WithComponent("bybit_rest").WithError(err).Warn("Метка времени запроса отклонена, повторная синхронизация времени.")
+	if syncErr := c.clock.Sync(ctx); syncErr != nil {
+		c.log.WithComponent("bybit_rest").WithError(syncErr).Warn("Не удалось синхронизировать время с сервером.")
+		return err
+	}
+	return c.send(ctx, method, path, params, body, auth, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path string, params url.Values, body any, auth bool, out any) error {
 	var bodyReader io.Reader
 	var bodyStr string
 	if body != nil {
@@ -39,8 +67,11 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params url.
 	}
 
 	if auth {
-		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
-		recvWindow := "5000"
+		if c.signer == nil {
+			return fmt.Errorf("Не заданы ключи для подписи запроса.")
+		}
+		timestamp := strconv.FormatInt(c.clock.Now().UnixMilli(), 10)
+		recvWindow := c.recvWindow
 		query := ""
 
 		if method == http.MethodGet && len(params) > 0 {
@@ -48,7 +79,10 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params url.
 		}
 
 		signBase := timestamp + c.apiKey + recvWindow + query + bodyStr
-		signature := sign(c.secret, signBase)
+		signature, err := c.signer.Sign(signBase)
+		if err != nil {
+			return err
+		}
 
 		req.Header.Set("X-BAPI-API-KEY", c.apiKey)
 		req.Header.Set("X-BAPI-SIGN", signature)
@@ -59,7 +93,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params url.
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("Ошибка запроса: %w", err)
+		return secrets.RedactError(fmt.Errorf("Ошибка запроса: %w", err))
 	}
 
 	defer resp.Body.Close()
@@ -74,7 +108,7 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params url.
 	}
 
 	if retCode, retMsg, ok := extractRetCode(out); ok && retCode != 0 {
-		return fmt.Errorf("Ошибка bybit: %s (code=%d)", retMsg, retCode)
+		return secrets.RedactError(&apiError{Code: retCode, Msg: retMsg})
 	}
 
 	if resp.StatusCode >= 400 {
@@ -84,12 +118,6 @@ func (c *Client) doRequest(ctx context.Context, method, path string, params url.
 	return nil
 }
 
-func sign(secret, payload string) string {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(payload))
-	return hex.EncodeToString(mac.Sum(nil))
-}
-
 // TODO
 func extractRetCode(v any) (int, string, bool) {
 	rv := reflect.ValueOf(v)
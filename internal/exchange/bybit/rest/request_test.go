@@ -0,0 +1,147 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange/bybit/sign"
+	"dcabot/internal/logger"
+	"dcabot/internal/secrets"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testAPIKey = "bybit-test-api-key"
+	testSecret = "bybit-test-secret-value"
+)
+
+// newTestClient возвращает REST клиент bybit с HMAC подписью против тестового сервера.
+func newTestClient(t *testing.T, baseURL string) *Client {
+	t.Helper()
+	return New(baseURL, testAPIKey, sign.NewHMAC(testSecret), "UNIFIED", 5000, time.Hour, logger.New(logger.Config{Level: "panic"}))
+}
+
+// serveTime отвечает на /v5/market/time временем сервера со смещением offset.
+func serveTime(w http.ResponseWriter, offset time.Duration) {
+	now := time.Now().Add(offset)
+	fmt.Fprintf(w, `{"retCode":0,"retMsg":"OK","result":{"timeSecond":"%d","timeNano":"%d"},"time":%d}`,
+		now.Unix(), now.UnixNano(), now.UnixMilli())
+}
+
+func TestDoRequestRedactsErrors(t *testing.T) {
+	secrets.Track(testAPIKey, testSecret)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v5/market/time" {
+			serveTime(w, 0)
+			return
+		}
+		fmt.Fprintf(w, `{"retCode":10003,"retMsg":"API key is invalid: api_key=%s secret %s","result":{}}`,
+			r.Header.Get("X-BAPI-API-KEY"), testSecret)
+	}))
+	t.Cleanup(server.Close)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		params  url.Values
+		auth    bool
+		wantErr string
+	}{
+		{name: "api error", baseURL: server.URL, auth: true, wantErr: "code=10003"},
+		{name: "transport error", baseURL: closed.URL, params: url.Values{"apiKey": {testAPIKey}, "note": {testSecret}}, wantErr: "Ошибка запроса"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, tt.baseURL)
+			var resp bybitResponse[struct{}]
+			err := c.doRequest(context.Background(), http.MethodGet, "/v5/account/wallet-balance", tt.params, nil, tt.auth, &resp)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("doRequest error = %v, want containing %q", err, tt.wantErr)
+			}
+			for _, secret := range []string{testAPIKey, testSecret} {
+				if strings.Contains(err.Error(), secret) {
+					t.Fatalf("error leaks %q: %s", secret, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestDoRequestTimestampRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		auth         bool
+		drift        time.Duration
+		code         int
+		timeFails    bool
+		wantRequests int32
+		wantErr      string
+	}{
+		{name: "resync after drift", auth: true, drift: time.Hour, wantRequests: 2},
+		{name: "retried only once", auth: true, code: retCodeTimestamp, wantRequests: 2, wantErr: "code=10002"},
+		{name: "other error not retried", auth: true, code: 10003, wantRequests: 1, wantErr: "code=10003"},
+		{name: "resync failure returns original error", auth: true, drift: time.Hour, timeFails: true, wantRequests: 1, wantErr: "code=10002"},
+		{name: "public request not retried", code: retCodeTimestamp, wantRequests: 1, wantErr: "code=10002"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var drift atomic.Int64
+			var timeFails atomic.Bool
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				offset := time.Duration(drift.Load())
+				if r.URL.Path == "/v5/market/time" {
+					if timeFails.Load() {
+						fmt.Fprint(w, `{"retCode":10016,"retMsg":"service unavailable","result":{}}`)
+						return
+					}
+					serveTime(w, offset)
+					return
+				}
+				requests.Add(1)
+				code := tt.code
+				if code == 0 && r.Header.Get("X-BAPI-TIMESTAMP") != "" {
+					sent, _ := strconv.ParseInt(r.Header.Get("X-BAPI-TIMESTAMP"), 10, 64)
+					if skew := time.Now().Add(offset).UnixMilli() - sent; skew > 5000 || skew < -5000 {
+						code = retCodeTimestamp
+					}
+				}
+				fmt.Fprintf(w, `{"retCode":%d,"retMsg":"code %d","result":{}}`, code, code)
+			}))
+			t.Cleanup(server.Close)
+
+			c := newTestClient(t, server.URL)
+			if err := c.Clock().Sync(context.Background()); err != nil {
+				t.Fatalf("Sync error = %v", err)
+			}
+			drift.Store(int64(tt.drift))
+			timeFails.Store(tt.timeFails)
+
+			var resp bybitResponse[struct{}]
+			err := c.doRequest(context.Background(), http.MethodGet, "/v5/account/wallet-balance", nil, nil, tt.auth, &resp)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("doRequest error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("doRequest error = %v, want containing %q", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Fatalf("server got %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr == "" {
+				if diff := c.Clock().Offset() - tt.drift; diff < -time.Second || diff > time.Second {
+					t.Fatalf("clock offset = %s after resync, want %s", c.Clock().Offset(), tt.drift)
+				}
+			}
+		})
+	}
+}
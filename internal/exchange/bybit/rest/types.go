@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"dcabot/internal/exchange/bybit/clock"
+	"dcabot/internal/exchange/bybit/sign"
 	"dcabot/internal/exchange/bybit/ws"
 	"dcabot/internal/logger"
 	"net/http"
@@ -12,7 +14,9 @@ type Client struct {
 	wsPrivateURL string
 	accountType  string
 	apiKey       string
-	secret       string
+	signer       sign.Signer
+	clock        *clock.Clock
+	recvWindow   string
 	httpClient   *http.Client
 	log          *logger.Logger
 	wsPublic     *ws.Client
@@ -43,3 +47,46 @@ type instrumentInfo struct {
 		} `json:"lotSizeFilter"`
 	} `json:"list"`
 }
+
+type serverTime struct {
+	TimeSecond string `json:"timeSecond"`
+	TimeNano   string `json:"timeNano"`
+}
+
+type executionList struct {
+	NextPageCursor string `json:"nextPageCursor"`
+	List           []struct {
+		OrderID   string `json:"orderId"`
+		OrderLink string `json:"orderLinkId"`
+		ExecID    string `json:"execId"`
+		Side      string `json:"side"`
+		ExecPrice string `json:"execPrice"`
+		ExecQty   string `json:"execQty"`
+		ExecTime  string `json:"execTime"`
+
+		ExecFee     string `json:"execFee"`
+		FeeCurrency string `json:"feeCurrency"`
+	} `json:"list"`
+}
+
+type orderList struct {
+	NextPageCursor string      `json:"nextPageCursor"`
+	List           []orderItem `json:"list"`
+}
+
+type orderItem struct {
+	OrderID      string `json:"orderId"`
+	OrderLink    string `json:"orderLinkId"`
+	Side         string `json:"side"`
+	OrderType    string `json:"orderType"`
+	Price        string `json:"price"`
+	Qty          string `json:"qty"`
+	LeavesQty    string `json:"leavesQty"`
+	CumExecQty   string `json:"cumExecQty"`
+	OrderStatus  string `json:"orderStatus"`
+	IsReduceOnly bool   `json:"reduceOnly"`
+	CreatedTime  string `json:"createdTime"`
+	UpdatedTime  string `json:"updatedTime"`
+	CancelType   string `json:"cancelType"`
+	RejectReason string `json:"rejectReason"`
+}
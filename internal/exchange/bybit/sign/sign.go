@@ -0,0 +1,95 @@
+package sign
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	MethodHMAC = "hmac"
+	MethodRSA  = "rsa"
+)
+
+type Signer interface {
+	Sign(payload string) (string, error)
+}
+
+type HMACSigner struct {
+	secret string
+}
+
+func NewHMAC(secret string) *HMACSigner {
+	return &HMACSigner{secret: secret}
+}
+
+func (s *HMACSigner) Sign(payload string) (string, error) {
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+type RSASigner struct {
+	key *rsa.PrivateKey
+}
+
+func NewRSAFromFile(path string) (*RSASigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось прочитать приватный ключ %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Файл %s не содержит PEM блок.", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return &RSASigner{key: key}, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось разобрать приватный ключ %s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Приватный ключ %s не является RSA ключом.", path)
+	}
+	return &RSASigner{key: key}, nil
+}
+
+func (s *RSASigner) Sign(payload string) (string, error) {
+	hash := sha256.Sum256([]byte(payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("Не удалось подписать запрос RSA ключом: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func New(method, secret, privateKeyFile string) (Signer, error) {
+	switch strings.ToLower(strings.TrimSpace(method)) {
+	case "", MethodHMAC:
+		if secret == "" {
+			return nil, nil
+		}
+		return NewHMAC(secret), nil
+	case MethodRSA:
+		if privateKeyFile == "" {
+			return nil, fmt.Errorf("Для подписи RSA не указан exchange.private_key_file.")
+		}
+		return NewRSAFromFile(privateKeyFile)
+	default:
+		return nil, fmt.Errorf("Неизвестный способ подписи: %s", method)
+	}
+}
@@ -0,0 +1,162 @@
+package sign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePEM сохраняет der в PEM файл во временном каталоге теста.
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	return path
+}
+
+func TestHMACSign(t *testing.T) {
+	// RFC 4231, test case 2.
+	got, err := NewHMAC("Jefe").Sign("what do ya want for nothing?")
+	if err != nil {
+		t.Fatalf("Sign error = %v", err)
+	}
+	if want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"; got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestRSASign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey error = %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "pkcs1", path: writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))},
+		{name: "pkcs8", path: writePEM(t, "PRIVATE KEY", pkcs8)},
+	}
+	const payload = "1700000000000bybit-test-api-key5000category=spot&symbol=BTCUSDT"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewRSAFromFile(tt.path)
+			if err != nil {
+				t.Fatalf("NewRSAFromFile error = %v", err)
+			}
+			signature, err := signer.Sign(payload)
+			if err != nil {
+				t.Fatalf("Sign error = %v", err)
+			}
+			raw, err := base64.StdEncoding.DecodeString(signature)
+			if err != nil {
+				t.Fatalf("signature is not base64: %v", err)
+			}
+			hash := sha256.Sum256([]byte(payload))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], raw); err != nil {
+				t.Fatalf("VerifyPKCS1v15 error = %v", err)
+			}
+		})
+	}
+}
+
+func TestNewRSAFromFileErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey error = %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey error = %v", err)
+	}
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.pem"), wantErr: "Не удалось прочитать"},
+		{name: "not pem", path: notPEM, wantErr: "не содержит PEM"},
+		{name: "broken der", path: writePEM(t, "PRIVATE KEY", []byte("broken")), wantErr: "Не удалось разобрать"},
+		{name: "not rsa", path: writePEM(t, "PRIVATE KEY", ecDER), wantErr: "не является RSA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRSAFromFile(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewRSAFromFile error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey error = %v", err)
+	}
+	keyFile := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+	tests := []struct {
+		name    string
+		method  string
+		secret  string
+		keyFile string
+		want    string
+		wantErr string
+	}{
+		{name: "default hmac", secret: "secret", want: "hmac"},
+		{name: "hmac mixed case", method: " HMAC ", secret: "secret", want: "hmac"},
+		{name: "hmac without secret", method: MethodHMAC, want: "none"},
+		{name: "rsa", method: MethodRSA, keyFile: keyFile, want: "rsa"},
+		{name: "rsa without key file", method: MethodRSA, secret: "secret", wantErr: "private_key_file"},
+		{name: "unknown", method: "ed25519", secret: "secret", wantErr: "Неизвестный способ подписи"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := New(tt.method, tt.secret, tt.keyFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New error = %v", err)
+			}
+			var got string
+			switch signer.(type) {
+			case nil:
+				got = "none"
+			case *HMACSigner:
+				got = "hmac"
+			case *RSASigner:
+				got = "rsa"
+			}
+			if got != tt.want {
+				t.Fatalf("New returned %T, want %s", signer, tt.want)
+			}
+		})
+	}
+}
@@ -1,33 +1,25 @@
 package ws
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"fmt"
-	"time"
+
+	"github.com/gorilla/websocket"
 )
 
-func (w *Client) authenticate() error {
-	expires := time.Now().UnixMilli() + 5_000
+func (w *Client) authenticate(ctx context.Context, conn *websocket.Conn) error {
+	expires := w.clock.Now().UnixMilli() + 5_000
 	payload := fmt.Sprintf("GET/realtime%d", expires)
 
-	sign := sign(w.secret, payload)
-
-	msg := AuthMessage{
-		Op:   "auth",
-		Args: []string{w.apiKey, fmt.Sprintf("%d", expires), sign},
+	sign, err := w.signer.Sign(payload)
+	if err != nil {
+		return err
 	}
 
-	if err := w.conn.WriteJSON(msg); err != nil {
+	if err := w.request(ctx, conn, "auth", []string{w.apiKey, fmt.Sprintf("%d", expires), sign}); err != nil {
 		return fmt.Errorf("Не удалось авторизоваться: %w", err)
 	}
 
+	w.logEntry().Info("WS авторизация подтверждена.")
 	return nil
 }
-
-func sign(secret, payload string) string {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(payload))
-	return hex.EncodeToString(mac.Sum(nil))
-}
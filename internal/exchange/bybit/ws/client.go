@@ -3,6 +3,8 @@ package ws
 import (
 	"context"
 	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/bybit/clock"
+	"dcabot/internal/exchange/bybit/sign"
 	"dcabot/internal/logger"
 	"fmt"
 	"time"
@@ -11,16 +13,19 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func New(url, apiKey, secret string, log *logger.Logger) (*Client, error) {
+func New(url, apiKey string, signer sign.Signer, clk *clock.Clock, log *logger.Logger) (*Client, error) {
 	return &Client{
 		url:          url,
 		apiKey:       apiKey,
-		secret:       secret,
+		signer:       signer,
+		clock:        clk,
 		log:          log,
 		events:       make(chan exchange.Event, 100),
 		stopCh:       make(chan struct{}),
 		reconnectMin: 1 * time.Second,
 		reconnectMax: 30 * time.Second,
+		pingInterval: 20 * time.Second,
+		pongTimeout:  10 * time.Second,
 	}, nil
 }
 
@@ -32,26 +37,49 @@ func (w *Client) Connect(ctx context.Context) error {
 		return fmt.Errorf("Не удалось подключиться к WS: %w", err)
 	}
 
-	w.conn = conn
-	w.conn.SetReadLimit(2 << 20)
+	conn.SetReadLimit(2 << 20)
+	w.setConn(conn)
+	w.startHeartbeat(conn)
+
+	go w.readLoop()
 
-	if w.apiKey != "" && w.secret != "" {
-		if err := w.authenticate(); err != nil {
+	if w.apiKey != "" && w.signer != nil {
+		if err := w.authenticate(ctx, conn); err != nil {
+			w.Close()
 			return err
 		}
 	}
 
 	w.logEntry().Info("WS соединение установлено.")
 
-	go w.readLoop()
-
 	return nil
 }
 
+func (w *Client) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		if conn := w.currentConn(); conn != nil {
+			_ = conn.Close()
+		}
+	})
+}
+
+func (w *Client) currentConn() *websocket.Conn {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	return w.conn
+}
+
+func (w *Client) setConn(conn *websocket.Conn) {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	w.conn = conn
+}
+
 func (w *Client) logEntry() *logrus.Entry {
 	entry := w.log.WithComponent("bybit_ws")
-	if w.symbol != "" {
-		entry = entry.WithField("symbol", w.symbol)
+	if symbol, _ := w.subscription(); symbol != "" {
+		entry = entry.WithField("symbol", symbol)
 	}
 	return entry
 }
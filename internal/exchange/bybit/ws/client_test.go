@@ -0,0 +1,307 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/bybit/sign"
+	"dcabot/internal/logger"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// stubWS - WS bybit в памяти: отвечает на ping и подтверждает запросы по req_id.
+type stubWS struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	conns    []*websocket.Conn
+	open     int
+	pings    int
+	requests []RequestMessage
+	silent   bool
+	reject   map[string]string
+	connsCh  chan struct{}
+	writeMus map[*websocket.Conn]*sync.Mutex
+}
+
+func newStubWS(t *testing.T) *stubWS {
+	t.Helper()
+	s := &stubWS{
+		t:        t,
+		reject:   map[string]string{},
+		connsCh:  make(chan struct{}, 64),
+		writeMus: map[*websocket.Conn]*sync.Mutex{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *stubWS) url() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+func (s *stubWS) serve(rw http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.writeMus[conn] = &sync.Mutex{}
+	s.open++
+	s.mu.Unlock()
+	s.connsCh <- struct{}{}
+
+	defer func() {
+		_ = conn.Close()
+		s.mu.Lock()
+		s.open--
+		s.mu.Unlock()
+	}()
+
+	for {
+		var req RequestMessage
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		s.mu.Lock()
+		if req.Op == "ping" {
+			s.pings++
+			silent := s.silent
+			s.mu.Unlock()
+			if !silent {
+				s.send(conn, map[string]any{"op": "pong"})
+			}
+			continue
+		}
+		s.requests = append(s.requests, req)
+		retMsg, rejected := s.reject[req.Op]
+		s.mu.Unlock()
+
+		s.send(conn, map[string]any{
+			"op":      req.Op,
+			"req_id":  req.ReqID,
+			"success": !rejected,
+			"ret_msg": retMsg,
+		})
+	}
+}
+
+func (s *stubWS) send(conn *websocket.Conn, v any) {
+	s.mu.Lock()
+	writeMu := s.writeMus[conn]
+	s.mu.Unlock()
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	_ = conn.WriteJSON(v)
+}
+
+// push отправляет сообщение в последнее соединение.
+func (s *stubWS) push(v any) {
+	s.mu.Lock()
+	conn := s.conns[len(s.conns)-1]
+	s.mu.Unlock()
+	s.send(conn, v)
+}
+
+// dropAll рвёт все соединения со стороны сервера.
+func (s *stubWS) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+func (s *stubWS) waitConns(n int) {
+	s.t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.connsCh:
+		case <-time.After(5 * time.Second):
+			s.t.Fatalf("нет WS соединения %d", i+1)
+		}
+	}
+}
+
+func (s *stubWS) stats() (conns, open, pings int, requests []RequestMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns), s.open, s.pings, append([]RequestMessage(nil), s.requests...)
+}
+
+// setReject заставляет stub отклонять запросы op с ret_msg retMsg; пустой retMsg снимает отказ.
+func (s *stubWS) setReject(op, retMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if retMsg == "" {
+		delete(s.reject, op)
+		return
+	}
+	s.reject[op] = retMsg
+}
+
+func newTestClient(t *testing.T, stub *stubWS) *Client {
+	t.Helper()
+	return newSignedTestClient(t, stub, "", nil)
+}
+
+func newSignedTestClient(t *testing.T, stub *stubWS, apiKey string, signer sign.Signer) *Client {
+	t.Helper()
+	w, err := New(stub.url(), apiKey, signer, nil, logger.New(logger.Config{Level: "panic"}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.reconnectMin = 10 * time.Millisecond
+	w.reconnectMax = 50 * time.Millisecond
+	w.pingInterval = 50 * time.Millisecond
+	w.pongTimeout = 100 * time.Millisecond
+	t.Cleanup(w.Close)
+	return w
+}
+
+func waitEvent(t *testing.T, w *Client, typ exchange.EventType) exchange.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-w.Events():
+			if event.Type == typ {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("нет события %s", typ)
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	tests := []struct {
+		name      string
+		silent    bool
+		reconnect bool
+	}{
+		{name: "pong keeps connection", silent: false, reconnect: false},
+		{name: "missing pong reconnects", silent: true, reconnect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubWS(t)
+			stub.silent = tt.silent
+			w := newTestClient(t, stub)
+
+			if err := w.Connect(context.Background()); err != nil {
+				t.Fatalf("Connect: %v", err)
+			}
+			stub.waitConns(1)
+
+			if tt.reconnect {
+				stub.waitConns(1)
+				waitEvent(t, w, exchange.EventTypeReconnect)
+				return
+			}
+
+			time.Sleep(10 * w.pingInterval)
+			conns, _, pings, _ := stub.stats()
+			if conns != 1 {
+				t.Fatalf("соединений %d, ожидалось 1", conns)
+			}
+			if pings < 3 {
+				t.Fatalf("получено %d ping, ожидалось не меньше 3", pings)
+			}
+		})
+	}
+}
+
+func TestReconnectRestoresSubscription(t *testing.T) {
+	stub := newStubWS(t)
+	w := newTestClient(t, stub)
+
+	if err := w.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	stub.waitConns(1)
+	topics := []string{"tickers.BTCUSDT"}
+	if err := w.SubscribeToTopics(context.Background(), "BTCUSDT", topics); err != nil {
+		t.Fatalf("SubscribeToTopics: %v", err)
+	}
+
+	stub.dropAll()
+	stub.waitConns(1)
+	waitEvent(t, w, exchange.EventTypeReconnect)
+
+	_, open, _, requests := stub.stats()
+	if open != 1 {
+		t.Fatalf("открыто %d соединений, ожидалось 1", open)
+	}
+	var subscribes int
+	for _, req := range requests {
+		if req.Op == "subscribe" {
+			subscribes++
+			if strings.Join(req.Args, ",") != strings.Join(topics, ",") {
+				t.Fatalf("подписка на %v, ожидалась %v", req.Args, topics)
+			}
+		}
+	}
+	if subscribes != 2 {
+		t.Fatalf("подписок %d, ожидалось 2", subscribes)
+	}
+
+	stub.push(map[string]any{
+		"topic": "tickers.BTCUSDT",
+		"ts":    1,
+		"data":  json.RawMessage(`{"symbol":"BTCUSDT","lastPrice":"100.5","seq":7}`),
+	})
+	event := waitEvent(t, w, exchange.EventTypeTicker)
+	if event.Ticker.LastPrice != 100.5 || event.Ticker.Sequence != 7 {
+		t.Fatalf("тикер после переподключения %+v", event.Ticker)
+	}
+}
+
+func TestCloseDuringReconnect(t *testing.T) {
+	stub := newStubWS(t)
+	w := newTestClient(t, stub)
+	w.reconnectMin = time.Millisecond
+	w.reconnectMax = time.Millisecond
+
+	if err := w.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	stub.waitConns(1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			stub.dropAll()
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+	time.Sleep(15 * time.Millisecond)
+	w.Close()
+	wg.Wait()
+
+	// После Close ни одно соединение, в том числе набранное reconnect, не остаётся открытым.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, open, _, _ := stub.stats()
+		if open == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("после Close открыто %d соединений", open)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -19,6 +19,9 @@ func (w *Client) handleExecution(msg Message) {
 		ExecQty   string `json:"execQty"`
 		ExecTime  string `json:"execTime"`
 		Seq       int64  `json:"seq"`
+
+		ExecFee     string `json:"execFee"`
+		FeeCurrency string `json:"feeCurrency"`
 	}
 
 	if err := json.Unmarshal(msg.Data, &data); err != nil {
@@ -42,6 +45,7 @@ func (w *Client) handleExecution(msg Message) {
 		price, _ := strconv.ParseFloat(item.ExecPrice, 64)
 		qty, _ := strconv.ParseFloat(item.ExecQty, 64)
 		tsMs, _ := strconv.ParseInt(item.ExecTime, 10, 64)
+		fee, _ := strconv.ParseFloat(item.ExecFee, 64)
 
 		w.events <- exchange.Event{
 			Type: exchange.EventTypeFill,
@@ -55,6 +59,8 @@ func (w *Client) handleExecution(msg Message) {
 				Qty:       qty,
 				Timestamp: time.UnixMilli(tsMs),
 				Sequence:  item.Seq,
+				Fee:       fee,
+				FeeCoin:   item.FeeCurrency,
 			},
 		}
 	}
@@ -112,8 +118,11 @@ func (w *Client) handleOrder(msg Message) {
 				Price:     price,
 				Qty:       qty,
 				FilledQty: qty - leaves,
-				Status:    models.OrderStatus(item.OrderStatus),
+				Status:    ToOrderStatus(item.OrderStatus),
 				Sequence:  item.Seq,
+
+				RejectReason: ToRejectReason(item.RejectReason),
+				CancelType:   ToCancelType(item.CancelType),
 			},
 		}
 	}
@@ -0,0 +1,60 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const writeTimeout = 10 * time.Second
+
+func (w *Client) write(conn *websocket.Conn, v any) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteJSON(v)
+}
+
+// startHeartbeat вызывается из Connect и readLoop, поэтому heartbeatStop не требует блокировки.
+func (w *Client) startHeartbeat(conn *websocket.Conn) {
+	if w.heartbeatStop != nil {
+		close(w.heartbeatStop)
+	}
+	stop := make(chan struct{})
+	w.heartbeatStop = stop
+
+	w.lastPong.Store(time.Now().UnixMilli())
+	w.extendReadDeadline(conn)
+	go w.heartbeat(conn, stop)
+}
+
+func (w *Client) extendReadDeadline(conn *websocket.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(w.pingInterval + w.pongTimeout))
+}
+
+func (w *Client) heartbeat(conn *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(w.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		silence := time.Since(time.UnixMilli(w.lastPong.Load()))
+		if silence > w.pingInterval+w.pongTimeout {
+			w.logEntry().WithField("since_pong", silence.Round(time.Second).String()).Warn("Пропущен heartbeat WS, принудительное переподключение.")
+			_ = conn.Close()
+			return
+		}
+
+		if err := w.write(conn, PingMessage{Op: "ping"}); err != nil {
+			w.logEntry().WithError(err).Debug("Heartbeat WS остановлен.")
+			return
+		}
+	}
+}
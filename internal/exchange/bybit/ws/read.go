@@ -19,8 +19,14 @@ func (w *Client) readLoop() {
 			return
 		default:
 		}
-		_, data, err := w.conn.ReadMessage()
+		conn := w.currentConn()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
+			select {
+			case <-w.stopCh:
+				return
+			default:
+			}
 			w.logEntry().WithError(err).Warn("Ошибка чтения WS.")
 
 			if !w.reconnect() {
@@ -29,6 +35,8 @@ func (w *Client) readLoop() {
 			continue
 		}
 
+		w.extendReadDeadline(conn)
+
 		var msg Message
 		if err := json.Unmarshal(data, &msg); err != nil {
 			w.logEntry().WithError(err).Warn("Не удалось разобрать WS сообщение.")
@@ -36,6 +44,16 @@ func (w *Client) readLoop() {
 		}
 
 		switch {
+		case msg.Op == "ping" || msg.Op == "pong":
+			w.lastPong.Store(time.Now().UnixMilli())
+		case msg.Op != "" && msg.Topic == "":
+			if !w.resolve(msg) && msg.Success != nil && !*msg.Success {
+				w.logEntry().WithFields(map[string]interface{}{
+					"op":      msg.Op,
+					"req_id":  msg.ReqID,
+					"ret_msg": msg.RetMsg,
+				}).Warn("WS вернул ошибку без ожидающего запроса.")
+			}
 		case msg.Topic == "execution" || strings.HasPrefix(msg.Topic, "execution"):
 			w.handleExecution(msg)
 		case msg.Topic == "order" || strings.HasPrefix(msg.Topic, "order"):
@@ -43,13 +61,19 @@ func (w *Client) readLoop() {
 		case strings.HasPrefix(msg.Topic, "tickers"):
 			w.handleTicker(msg)
 		default:
-			continue
+			w.logEntry().WithFields(map[string]interface{}{
+				"op":    msg.Op,
+				"topic": msg.Topic,
+			}).Debug("Неизвестное WS сообщение.")
 		}
 	}
 }
 
 func (w *Client) reconnect() bool {
 	backoff := w.reconnectMin
+	for i := int32(0); i < w.failures.Load() && backoff < w.reconnectMax; i++ {
+		backoff = w.nextBackoff(backoff)
+	}
 
 	for {
 		select {
@@ -69,33 +93,63 @@ func (w *Client) reconnect() bool {
 			continue
 		}
 
-		if w.conn != nil {
-			_ = w.conn.Close()
+		conn.SetReadLimit(2 << 20)
+		if !w.swapConn(conn) {
+			return false
 		}
+		w.startHeartbeat(conn)
 
-		w.conn = conn
-		w.conn.SetReadLimit(2 << 20)
+		go w.restore(conn)
+		return true
+	}
+}
 
-		if w.apiKey != "" && w.secret != "" {
-			if err := w.authenticate(); err != nil {
-				w.logEntry().WithError(err).Warn("Не удалось повторно авторизоваться в WS.")
-				backoff = w.nextBackoff(backoff)
-				continue
-			}
-		}
+// swapConn заменяет соединение новым и закрывает старое.
+// После Close новое соединение закрывается сразу, чтобы не осталось открытым.
+func (w *Client) swapConn(conn *websocket.Conn) bool {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+
+	select {
+	case <-w.stopCh:
+		_ = conn.Close()
+		return false
+	default:
+	}
 
-		if w.symbol != "" {
-			if err := w.SubscribeToTopics(context.Background(), w.symbol, w.topics); err != nil {
-				w.logEntry().WithError(err).Warn("Не удалось повторно подписаться на WS.")
-				backoff = w.nextBackoff(backoff)
-				continue
-			}
+	if w.conn != nil {
+		_ = w.conn.Close()
+	}
+	w.conn = conn
+	return true
+}
+
+// restore авторизуется и восстанавливает подписки на новом соединении, пока readLoop читает ответы.
+// При ошибке соединение закрывается, и readLoop уходит на следующее переподключение.
+func (w *Client) restore(conn *websocket.Conn) {
+	ctx := context.Background()
+
+	if w.apiKey != "" && w.signer != nil {
+		if err := w.authenticate(ctx, conn); err != nil {
+			w.logEntry().WithError(err).Warn("Не удалось повторно авторизоваться в WS.")
+			w.failures.Add(1)
+			_ = conn.Close()
+			return
 		}
+	}
 
-		w.events <- exchange.Event{Type: exchange.EventTypeReconnect}
-		w.logEntry().Info("WS переподключён и подписки восстановлены.")
-		return true
+	if symbol, topics := w.subscription(); symbol != "" && len(topics) > 0 {
+		if err := w.subscribe(ctx, conn); err != nil {
+			w.logEntry().WithError(err).Warn("Не удалось повторно подписаться на WS.")
+			w.failures.Add(1)
+			_ = conn.Close()
+			return
+		}
 	}
+
+	w.failures.Store(0)
+	w.events <- exchange.Event{Type: exchange.EventTypeReconnect}
+	w.logEntry().Info("WS переподключён и подписки восстановлены.")
 }
 
 func (w *Client) nextBackoff(current time.Duration) time.Duration {
@@ -0,0 +1,75 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const ackTimeout = 10 * time.Second
+
+func (w *Client) request(ctx context.Context, conn *websocket.Conn, op string, args []string) error {
+	reqID := op + "-" + strconv.FormatInt(w.reqSeq.Add(1), 10)
+	resp := make(chan Message, 1)
+
+	w.pendingMu.Lock()
+	if w.pending == nil {
+		w.pending = make(map[string]pendingRequest)
+	}
+	w.pending[reqID] = pendingRequest{op: op, resp: resp}
+	w.pendingMu.Unlock()
+
+	defer func() {
+		w.pendingMu.Lock()
+		delete(w.pending, reqID)
+		w.pendingMu.Unlock()
+	}()
+
+	if err := w.write(conn, RequestMessage{Op: op, ReqID: reqID, Args: args}); err != nil {
+		return fmt.Errorf("Не удалось отправить %s в WS: %w", op, err)
+	}
+
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-resp:
+		if msg.Success != nil && !*msg.Success {
+			return fmt.Errorf("WS отклонил %s: %s", op, msg.RetMsg)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("Нет ответа WS на %s за %s.", op, ackTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.stopCh:
+		return errors.New("WS закрыт.")
+	}
+}
+
+// resolve передаёт ответ ожидающему запросу: по req_id, а если биржа его не вернула - по op.
+func (w *Client) resolve(msg Message) bool {
+	w.pendingMu.Lock()
+	defer w.pendingMu.Unlock()
+
+	req, ok := w.pending[msg.ReqID]
+	if !ok {
+		for id, candidate := range w.pending {
+			if candidate.op == msg.Op {
+				req, ok = candidate, true
+				msg.ReqID = id
+				break
+			}
+		}
+	}
+	if !ok {
+		return false
+	}
+	delete(w.pending, msg.ReqID)
+	req.resp <- msg
+	return true
+}
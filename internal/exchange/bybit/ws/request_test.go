@@ -0,0 +1,160 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/bybit/sign"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveCorrelatesAcks(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		want    string
+		handled bool
+	}{
+		{name: "by req_id", msg: Message{Op: "subscribe", ReqID: "subscribe-2"}, want: "subscribe-2", handled: true},
+		{name: "req_id wins over op", msg: Message{Op: "auth", ReqID: "subscribe-1"}, want: "subscribe-1", handled: true},
+		{name: "by op without req_id", msg: Message{Op: "auth"}, want: "auth-3", handled: true},
+		{name: "unknown req_id falls back to op", msg: Message{Op: "auth", ReqID: "auth-99"}, want: "auth-3", handled: true},
+		{name: "no pending request", msg: Message{Op: "unsubscribe", ReqID: "unsubscribe-4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Client{pending: map[string]pendingRequest{}}
+			resps := map[string]chan Message{}
+			for _, id := range []string{"subscribe-1", "subscribe-2", "auth-3"} {
+				resps[id] = make(chan Message, 1)
+				w.pending[id] = pendingRequest{op: strings.Split(id, "-")[0], resp: resps[id]}
+			}
+
+			if handled := w.resolve(tt.msg); handled != tt.handled {
+				t.Fatalf("resolve = %v, ожидалось %v", handled, tt.handled)
+			}
+			for id, resp := range resps {
+				select {
+				case msg := <-resp:
+					if id != tt.want {
+						t.Fatalf("ответ доставлен %s, ожидался %q", id, tt.want)
+					}
+					if msg.ReqID != id {
+						t.Fatalf("req_id ответа %q, ожидался %q", msg.ReqID, id)
+					}
+					if _, ok := w.pending[id]; ok {
+						t.Fatalf("запрос %s остался ожидающим", id)
+					}
+				default:
+					if id == tt.want {
+						t.Fatalf("ответ не доставлен %s", id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRequestRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		reject  string
+		wantErr string
+	}{
+		{name: "accepted"},
+		{name: "rejected", reject: "topic not found", wantErr: "WS отклонил subscribe: topic not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubWS(t)
+			stub.setReject("subscribe", tt.reject)
+			w := newTestClient(t, stub)
+			if err := w.Connect(context.Background()); err != nil {
+				t.Fatalf("Connect: %v", err)
+			}
+
+			err := w.SubscribeToTopics(context.Background(), "BTCUSDT", []string{"tickers.BTCUSDT"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("SubscribeToTopics: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("SubscribeToTopics error = %v, ожидалось %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthFailure(t *testing.T) {
+	const apiKey = "ws-test-api-key"
+	signer := sign.NewHMAC("ws-test-secret")
+
+	tests := []struct {
+		name    string
+		reject  string
+		wantErr string
+	}{
+		{name: "accepted"},
+		{name: "rejected", reject: "Invalid apikey", wantErr: "Не удалось авторизоваться: WS отклонил auth: Invalid apikey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubWS(t)
+			stub.setReject("auth", tt.reject)
+			w := newSignedTestClient(t, stub, apiKey, signer)
+
+			err := w.Connect(context.Background())
+			_, _, _, requests := stub.stats()
+			if len(requests) != 1 || requests[0].Op != "auth" || len(requests[0].Args) != 3 {
+				t.Fatalf("запросы %+v, ожидался один auth", requests)
+			}
+			args := requests[0].Args
+			want, _ := signer.Sign("GET/realtime" + args[1])
+			if args[0] != apiKey || args[2] != want {
+				t.Fatalf("auth args %v, ожидались ключ %s и подпись %s", args, apiKey, want)
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Connect: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Connect error = %v, ожидалось %q", err, tt.wantErr)
+			}
+			select {
+			case <-w.stopCh:
+			default:
+				t.Fatal("клиент не закрыт после отказа в авторизации")
+			}
+		})
+	}
+}
+
+func TestReauthFailureRetriesReconnect(t *testing.T) {
+	stub := newStubWS(t)
+	w := newSignedTestClient(t, stub, "ws-test-api-key", sign.NewHMAC("ws-test-secret"))
+	if err := w.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	stub.waitConns(1)
+
+	// Отказ в авторизации после переподключения не считается восстановлением соединения.
+	stub.setReject("auth", "Invalid apikey")
+	stub.dropAll()
+	stub.waitConns(2)
+	select {
+	case event := <-w.Events():
+		if event.Type == exchange.EventTypeReconnect {
+			t.Fatal("событие Reconnect без авторизации")
+		}
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stub.setReject("auth", "")
+	waitEvent(t, w, exchange.EventTypeReconnect)
+	if w.failures.Load() != 0 {
+		t.Fatalf("счётчик неудач %d после восстановления", w.failures.Load())
+	}
+}
@@ -0,0 +1,34 @@
+package ws
+
+import "dcabot/internal/models"
+
+func ToOrderStatus(status string) models.OrderStatus {
+	switch status {
+	case "New", "Untriggered", "Triggered":
+		return models.OrderStatusNew
+	case "PartiallyFilled":
+		return models.OrderStatusPartiallyFilled
+	case "Filled":
+		return models.OrderStatusFilled
+	case "Cancelled", "PartiallyFilledCanceled", "Deactivated":
+		return models.OrderStatusCanceled
+	case "Rejected":
+		return models.OrderStatusRejected
+	default:
+		return models.OrderStatus(status)
+	}
+}
+
+func ToRejectReason(reason string) string {
+	if reason == "EC_NoError" {
+		return ""
+	}
+	return reason
+}
+
+func ToCancelType(cancelType string) string {
+	if cancelType == "UNKNOWN" {
+		return ""
+	}
+	return cancelType
+}
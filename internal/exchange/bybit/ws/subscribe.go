@@ -2,16 +2,33 @@ package ws
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/gorilla/websocket"
 )
 
 func (w *Client) SubscribeToTopics(ctx context.Context, symbol string, topics []string) error {
+	w.connMu.Lock()
 	w.symbol = symbol
 	w.topics = topics
+	conn := w.conn
+	w.connMu.Unlock()
+
+	return w.subscribe(ctx, conn)
+}
+
+func (w *Client) subscription() (string, []string) {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	return w.symbol, w.topics
+}
 
-	msg := SubscribeMessage{
-		Op:   "subscribe",
-		Args: topics,
+func (w *Client) subscribe(ctx context.Context, conn *websocket.Conn) error {
+	_, topics := w.subscription()
+	if err := w.request(ctx, conn, "subscribe", topics); err != nil {
+		return fmt.Errorf("Не удалось подписаться на %v: %w", topics, err)
 	}
 
-	return w.conn.WriteJSON(msg)
+	w.logEntry().WithField("topics", topics).Info("WS подписка подтверждена.")
+	return nil
 }
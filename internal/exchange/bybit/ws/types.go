@@ -2,9 +2,12 @@ package ws
 
 import (
 	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/bybit/clock"
+	"dcabot/internal/exchange/bybit/sign"
 	"dcabot/internal/logger"
 	"encoding/json"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -13,31 +16,57 @@ import (
 type Client struct {
 	url          string
 	apiKey       string
-	secret       string
+	signer       sign.Signer
+	clock        *clock.Clock
 	log          *logger.Logger
-	conn         *websocket.Conn
 	events       chan exchange.Event
 	stopCh       chan struct{}
 	stopOnce     sync.Once
-	symbol       string
-	topics       []string
 	reconnectMin time.Duration
 	reconnectMax time.Duration
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	writeMu      sync.Mutex
+	lastPong     atomic.Int64
+	pendingMu    sync.Mutex
+	pending      map[string]pendingRequest
+	reqSeq       atomic.Int64
+	failures     atomic.Int32
+
+	// heartbeatStop останавливает heartbeat текущего соединения при переподключении.
+	heartbeatStop chan struct{}
+
+	// connMu защищает conn и подписку: readLoop меняет соединение при переподключении,
+	// Close и SubscribeToTopics вызываются из других горутин.
+	connMu sync.Mutex
+	conn   *websocket.Conn
+	symbol string
+	topics []string
+}
+
+type pendingRequest struct {
+	op   string
+	resp chan Message
 }
 
 type Message struct {
-	Topic string          `json:"topic"`
-	Type  string          `json:"type"`
-	TS    int64           `json:"ts"`
-	Data  json.RawMessage `json:"data"`
+	Op      string          `json:"op"`
+	ReqID   string          `json:"req_id"`
+	Success *bool           `json:"success"`
+	RetMsg  string          `json:"ret_msg"`
+	Topic   string          `json:"topic"`
+	Type    string          `json:"type"`
+	TS      int64           `json:"ts"`
+	Data    json.RawMessage `json:"data"`
 }
 
-type AuthMessage struct {
-	Op   string   `json:"op"`
-	Args []string `json:"args"`
+type PingMessage struct {
+	Op    string `json:"op"`
+	ReqID string `json:"req_id,omitempty"`
 }
 
-type SubscribeMessage struct {
-	Op   string   `json:"op"`
-	Args []string `json:"args"`
+type RequestMessage struct {
+	Op    string   `json:"op"`
+	ReqID string   `json:"req_id,omitempty"`
+	Args  []string `json:"args"`
 }
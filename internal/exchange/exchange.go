@@ -3,6 +3,8 @@ package exchange
 import (
 	"context"
 	"dcabot/internal/models"
+	"errors"
+	"time"
 )
 
 type EventType string
@@ -30,14 +32,62 @@ type InstrumentRules struct {
 	QuoteCoin   string
 }
 
+const (
+	FeeCurrencyReceived = "received"
+	FeeCurrencyQuote    = "quote"
+)
+
+var ErrNotSupported = errors.New("Операция не поддерживается биржей.")
+
+// ErrConvertUnavailable - биржа не конвертирует монету или объём меньше минимального.
+var ErrConvertUnavailable = errors.New("Конвертация недоступна для этого объёма.")
+
+type Capabilities struct {
+	Amend          bool
+	BatchOrders    bool
+	PostOnly       bool
+	ReduceOnlySpot bool
+	FeeCurrency    string
+
+	Convert bool
+}
+
+type FillQuery struct {
+	Symbol string
+	Since  time.Time
+	Until  time.Time
+	LinkID string
+	Limit  int
+}
+
+type OrderQuery struct {
+	Symbol string
+	Since  time.Time
+	Until  time.Time
+	LinkID string
+	Limit  int
+}
+
+type ConvertResult struct {
+	ID         string
+	Status     string
+	FromAmount float64
+	ToAmount   float64
+}
+
 type Client interface {
+	Capabilities() Capabilities
 	GetInstrumentRules(ctx context.Context, symbol string) (InstrumentRules, error)
 	Subscribe(ctx context.Context, symbol string) (<-chan Event, error)
 	CancelOrder(ctx context.Context, symbol, orderID string) error
 	GetOpenOrders(ctx context.Context, symbol string) ([]models.Order, error)
 	PlaceOrder(ctx context.Context, order models.Order) (models.Order, error)
-	GetFills(ctx context.Context, symbol string) ([]models.Fill, error)
+	AmendOrder(ctx context.Context, order models.Order) (models.Order, error)
+	GetFills(ctx context.Context, query FillQuery) ([]models.Fill, error)
+	GetOrderHistory(ctx context.Context, query OrderQuery) ([]models.Order, error)
 	GetBalances(ctx context.Context, coins []string) (map[string]Balance, error)
+	GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error)
+	Convert(ctx context.Context, fromCoin, toCoin string, amount float64) (ConvertResult, error)
 }
 
 type Balance struct {
@@ -0,0 +1,181 @@
+package okx
+
+import (
+	"context"
+	"dcabot/internal/config"
+	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/okx/rest"
+	"dcabot/internal/exchange/okx/ws"
+	"dcabot/internal/logger"
+	"dcabot/internal/models"
+	"errors"
+	"fmt"
+)
+
+type Client struct {
+	rest        *rest.Client
+	wsPublic    *ws.Client
+	wsPrivate   *ws.Client
+	wsConnected bool
+	log         *logger.Logger
+}
+
+func New(baseURL, wsPublicURL, wsPrivateURL, apiKey, secret, passphrase string, log *logger.Logger) *Client {
+	return &Client{
+		rest:      rest.New(baseURL, apiKey, secret, passphrase, log),
+		wsPublic:  ws.New(wsPublicURL, "", "", "", log),
+		wsPrivate: ws.New(wsPrivateURL, apiKey, secret, passphrase, log),
+		log:       log,
+	}
+}
+
+func init() {
+	exchange.Register("okx", func(cfg config.ExchangeConfig, log *logger.Logger) (exchange.Client, error) {
+		return New(cfg.BaseUrl, cfg.WSPublicURL, cfg.WSPrivateURL, cfg.ApiKey, cfg.Secret, cfg.Passphrase, log), nil
+	})
+}
+
+func (c *Client) Capabilities() exchange.Capabilities {
+	return exchange.Capabilities{
+		Amend:       true,
+		BatchOrders: true,
+		PostOnly:    true,
+		FeeCurrency: exchange.FeeCurrencyReceived,
+	}
+}
+
+func (c *Client) GetInstrumentRules(ctx context.Context, symbol string) (exchange.InstrumentRules, error) {
+	return c.rest.GetInstrumentRules(ctx, instID(symbol))
+}
+
+func (c *Client) Subscribe(ctx context.Context, symbol string) (<-chan exchange.Event, error) {
+	c.log.WithSymbol(symbol).WithField("component", "okx").Info("Подписываемся на торговую пару.")
+
+	if c.wsConnected {
+		return nil, errors.New("Подписка уже создана.")
+	}
+
+	inst := instID(symbol)
+
+	if err := c.wsPublic.Connect(ctx, []ws.Arg{{Channel: "tickers", InstID: inst}}); err != nil {
+		return nil, err
+	}
+
+	if err := c.wsPrivate.Connect(ctx, []ws.Arg{{Channel: "orders", InstType: "SPOT", InstID: inst}}); err != nil {
+		return nil, err
+	}
+
+	merged := make(chan exchange.Event, 200)
+
+	c.log.WithSymbol(symbol).WithField("component", "okx").Debug("Запуск forwardEvents.")
+	go forwardEvents(c.wsPublic.Events(), merged, symbol)
+	go forwardEvents(c.wsPrivate.Events(), merged, symbol)
+	go func() {
+		<-ctx.Done()
+		c.wsPublic.Close()
+		c.wsPrivate.Close()
+	}()
+
+	c.wsConnected = true
+
+	c.log.WithSymbol(symbol).WithField("component", "okx").Info("Подписки активированы.")
+
+	return merged, nil
+}
+
+func (c *Client) CancelOrder(ctx context.Context, symbol, orderID string) error {
+	return c.rest.CancelOrder(ctx, instID(symbol), orderID)
+}
+
+func (c *Client) GetOpenOrders(ctx context.Context, symbol string) ([]models.Order, error) {
+	orders, err := c.rest.GetOpenOrders(ctx, instID(symbol))
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Symbol = symbol
+		orders[i].LinkID = decodeLinkID(orders[i].LinkID)
+	}
+	return orders, nil
+}
+
+func (c *Client) PlaceOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	linkID := order.LinkID
+	clOrdID := encodeLinkID(linkID)
+	if len(clOrdID) > maxClOrdIDLen {
+		return models.Order{}, fmt.Errorf("Слишком длинный clOrdId для okx: %s", clOrdID)
+	}
+	order.LinkID = clOrdID
+
+	placed, err := c.rest.PlaceOrder(ctx, instID(order.Symbol), order)
+	if err != nil {
+		return models.Order{}, err
+	}
+	placed.LinkID = linkID
+	return placed, nil
+}
+
+func (c *Client) AmendOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	linkID := order.LinkID
+	amended, err := c.rest.AmendOrder(ctx, instID(order.Symbol), order)
+	if err != nil {
+		return models.Order{}, err
+	}
+	amended.LinkID = linkID
+	return amended, nil
+}
+
+func (c *Client) GetFills(ctx context.Context, query exchange.FillQuery) ([]models.Fill, error) {
+	query.LinkID = encodeLinkID(query.LinkID)
+	fills, err := c.rest.GetFills(ctx, instID(query.Symbol), query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fills {
+		fills[i].Symbol = query.Symbol
+		fills[i].LinkID = decodeLinkID(fills[i].LinkID)
+	}
+	return fills, nil
+}
+
+func (c *Client) GetOrderHistory(ctx context.Context, query exchange.OrderQuery) ([]models.Order, error) {
+	query.LinkID = encodeLinkID(query.LinkID)
+	orders, err := c.rest.GetOrderHistory(ctx, instID(query.Symbol), query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Symbol = query.Symbol
+		orders[i].LinkID = decodeLinkID(orders[i].LinkID)
+	}
+	return orders, nil
+}
+
+func (c *Client) GetBalances(ctx context.Context, coins []string) (map[string]exchange.Balance, error) {
+	return c.rest.GetBalances(ctx, coins)
+}
+
+func (c *Client) GetKlines(ctx context.Context, symbol, interval string, limit int) ([]models.Kline, error) {
+	return c.rest.GetKlines(ctx, instID(symbol), interval, limit)
+}
+
+func (c *Client) Convert(ctx context.Context, fromCoin, toCoin string, amount float64) (exchange.ConvertResult, error) {
+	return exchange.ConvertResult{}, exchange.ErrNotSupported
+}
+
+func forwardEvents(src <-chan exchange.Event, dst chan<- exchange.Event, symbol string) {
+	for event := range src {
+		if event.Order != nil {
+			event.Order.Symbol = symbol
+			event.Order.LinkID = decodeLinkID(event.Order.LinkID)
+		}
+		if event.Fill != nil {
+			event.Fill.Symbol = symbol
+			event.Fill.LinkID = decodeLinkID(event.Fill.LinkID)
+		}
+		if event.Ticker != nil {
+			event.Ticker.Symbol = symbol
+		}
+		dst <- event
+	}
+}
@@ -0,0 +1,81 @@
+package okx
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	linkIDMarker    = "D"
+	linkIDSeparator = "X"
+	linkIDTimeToken = "T"
+	maxClOrdIDLen   = 32
+)
+
+// encodeLinkID сжимает числовые токены (метки времени) в base36. Токен с ведущим нулём
+// (например, id сделки из одних цифр) остаётся как есть: base36 его не восстановит.
+// Маркер в начале отличает clOrdId бота от чужих ордеров на том же аккаунте.
+func encodeLinkID(linkID string) string {
+	if linkID == "" {
+		return ""
+	}
+	tokens := strings.Split(linkID, "-")
+	for i, token := range tokens {
+		if len(token) < 8 || !isDigits(token) {
+			continue
+		}
+		value, err := strconv.ParseInt(token, 10, 64)
+		if err != nil || strconv.FormatInt(value, 10) != token {
+			continue
+		}
+		tokens[i] = linkIDTimeToken + strconv.FormatInt(value, 36)
+	}
+	return linkIDMarker + strings.Join(tokens, linkIDSeparator)
+}
+
+// decodeLinkID восстанавливает link id бота. clOrdId без маркера или не совпадающий
+// с собственной кодировкой принадлежит чужому ордеру и возвращается как есть.
+func decodeLinkID(clOrdID string) string {
+	body, ok := strings.CutPrefix(clOrdID, linkIDMarker)
+	if !ok || !strings.Contains(body, linkIDSeparator) {
+		return clOrdID
+	}
+	tokens := strings.Split(body, linkIDSeparator)
+	for i, token := range tokens {
+		if !strings.HasPrefix(token, linkIDTimeToken) {
+			continue
+		}
+		value, err := strconv.ParseInt(strings.TrimPrefix(token, linkIDTimeToken), 36, 64)
+		if err != nil {
+			return clOrdID
+		}
+		tokens[i] = strconv.FormatInt(value, 10)
+	}
+	linkID := strings.Join(tokens, "-")
+	if encodeLinkID(linkID) != clOrdID {
+		return clOrdID
+	}
+	return linkID
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func instID(symbol string) string {
+	if strings.Contains(symbol, "-") {
+		return strings.ToUpper(symbol)
+	}
+	upper := strings.ToUpper(symbol)
+	for _, quote := range []string{"USDT", "USDC", "BTC", "ETH", "EUR", "USD", "OKB"} {
+		if strings.HasSuffix(upper, quote) && len(upper) > len(quote) {
+			return strings.TrimSuffix(upper, quote) + "-" + quote
+		}
+	}
+	return upper
+}
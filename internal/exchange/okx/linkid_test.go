@@ -0,0 +1,72 @@
+package okx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLinkIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		linkID string
+		maxLen bool
+	}{
+		{name: "entry", linkID: "a1b2c3d4e5f6-entry"},
+		{name: "tp", linkID: "a1b2c3d4e5f6-tp-1700000000-123"},
+		{name: "anchored safety order", linkID: "a1b2c3d4e5f6-so-12-a3"},
+		{name: "accumulate", linkID: "a1b2c3d4e5f6-acc-1700000000"},
+		{name: "grid at max length", linkID: "a1b2c3d4e5f6-gb-999-1700000000-999", maxLen: true},
+		{name: "eight digit token", linkID: "a1b2c3d4e5f6-12345678"},
+		{name: "digits deal id", linkID: "123456789012-tp-1700000000-1"},
+		{name: "leading zero token", linkID: "012345678901-tp-1700000000-1"},
+		{name: "token beyond int64", linkID: "a1b2-99999999999999999999"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clOrdID := encodeLinkID(tt.linkID)
+			if strings.Contains(clOrdID, "-") {
+				t.Fatalf("clOrdId %q содержит недопустимый символ", clOrdID)
+			}
+			if len(clOrdID) > maxClOrdIDLen {
+				t.Fatalf("clOrdId %q длиннее %d", clOrdID, maxClOrdIDLen)
+			}
+			if tt.maxLen && len(clOrdID) != maxClOrdIDLen {
+				t.Fatalf("длина clOrdId %q = %d, ожидалось %d", clOrdID, len(clOrdID), maxClOrdIDLen)
+			}
+			if got := decodeLinkID(clOrdID); got != tt.linkID {
+				t.Fatalf("decode(%q) = %q, ожидалось %q", clOrdID, got, tt.linkID)
+			}
+		})
+	}
+}
+
+func TestLinkIDTooLong(t *testing.T) {
+	clOrdID := encodeLinkID("a1b2c3d4e5f6-gb-1000-1700000000-999")
+	if len(clOrdID) <= maxClOrdIDLen {
+		t.Fatalf("clOrdId %q должен превышать %d символов", clOrdID, maxClOrdIDLen)
+	}
+}
+
+func TestDecodeForeignClOrdID(t *testing.T) {
+	tests := []struct {
+		name    string
+		clOrdID string
+	}{
+		{name: "empty", clOrdID: ""},
+		{name: "plain", clOrdID: "manualorder1"},
+		{name: "digits", clOrdID: "12345678"},
+		{name: "separator without marker", clOrdID: "abcXT1z"},
+		{name: "marker without separator", clOrdID: "Dabc"},
+		{name: "upper case time token", clOrdID: "DabcXTZZZZZZ"},
+		{name: "short time token", clOrdID: "DabcXT1"},
+		{name: "padded time token", clOrdID: "DabcXT0sbnq8w"},
+		{name: "invalid time token", clOrdID: "DabcXTpp!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeLinkID(tt.clOrdID); got != tt.clOrdID {
+				t.Fatalf("decode(%q) = %q, ожидался исходный clOrdId", tt.clOrdID, got)
+			}
+		})
+	}
+}
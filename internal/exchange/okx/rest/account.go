@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (c *Client) GetBalances(ctx context.Context, coins []string) (map[string]exchange.Balance, error) {
+	params := url.Values{}
+	if len(coins) > 0 {
+		params.Set("ccy", strings.Join(coins, ","))
+	}
+
+	var resp okxResponse[struct {
+		Details []struct {
+			Ccy      string `json:"ccy"`
+			CashBal  string `json:"cashBal"`
+			AvailBal string `json:"availBal"`
+		} `json:"details"`
+	}]
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v5/account/balance", params, nil, true, &resp); err != nil {
+		return nil, err
+	}
+
+	balances := map[string]exchange.Balance{}
+	for _, account := range resp.Data {
+		for _, item := range account.Details {
+			wallet, _ := parseFloatOrZero(item.CashBal)
+			available, _ := parseFloatOrZero(item.AvailBal)
+			if available == 0 {
+				available = wallet
+			}
+
+			balances[item.Ccy] = exchange.Balance{
+				Coin:      item.Ccy,
+				Wallet:    wallet,
+				Available: available,
+			}
+		}
+	}
+	return balances, nil
+}
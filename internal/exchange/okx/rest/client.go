@@ -0,0 +1,20 @@
+package rest
+
+import (
+	"dcabot/internal/logger"
+	"net/http"
+	"time"
+)
+
+func New(baseURL, apiKey, secret, passphrase string, log *logger.Logger) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		secret:     secret,
+		passphrase: passphrase,
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		log: log,
+	}
+}
@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const historyLimit = 100
+
+// errOrderNotFound - ордера с таким clOrdId нет: запрос по link id ещё не выставленного
+// ордера возвращает пустой результат, а не ошибку.
+var errOrderNotFound = errors.New("Ордер okx не найден.")
+
+func historyParams(instID string, since, until int64) url.Values {
+	params := url.Values{}
+	params.Set("instType", "SPOT")
+	params.Set("instId", instID)
+	params.Set("limit", strconv.Itoa(historyLimit))
+	if since > 0 {
+		params.Set("begin", strconv.FormatInt(since, 10))
+	}
+	if until > 0 {
+		params.Set("end", strconv.FormatInt(until, 10))
+	}
+	return params
+}
+
+// GetFills читает fills-history (до 3 месяцев) постранично через after=billId.
+// query.LinkID должен быть уже закодирован в clOrdId.
+func (c *Client) GetFills(ctx context.Context, instID string, query exchange.FillQuery) ([]models.Fill, error) {
+	params := historyParams(instID, unixMilli(query.Since), unixMilli(query.Until))
+	if query.LinkID != "" {
+		order, err := c.orderByClOrdID(ctx, instID, query.LinkID)
+		if errors.Is(err, errOrderNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		params.Set("ordId", order.OrdID)
+	}
+
+	var items []fillInfo
+	for {
+		var resp okxResponse[fillInfo]
+		if err := c.doRequest(ctx, http.MethodGet, "/api/v5/trade/fills-history", params, nil, true, &resp); err != nil {
+			return nil, err
+		}
+		items = append(items, resp.Data...)
+		if query.Limit > 0 && len(items) >= query.Limit {
+			items = items[:query.Limit]
+			break
+		}
+		if len(resp.Data) < historyLimit {
+			break
+		}
+		params.Set("after", resp.Data[len(resp.Data)-1].BillID)
+	}
+
+	return toFills(items), nil
+}
+
+func (c *Client) GetOrderHistory(ctx context.Context, instID string, query exchange.OrderQuery) ([]models.Order, error) {
+	if query.LinkID != "" {
+		order, err := c.orderByClOrdID(ctx, instID, query.LinkID)
+		if errors.Is(err, errOrderNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []models.Order{ToOrder(order)}, nil
+	}
+
+	params := historyParams(instID, unixMilli(query.Since), unixMilli(query.Until))
+
+	var orders []models.Order
+	for {
+		var resp okxResponse[OrderInfo]
+		if err := c.doRequest(ctx, http.MethodGet, "/api/v5/trade/orders-history-archive", params, nil, true, &resp); err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Data {
+			orders = append(orders, ToOrder(item))
+		}
+		if query.Limit > 0 && len(orders) >= query.Limit {
+			orders = orders[:query.Limit]
+			break
+		}
+		if len(resp.Data) < historyLimit {
+			break
+		}
+		params.Set("after", resp.Data[len(resp.Data)-1].OrdID)
+	}
+	return orders, nil
+}
+
+func (c *Client) orderByClOrdID(ctx context.Context, instID, clOrdID string) (OrderInfo, error) {
+	params := url.Values{}
+	params.Set("instId", instID)
+	params.Set("clOrdId", clOrdID)
+
+	var resp okxResponse[OrderInfo]
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v5/trade/order", params, nil, true, &resp); err != nil {
+		if strings.Contains(err.Error(), "code=51603") {
+			return OrderInfo{}, errOrderNotFound
+		}
+		return OrderInfo{}, err
+	}
+	if len(resp.Data) == 0 {
+		return OrderInfo{}, errOrderNotFound
+	}
+	return resp.Data[0], nil
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
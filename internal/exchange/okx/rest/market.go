@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) GetInstrumentRules(ctx context.Context, instID string) (exchange.InstrumentRules, error) {
+	params := url.Values{}
+	params.Set("instType", "SPOT")
+	params.Set("instId", instID)
+
+	var resp okxResponse[struct {
+		InstID   string `json:"instId"`
+		BaseCcy  string `json:"baseCcy"`
+		QuoteCcy string `json:"quoteCcy"`
+		TickSz   string `json:"tickSz"`
+		LotSz    string `json:"lotSz"`
+		MinSz    string `json:"minSz"`
+	}]
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v5/public/instruments", params, nil, false, &resp); err != nil {
+		return exchange.InstrumentRules{}, err
+	}
+
+	if len(resp.Data) == 0 {
+		return exchange.InstrumentRules{}, fmt.Errorf("Торговая пара не найдена: %s", instID)
+	}
+
+	info := resp.Data[0]
+
+	tick, err := strconv.ParseFloat(info.TickSz, 64)
+	if err != nil {
+		return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение tickSz=%q: %w", info.TickSz, err)
+	}
+
+	lot, err := parseFloatOrZero(info.LotSz)
+	if err != nil {
+		return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение lotSz=%q: %w", info.LotSz, err)
+	}
+
+	if lot == 0 {
+		return exchange.InstrumentRules{}, fmt.Errorf("Не удалось определить lot size для торговой пары: %s", instID)
+	}
+
+	minQty, err := parseFloatOrZero(info.MinSz)
+	if err != nil {
+		return exchange.InstrumentRules{}, fmt.Errorf("Некорректное значение minSz=%q: %w", info.MinSz, err)
+	}
+
+	return exchange.InstrumentRules{
+		TickSize:  tick,
+		LotSize:   lot,
+		MinQty:    minQty,
+		BaseCoin:  info.BaseCcy,
+		QuoteCoin: info.QuoteCcy,
+	}, nil
+}
+
+func (c *Client) GetKlines(ctx context.Context, instID, interval string, limit int) ([]models.Kline, error) {
+	params := url.Values{}
+	params.Set("instId", instID)
+	params.Set("bar", candleBar(interval))
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var resp okxResponse[[]string]
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v5/market/candles", params, nil, false, &resp); err != nil {
+		return nil, err
+	}
+
+	klines := make([]models.Kline, 0, len(resp.Data))
+	for _, item := range resp.Data {
+		if len(item) < 6 {
+			continue
+		}
+		startMs, _ := strconv.ParseInt(item[0], 10, 64)
+		open, _ := strconv.ParseFloat(item[1], 64)
+		high, _ := strconv.ParseFloat(item[2], 64)
+		low, _ := strconv.ParseFloat(item[3], 64)
+		closePrice, _ := strconv.ParseFloat(item[4], 64)
+		volume, _ := strconv.ParseFloat(item[5], 64)
+
+		klines = append(klines, models.Kline{
+			Start:  time.UnixMilli(startMs),
+			Open:   open,
+			High:   high,
+			Low:    low,
+			Close:  closePrice,
+			Volume: volume,
+		})
+	}
+
+	sort.Slice(klines, func(i, j int) bool {
+		return klines[i].Start.Before(klines[j].Start)
+	})
+	return klines, nil
+}
+
+func candleBar(interval string) string {
+	switch strings.ToUpper(strings.TrimSpace(interval)) {
+	case "1", "3", "5", "15", "30":
+		return interval + "m"
+	case "60":
+		return "1H"
+	case "120":
+		return "2H"
+	case "240":
+		return "4H"
+	case "360":
+		return "6Hutc"
+	case "720":
+		return "12Hutc"
+	case "D":
+		return "1Dutc"
+	case "W":
+		return "1Wutc"
+	case "M":
+		return "1Mutc"
+	default:
+		return interval
+	}
+}
@@ -0,0 +1,171 @@
+package rest
+
+import (
+	"context"
+	"dcabot/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Client) PlaceOrder(ctx context.Context, instID string, order models.Order) (models.Order, error) {
+	body := map[string]any{
+		"instId":  instID,
+		"tdMode":  "cash",
+		"side":    strings.ToLower(string(order.Side)),
+		"ordType": strings.ToLower(string(order.Type)),
+		"sz":      formatWithStep(order.Qty, order.QtyStep),
+		"px":      formatWithStep(order.Price, order.PriceStep),
+		"clOrdId": order.LinkID,
+	}
+
+	if order.Type == models.OrderTypeMarket {
+		delete(body, "px")
+		if strings.EqualFold(order.MarketUnit, "quoteCoin") {
+			body["tgtCcy"] = "quote_ccy"
+			body["sz"] = formatWithStep(order.Qty, 0)
+		} else {
+			body["tgtCcy"] = "base_ccy"
+		}
+	} else if strings.EqualFold(order.TimeInForce, "IOC") {
+		body["ordType"] = "ioc"
+	}
+
+	var resp okxResponse[orderAck]
+
+	if err := c.doRequest(ctx, http.MethodPost, "/api/v5/trade/order", nil, body, true, &resp); err != nil {
+		return models.Order{}, err
+	}
+
+	if len(resp.Data) > 0 {
+		order.ID = resp.Data[0].OrdID
+	}
+	return order, nil
+}
+
+func (c *Client) AmendOrder(ctx context.Context, instID string, order models.Order) (models.Order, error) {
+	body := map[string]any{
+		"instId": instID,
+		"ordId":  order.ID,
+		"newSz":  formatWithStep(order.Qty, order.QtyStep),
+		"newPx":  formatWithStep(order.Price, order.PriceStep),
+	}
+
+	var resp okxResponse[orderAck]
+
+	if err := c.doRequest(ctx, http.MethodPost, "/api/v5/trade/amend-order", nil, body, true, &resp); err != nil {
+		return models.Order{}, err
+	}
+
+	if len(resp.Data) > 0 && resp.Data[0].OrdID != "" {
+		order.ID = resp.Data[0].OrdID
+	}
+	return order, nil
+}
+
+func (c *Client) CancelOrder(ctx context.Context, instID, orderID string) error {
+	body := map[string]any{
+		"instId": instID,
+		"ordId":  orderID,
+	}
+
+	var resp okxResponse[orderAck]
+
+	return c.doRequest(ctx, http.MethodPost, "/api/v5/trade/cancel-order", nil, body, true, &resp)
+}
+
+func (c *Client) GetOpenOrders(ctx context.Context, instID string) ([]models.Order, error) {
+	params := url.Values{}
+	params.Set("instType", "SPOT")
+	params.Set("instId", instID)
+
+	var resp okxResponse[OrderInfo]
+
+	if err := c.doRequest(ctx, http.MethodGet, "/api/v5/trade/orders-pending", params, nil, true, &resp); err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, item := range resp.Data {
+		orders = append(orders, ToOrder(item))
+	}
+	return orders, nil
+}
+
+func toFills(items []fillInfo) []models.Fill {
+	var fills []models.Fill
+	for _, item := range items {
+		price, _ := strconv.ParseFloat(item.FillPx, 64)
+		qty, _ := strconv.ParseFloat(item.FillSz, 64)
+		tsMs, _ := strconv.ParseInt(item.TS, 10, 64)
+		fee, _ := strconv.ParseFloat(item.Fee, 64)
+
+		fills = append(fills, models.Fill{
+			OrderID:   item.OrdID,
+			LinkID:    item.ClOrdID,
+			ExecID:    item.TradeID,
+			Symbol:    item.InstID,
+			Side:      ToSide(item.Side),
+			Price:     price,
+			Qty:       qty,
+			Timestamp: time.UnixMilli(tsMs),
+			Fee:       -fee,
+			FeeCoin:   item.FeeCcy,
+		})
+	}
+	return fills
+}
+
+func ToOrder(item OrderInfo) models.Order {
+	price, _ := strconv.ParseFloat(item.Px, 64)
+	qty, _ := strconv.ParseFloat(item.Sz, 64)
+	filled, _ := strconv.ParseFloat(item.AccFillSz, 64)
+	cTime, _ := strconv.ParseInt(item.CTime, 10, 64)
+	uTime, _ := strconv.ParseInt(item.UTime, 10, 64)
+
+	return models.Order{
+		ID:         item.OrdID,
+		LinkID:     item.ClOrdID,
+		Symbol:     item.InstID,
+		Side:       ToSide(item.Side),
+		Type:       ToOrderType(item.OrdType),
+		Price:      price,
+		Qty:        qty,
+		FilledQty:  filled,
+		Status:     ToOrderStatus(item.State),
+		CreateTime: time.UnixMilli(cTime),
+		UpdateTime: time.UnixMilli(uTime),
+		CancelType: item.CancelSourceReason,
+	}
+}
+
+func ToSide(side string) models.OrderSide {
+	if strings.EqualFold(side, "sell") {
+		return models.OrderSideSell
+	}
+	return models.OrderSideBuy
+}
+
+func ToOrderType(orderType string) models.OrderType {
+	if strings.EqualFold(orderType, "market") {
+		return models.OrderTypeMarket
+	}
+	return models.OrderTypeLimit
+}
+
+func ToOrderStatus(state string) models.OrderStatus {
+	switch strings.ToLower(state) {
+	case "live":
+		return models.OrderStatusNew
+	case "partially_filled":
+		return models.OrderStatusPartiallyFilled
+	case "filled":
+		return models.OrderStatusFilled
+	case "canceled", "mmp_canceled":
+		return models.OrderStatusCanceled
+	default:
+		return models.OrderStatus(state)
+	}
+}
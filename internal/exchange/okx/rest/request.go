@@ -0,0 +1,94 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dcabot/internal/secrets"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func (c *Client) doRequest(ctx context.Context, method, path string, params url.Values, body any, auth bool, out any) error {
+	var bodyReader io.Reader
+	var bodyStr string
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("Не удалось подготовить тело запроса: %w", err)
+		}
+		bodyStr = string(payload)
+		bodyReader = bytes.NewReader(payload)
+	}
+
+	requestPath := path
+	if len(params) > 0 {
+		requestPath += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+requestPath, bodyReader)
+	if err != nil {
+		return fmt.Errorf("Не удалось создать запрос: %w", err)
+	}
+
+	if auth {
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		signature := Sign(c.secret, timestamp+method+requestPath+bodyStr)
+
+		req.Header.Set("OK-ACCESS-KEY", c.apiKey)
+		req.Header.Set("OK-ACCESS-SIGN", signature)
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", c.passphrase)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return secrets.RedactError(fmt.Errorf("Ошибка запроса: %w", err))
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Не удалось прочитать ответ: %w", err)
+	}
+
+	var head struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return fmt.Errorf("Не удалось разобрать ответ: %w", err)
+	}
+
+	if head.Code != "" && head.Code != "0" {
+		var acks okxResponse[orderAck]
+		if err := json.Unmarshal(data, &acks); err == nil && len(acks.Data) > 0 && acks.Data[0].SCode != "" && acks.Data[0].SCode != "0" {
+			return secrets.RedactError(fmt.Errorf("Ошибка okx: %s (code=%s)", acks.Data[0].SMsg, acks.Data[0].SCode))
+		}
+		return secrets.RedactError(fmt.Errorf("Ошибка okx: %s (code=%s)", head.Msg, head.Code))
+	}
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Неуспешный статус: %s", resp.Status)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("Не удалось разобрать ответ: %w", err)
+	}
+
+	return nil
+}
+
+func Sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"dcabot/internal/logger"
+	"net/http"
+)
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	secret     string
+	passphrase string
+	httpClient *http.Client
+	log        *logger.Logger
+}
+
+type okxResponse[T any] struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data []T    `json:"data"`
+}
+
+type orderAck struct {
+	OrdID   string `json:"ordId"`
+	ClOrdID string `json:"clOrdId"`
+	SCode   string `json:"sCode"`
+	SMsg    string `json:"sMsg"`
+}
+
+type OrderInfo struct {
+	InstID    string `json:"instId"`
+	OrdID     string `json:"ordId"`
+	ClOrdID   string `json:"clOrdId"`
+	Side      string `json:"side"`
+	OrdType   string `json:"ordType"`
+	Px        string `json:"px"`
+	Sz        string `json:"sz"`
+	AccFillSz string `json:"accFillSz"`
+	State     string `json:"state"`
+	CTime     string `json:"cTime"`
+	UTime     string `json:"uTime"`
+
+	CancelSource       string `json:"cancelSource"`
+	CancelSourceReason string `json:"cancelSourceReason"`
+}
+
+type fillInfo struct {
+	InstID  string `json:"instId"`
+	TradeID string `json:"tradeId"`
+	BillID  string `json:"billId"`
+	OrdID   string `json:"ordId"`
+	ClOrdID string `json:"clOrdId"`
+	Side    string `json:"side"`
+	FillPx  string `json:"fillPx"`
+	FillSz  string `json:"fillSz"`
+	TS      string `json:"ts"`
+
+	// Fee отрицательная, если комиссия списана.
+	Fee    string `json:"fee"`
+	FeeCcy string `json:"feeCcy"`
+}
@@ -0,0 +1,54 @@
+package ws
+
+import (
+	"dcabot/internal/exchange/okx/rest"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func (w *Client) login(conn *websocket.Conn) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	msg := Request{
+		Op: "login",
+		Args: []LoginArg{{
+			APIKey:     w.apiKey,
+			Passphrase: w.passphrase,
+			Timestamp:  timestamp,
+			Sign:       rest.Sign(w.secret, timestamp+"GET/users/self/verify"),
+		}},
+	}
+
+	if err := w.write(conn, msg); err != nil {
+		return fmt.Errorf("Не удалось авторизоваться: %w", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("Нет ответа на авторизацию: %w", err)
+		}
+
+		var resp Message
+		if err := json.Unmarshal(data, &resp); err != nil {
+			continue
+		}
+
+		switch resp.Event {
+		case "login":
+			if resp.Code != "" && resp.Code != "0" {
+				return fmt.Errorf("Ошибка авторизации okx: %s (code=%s)", resp.Msg, resp.Code)
+			}
+			return nil
+		case "error":
+			return fmt.Errorf("Ошибка авторизации okx: %s (code=%s)", resp.Msg, resp.Code)
+		}
+	}
+}
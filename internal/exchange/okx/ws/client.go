@@ -0,0 +1,123 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"dcabot/internal/logger"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+func New(url, apiKey, secret, passphrase string, log *logger.Logger) *Client {
+	return &Client{
+		url:          url,
+		apiKey:       apiKey,
+		secret:       secret,
+		passphrase:   passphrase,
+		log:          log,
+		events:       make(chan exchange.Event, 100),
+		stopCh:       make(chan struct{}),
+		pingInterval: 25 * time.Second,
+		reconnectMin: 1 * time.Second,
+		reconnectMax: 30 * time.Second,
+	}
+}
+
+func (w *Client) Connect(ctx context.Context, args []Arg) error {
+	w.args = args
+
+	conn, err := w.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.conn = conn
+	w.mu.Unlock()
+
+	w.logEntry().Info("WS соединение установлено.")
+
+	go w.readLoop()
+	go w.pingLoop()
+
+	return nil
+}
+
+func (w *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	w.logEntry().WithField("url", w.url).Info("Подключение к WS.")
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, w.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось подключиться к WS: %w", err)
+	}
+	conn.SetReadLimit(2 << 20)
+
+	if w.apiKey != "" && w.secret != "" {
+		if err := w.login(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	if len(w.args) > 0 {
+		if err := w.write(conn, Request{Op: "subscribe", Args: w.args}); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("Не удалось подписаться на WS: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (w *Client) write(conn *websocket.Conn, msg any) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	return conn.WriteJSON(msg)
+}
+
+func (w *Client) pingLoop() {
+	ticker := time.NewTicker(w.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			conn := w.conn
+			w.mu.Unlock()
+			if conn == nil {
+				continue
+			}
+			w.writeMu.Lock()
+			err := conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+			w.writeMu.Unlock()
+			if err != nil {
+				w.logEntry().WithError(err).Debug("Не удалось отправить ping.")
+			}
+		}
+	}
+}
+
+func (w *Client) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.mu.Lock()
+		if w.conn != nil {
+			_ = w.conn.Close()
+		}
+		w.mu.Unlock()
+	})
+}
+
+func (w *Client) logEntry() *logrus.Entry {
+	return w.log.WithComponent("okx_ws")
+}
+
+func (w *Client) Events() <-chan exchange.Event {
+	return w.events
+}
@@ -0,0 +1,135 @@
+package ws
+
+import (
+	"dcabot/internal/exchange"
+	"dcabot/internal/exchange/okx/rest"
+	"dcabot/internal/models"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+func (w *Client) handleOrders(msg Message) {
+	var data []struct {
+		InstID       string `json:"instId"`
+		OrdID        string `json:"ordId"`
+		ClOrdID      string `json:"clOrdId"`
+		Side         string `json:"side"`
+		OrdType      string `json:"ordType"`
+		Px           string `json:"px"`
+		Sz           string `json:"sz"`
+		AccFillSz    string `json:"accFillSz"`
+		State        string `json:"state"`
+		TradeID      string `json:"tradeId"`
+		FillPx       string `json:"fillPx"`
+		FillSz       string `json:"fillSz"`
+		FillTime     string `json:"fillTime"`
+		CancelSource string `json:"cancelSource"`
+		CancelReason string `json:"cancelSourceReason"`
+		CTime        string `json:"cTime"`
+		UTime        string `json:"uTime"`
+
+		// FillFee отрицательная, если комиссия списана.
+		FillFee    string `json:"fillFee"`
+		FillFeeCcy string `json:"fillFeeCcy"`
+	}
+
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		w.logEntry().WithError(err).Warn("Не удалось разобрать orders.")
+		return
+	}
+
+	for _, item := range data {
+		w.logEntry().WithFields(map[string]interface{}{
+			"inst_id":       item.InstID,
+			"side":          item.Side,
+			"order_id":      item.OrdID,
+			"cl_ord_id":     item.ClOrdID,
+			"type":          item.OrdType,
+			"state":         item.State,
+			"cancel_source": item.CancelSource,
+			"price":         item.Px,
+			"qty":           item.Sz,
+			"acc_fill_sz":   item.AccFillSz,
+			"trade_id":      item.TradeID,
+		}).Debug("order")
+
+		fillSz, _ := strconv.ParseFloat(item.FillSz, 64)
+		if item.TradeID != "" && fillSz > 0 {
+			fillPx, _ := strconv.ParseFloat(item.FillPx, 64)
+			fillMs, _ := strconv.ParseInt(item.FillTime, 10, 64)
+			seq, _ := strconv.ParseInt(item.TradeID, 10, 64)
+			fee, _ := strconv.ParseFloat(item.FillFee, 64)
+
+			w.events <- exchange.Event{
+				Type: exchange.EventTypeFill,
+				Fill: &models.Fill{
+					OrderID:   item.OrdID,
+					LinkID:    item.ClOrdID,
+					ExecID:    item.TradeID,
+					Symbol:    item.InstID,
+					Side:      rest.ToSide(item.Side),
+					Price:     fillPx,
+					Qty:       fillSz,
+					Timestamp: time.UnixMilli(fillMs),
+					Sequence:  seq,
+					Fee:       -fee,
+					FeeCoin:   item.FillFeeCcy,
+				},
+			}
+		}
+
+		order := rest.ToOrder(rest.OrderInfo{
+			InstID:    item.InstID,
+			OrdID:     item.OrdID,
+			ClOrdID:   item.ClOrdID,
+			Side:      item.Side,
+			OrdType:   item.OrdType,
+			Px:        item.Px,
+			Sz:        item.Sz,
+			AccFillSz: item.AccFillSz,
+			State:     item.State,
+			CTime:     item.CTime,
+			UTime:     item.UTime,
+
+			CancelSource:       item.CancelSource,
+			CancelSourceReason: item.CancelReason,
+		})
+		if order.CancelType == "" {
+			order.CancelType = item.CancelSource
+		}
+
+		w.events <- exchange.Event{
+			Type:  exchange.EventTypeOrder,
+			Order: &order,
+		}
+	}
+}
+
+func (w *Client) handleTickers(msg Message) {
+	var data []struct {
+		InstID string `json:"instId"`
+		Last   string `json:"last"`
+		TS     string `json:"ts"`
+	}
+
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		w.logEntry().WithError(err).Warn("Не удалось разобрать ticker.")
+		return
+	}
+
+	for _, item := range data {
+		price, _ := strconv.ParseFloat(item.Last, 64)
+		tsMs, _ := strconv.ParseInt(item.TS, 10, 64)
+
+		w.events <- exchange.Event{
+			Type: exchange.EventTypeTicker,
+			Ticker: &models.Ticker{
+				Symbol:    item.InstID,
+				LastPrice: price,
+				Timestamp: time.UnixMilli(tsMs),
+				Sequence:  tsMs,
+			},
+		}
+	}
+}
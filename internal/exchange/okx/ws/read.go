@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"context"
+	"dcabot/internal/exchange"
+	"encoding/json"
+	"time"
+)
+
+func (w *Client) readLoop() {
+	w.logEntry().Debug("readLoop запущен.")
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		default:
+		}
+
+		w.mu.Lock()
+		conn := w.conn
+		w.mu.Unlock()
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			w.logEntry().WithError(err).Warn("Ошибка чтения WS.")
+
+			if !w.reconnect() {
+				return
+			}
+			continue
+		}
+
+		if string(data) == "pong" {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			w.logEntry().WithError(err).Warn("Не удалось разобрать WS сообщение.")
+			continue
+		}
+
+		if msg.Event == "error" {
+			w.logEntry().WithFields(map[string]interface{}{
+				"code": msg.Code,
+				"msg":  msg.Msg,
+			}).Warn("Ошибка WS okx.")
+			continue
+		}
+
+		if msg.Event != "" || len(msg.Data) == 0 {
+			continue
+		}
+
+		switch msg.Arg.Channel {
+		case "orders":
+			w.handleOrders(msg)
+		case "tickers":
+			w.handleTickers(msg)
+		default:
+			continue
+		}
+	}
+}
+
+func (w *Client) reconnect() bool {
+	backoff := w.reconnectMin
+
+	for {
+		select {
+		case <-w.stopCh:
+			return false
+		default:
+		}
+
+		w.logEntry().Info("Попытка переподключения к WS.")
+
+		time.Sleep(backoff)
+
+		conn, err := w.dial(context.Background())
+		if err != nil {
+			w.logEntry().WithError(err).Warn("Не удалось переподключиться к WS.")
+			backoff = w.nextBackoff(backoff)
+			continue
+		}
+
+		w.mu.Lock()
+		if w.conn != nil {
+			_ = w.conn.Close()
+		}
+		w.conn = conn
+		w.mu.Unlock()
+
+		w.events <- exchange.Event{Type: exchange.EventTypeReconnect}
+		w.logEntry().Info("WS переподключён и подписки восстановлены.")
+		return true
+	}
+}
+
+func (w *Client) nextBackoff(current time.Duration) time.Duration {
+	next := current * 2
+	if next > w.reconnectMax {
+		return w.reconnectMax
+	}
+	return next
+}
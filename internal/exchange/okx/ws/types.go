@@ -0,0 +1,55 @@
+package ws
+
+import (
+	"dcabot/internal/exchange"
+	"dcabot/internal/logger"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type Client struct {
+	url          string
+	apiKey       string
+	secret       string
+	passphrase   string
+	log          *logger.Logger
+	mu           sync.Mutex
+	writeMu      sync.Mutex
+	conn         *websocket.Conn
+	events       chan exchange.Event
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	args         []Arg
+	pingInterval time.Duration
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+}
+
+type Arg struct {
+	Channel  string `json:"channel"`
+	InstType string `json:"instType,omitempty"`
+	InstID   string `json:"instId,omitempty"`
+}
+
+type LoginArg struct {
+	APIKey     string `json:"apiKey"`
+	Passphrase string `json:"passphrase"`
+	Timestamp  string `json:"timestamp"`
+	Sign       string `json:"sign"`
+}
+
+type Request struct {
+	Op   string `json:"op"`
+	Args any    `json:"args"`
+}
+
+type Message struct {
+	Event string          `json:"event"`
+	Code  string          `json:"code"`
+	Msg   string          `json:"msg"`
+	Arg   Arg             `json:"arg"`
+	Data  json.RawMessage `json:"data"`
+}
@@ -0,0 +1,44 @@
+package exchange
+
+import (
+	"dcabot/internal/config"
+	"dcabot/internal/logger"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Factory func(cfg config.ExchangeConfig, log *logger.Logger) (Client, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(strings.TrimSpace(name))] = factory
+}
+
+func New(cfg config.ExchangeConfig, log *logger.Logger) (Client, error) {
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(strings.TrimSpace(cfg.Name))]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Неизвестная биржа: %s (доступны: %s)", cfg.Name, strings.Join(Names(), ", "))
+	}
+	return factory(cfg, log)
+}
+
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -17,6 +17,8 @@ type Config struct {
 	MaxBackups int
 	MaxAge     int
 	Compress   bool
+	Fields     logrus.Fields
+	Redact     func(string) string
 }
 
 type Logger struct {
@@ -68,9 +70,55 @@ func New(cfg Config) *Logger {
 
 	log.SetOutput(writer)
 
+	if len(cfg.Fields) > 0 {
+		log.AddHook(&fieldsHook{fields: cfg.Fields})
+	}
+
+	if cfg.Redact != nil {
+		log.AddHook(&redactHook{redact: cfg.Redact})
+	}
+
 	return &Logger{log: log}
 }
 
+type fieldsHook struct {
+	fields logrus.Fields
+}
+
+func (h *fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fieldsHook) Fire(entry *logrus.Entry) error {
+	for key, value := range h.fields {
+		if _, ok := entry.Data[key]; !ok {
+			entry.Data[key] = value
+		}
+	}
+	return nil
+}
+
+type redactHook struct {
+	redact func(string) string
+}
+
+func (h *redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = h.redact(entry.Message)
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = h.redact(v)
+		case error:
+			entry.Data[key] = h.redact(v.Error())
+		}
+	}
+	return nil
+}
+
 func (l *Logger) Debug(msg string) {
 	l.log.Debug(msg)
 }
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"dcabot/internal/logger"
+	"errors"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	tags = expvar.NewMap("tags")
+
+	muxOnce sync.Once
+	mux     *http.ServeMux
+)
+
+func SetTags(exchange, environment string) {
+	tags.Set("exchange", stringVar(exchange))
+	tags.Set("environment", stringVar(environment))
+}
+
+func stringVar(value string) *expvar.String {
+	v := new(expvar.String)
+	v.Set(value)
+	return v
+}
+
+func Mux() *http.ServeMux {
+	muxOnce.Do(func() {
+		mux = http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+	})
+	return mux
+}
+
+func Handle(pattern string, handler http.Handler) {
+	Mux().Handle(pattern, handler)
+}
+
+// Serve поднимает HTTP сервер с метриками expvar (/debug/vars) и останавливает его по ctx.
+func Serve(ctx context.Context, addr string, log *logger.Logger) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           Mux(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.WithComponent("metrics").WithField("addr", addr).Info("HTTP сервер метрик запущен.")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.WithComponent("metrics").WithError(err).Error("HTTP сервер метрик остановлен с ошибкой.")
+	}
+}
@@ -25,6 +25,7 @@ const (
 	OrderStatusPartiallyFilled OrderStatus = "PartiallyFilled"
 	OrderStatusFilled          OrderStatus = "Filled"
 	OrderStatusCanceled        OrderStatus = "Canceled"
+	OrderStatusRejected        OrderStatus = "Rejected"
 )
 
 const (
@@ -52,6 +53,9 @@ type Order struct {
 	MarketUnit  string      `json:"market_unit"`
 	PriceStep   float64     `json:"price_step"`
 	QtyStep     float64     `json:"qty_step"`
+
+	RejectReason string `json:"reject_reason,omitempty"`
+	CancelType   string `json:"cancel_type,omitempty"`
 }
 
 type Fill struct {
@@ -64,6 +68,10 @@ type Fill struct {
 	Qty       float64   `json:"qty"`
 	Timestamp time.Time `json:"timestamp"`
 	Sequence  int64     `json:"sequence"`
+
+	// Fee - списанная комиссия (положительная) в монете FeeCoin.
+	Fee     float64 `json:"fee"`
+	FeeCoin string  `json:"fee_coin"`
 }
 
 type Ticker struct {
@@ -72,3 +80,12 @@ type Ticker struct {
 	Timestamp time.Time `json:"timestamp"`
 	Sequence  int64     `json:"sequence"`
 }
+
+type Kline struct {
+	Start  time.Time `json:"start"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
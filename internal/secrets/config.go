@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"context"
+	"dcabot/internal/config"
+	"fmt"
+	"os"
+)
+
+const keystorePassphraseEnv = "DCABOT_KEYSTORE_PASSPHRASE"
+
+// Apply подключает провайдеров из секции secrets и раскрывает ключи биржи в конфиге.
+func Apply(ctx context.Context, cfg *config.Config) error {
+	if cfg.Secrets.Vault.Address != "" {
+		token, err := Resolve(ctx, cfg.Secrets.Vault.Token)
+		if err != nil {
+			return err
+		}
+		Register("vault", NewHTTPProvider(cfg.Secrets.Vault.Address, token, cfg.Secrets.Vault.Timeout))
+	}
+
+	if cfg.Secrets.KeystoreFile != "" {
+		passphrase, err := Resolve(ctx, cfg.Secrets.KeystorePassphrase)
+		if err != nil {
+			return err
+		}
+		if passphrase == "" {
+			passphrase = os.Getenv(keystorePassphraseEnv)
+		}
+		ks, err := OpenKeystore(cfg.Secrets.KeystoreFile, passphrase)
+		if err != nil {
+			return err
+		}
+		Register("keystore", ks)
+	}
+
+	fields := []*string{&cfg.Exchange.ApiKey, &cfg.Exchange.Secret, &cfg.Exchange.Passphrase}
+	for _, field := range fields {
+		value, err := Resolve(ctx, *field)
+		if err != nil {
+			return fmt.Errorf("Не удалось загрузить ключи биржи: %w", err)
+		}
+		*field = value
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	keystoreVersion    = 1
+	keystoreKDF        = "pbkdf2-sha256"
+	keystoreIterations = 600000
+	keystoreSaltSize   = 16
+	keystoreKeySize    = 32
+)
+
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type Keystore struct {
+	path       string
+	passphrase string
+	values     map[string]string
+}
+
+func OpenKeystore(path, passphrase string) (*Keystore, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("Не задан пароль хранилища ключей.")
+	}
+	ks := &Keystore{path: path, passphrase: passphrase, values: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось прочитать хранилище ключей %s: %w", path, err)
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("Не удалось разобрать хранилище ключей %s: %w", path, err)
+	}
+	if file.Version != keystoreVersion || file.KDF != keystoreKDF {
+		return nil, fmt.Errorf("Неподдерживаемый формат хранилища ключей: version=%d kdf=%s", file.Version, file.KDF)
+	}
+
+	gcm, err := keystoreCipher(passphrase, file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Не удалось расшифровать хранилище ключей, неверный пароль или файл повреждён.")
+	}
+	if err := json.Unmarshal(plain, &ks.values); err != nil {
+		return nil, fmt.Errorf("Не удалось разобрать содержимое хранилища ключей: %w", err)
+	}
+	for _, value := range ks.values {
+		Track(value)
+	}
+	return ks, nil
+}
+
+func (ks *Keystore) Get(_ context.Context, key string) (string, error) {
+	value, ok := ks.values[key]
+	if !ok {
+		return "", fmt.Errorf("ключ %s не найден в хранилище", key)
+	}
+	return value, nil
+}
+
+func (ks *Keystore) Set(key, value string) {
+	ks.values[key] = value
+}
+
+func (ks *Keystore) Delete(key string) {
+	delete(ks.values, key)
+}
+
+func (ks *Keystore) Keys() []string {
+	keys := make([]string, 0, len(ks.values))
+	for key := range ks.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (ks *Keystore) Save() error {
+	plain, err := json.Marshal(ks.values)
+	if err != nil {
+		return fmt.Errorf("Не удалось подготовить содержимое хранилища ключей: %w", err)
+	}
+
+	salt := make([]byte, keystoreSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := keystoreCipher(ks.passphrase, salt, keystoreIterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keystoreFile{
+		Version:    keystoreVersion,
+		KDF:        keystoreKDF,
+		Iterations: keystoreIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Не удалось подготовить хранилище ключей: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ks.path), 0o700); err != nil {
+		return fmt.Errorf("Не удалось создать каталог для хранилища ключей: %w", err)
+	}
+	tmp := ks.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("Не удалось записать хранилище ключей %s: %w", ks.path, err)
+	}
+	return os.Rename(tmp, ks.path)
+}
+
+func keystoreCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 {
+		return nil, fmt.Errorf("Некорректное число итераций хранилища ключей: %d", iterations)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keystoreKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,161 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+func saveTestKeystore(t *testing.T, values map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys", "keystore.json")
+	ks, err := OpenKeystore(path, testPassphrase)
+	if err != nil {
+		t.Fatalf("OpenKeystore(new) error = %v", err)
+	}
+	for key, value := range values {
+		ks.Set(key, value)
+	}
+	if err := ks.Save(); err != nil {
+		t.Fatalf("Save error = %v", err)
+	}
+	return path
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	values := map[string]string{"api_key": "ks-round-trip-key", "secret": "ks-round-trip-secret"}
+	path := saveTestKeystore(t, values)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("keystore mode = %o, want 600", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+	for _, value := range values {
+		if strings.Contains(string(data), value) {
+			t.Fatalf("keystore file contains plaintext %q", value)
+		}
+	}
+
+	ks, err := OpenKeystore(path, testPassphrase)
+	if err != nil {
+		t.Fatalf("OpenKeystore error = %v", err)
+	}
+	if got := strings.Join(ks.Keys(), ","); got != "api_key,secret" {
+		t.Fatalf("Keys = %q, want api_key,secret", got)
+	}
+	for key, want := range values {
+		got, err := ks.Get(context.Background(), key)
+		if err != nil || got != want {
+			t.Fatalf("Get(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if _, err := ks.Get(context.Background(), "passphrase"); err == nil {
+		t.Fatal("Get of missing key returned no error")
+	}
+
+	ks.Delete("secret")
+	if err := ks.Save(); err != nil {
+		t.Fatalf("Save after Delete error = %v", err)
+	}
+	reopened, err := OpenKeystore(path, testPassphrase)
+	if err != nil {
+		t.Fatalf("OpenKeystore after Delete error = %v", err)
+	}
+	if got := strings.Join(reopened.Keys(), ","); got != "api_key" {
+		t.Fatalf("Keys after Delete = %q, want api_key", got)
+	}
+}
+
+func TestOpenKeystoreErrors(t *testing.T) {
+	path := saveTestKeystore(t, map[string]string{"api_key": "ks-error-key"})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile error = %v", err)
+	}
+
+	rewrite := func(t *testing.T, edit func(file *keystoreFile)) string {
+		t.Helper()
+		var file keystoreFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatalf("Unmarshal error = %v", err)
+		}
+		edit(&file)
+		out, err := json.Marshal(file)
+		if err != nil {
+			t.Fatalf("Marshal error = %v", err)
+		}
+		edited := filepath.Join(t.TempDir(), "keystore.json")
+		if err := os.WriteFile(edited, out, 0o600); err != nil {
+			t.Fatalf("WriteFile error = %v", err)
+		}
+		return edited
+	}
+
+	tests := []struct {
+		name       string
+		path       func(t *testing.T) string
+		passphrase string
+		wantErr    string
+	}{
+		{
+			name:       "wrong passphrase",
+			path:       func(t *testing.T) string { return path },
+			passphrase: "wrong passphrase",
+			wantErr:    "неверный пароль",
+		},
+		{
+			name:       "empty passphrase",
+			path:       func(t *testing.T) string { return path },
+			passphrase: "",
+			wantErr:    "Не задан пароль",
+		},
+		{
+			name: "tampered ciphertext",
+			path: func(t *testing.T) string {
+				return rewrite(t, func(file *keystoreFile) { file.Ciphertext[0] ^= 0xff })
+			},
+			passphrase: testPassphrase,
+			wantErr:    "неверный пароль",
+		},
+		{
+			name: "unsupported version",
+			path: func(t *testing.T) string {
+				return rewrite(t, func(file *keystoreFile) { file.Version = keystoreVersion + 1 })
+			},
+			passphrase: testPassphrase,
+			wantErr:    "Неподдерживаемый формат",
+		},
+		{
+			name: "not json",
+			path: func(t *testing.T) string {
+				broken := filepath.Join(t.TempDir(), "keystore.json")
+				if err := os.WriteFile(broken, []byte("not json"), 0o600); err != nil {
+					t.Fatalf("WriteFile error = %v", err)
+				}
+				return broken
+			},
+			passphrase: testPassphrase,
+			wantErr:    "Не удалось разобрать",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenKeystore(tt.path(t), tt.passphrase)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("OpenKeystore error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
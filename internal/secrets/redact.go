@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	redacted        = "***"
+	minRedactLength = 4
+)
+
+var (
+	trackedMu sync.RWMutex
+	tracked   []string
+
+	signaturePattern = regexp.MustCompile(`(?i)(signature|sign|apiKey|api_key|passphrase)=([^&\s"]+)`)
+)
+
+func Track(values ...string) {
+	trackedMu.Lock()
+	defer trackedMu.Unlock()
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minRedactLength {
+			continue
+		}
+		exists := false
+		for _, known := range tracked {
+			if known == value {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			tracked = append(tracked, value)
+		}
+	}
+	sort.Slice(tracked, func(i, j int) bool { return len(tracked[i]) > len(tracked[j]) })
+}
+
+func Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	trackedMu.RLock()
+	for _, value := range tracked {
+		s = strings.ReplaceAll(s, value, redacted)
+	}
+	trackedMu.RUnlock()
+	return signaturePattern.ReplaceAllString(s, "$1="+redacted)
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err}
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Provider interface {
+	Get(ctx context.Context, key string) (string, error)
+}
+
+type ProviderFunc func(ctx context.Context, key string) (string, error)
+
+func (f ProviderFunc) Get(ctx context.Context, key string) (string, error) {
+	return f(ctx, key)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+func init() {
+	Register("env", ProviderFunc(envSecret))
+	Register("file", ProviderFunc(fileSecret))
+}
+
+func Register(scheme string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[strings.ToLower(scheme)] = provider
+}
+
+func lookup(scheme string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[strings.ToLower(scheme)]
+	return provider, ok
+}
+
+// Resolve раскрывает ссылку вида scheme:key через зарегистрированного провайдера,
+// значения без известной схемы возвращаются как есть.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	scheme, key, ok := strings.Cut(ref, ":")
+	if !ok {
+		Track(ref)
+		return ref, nil
+	}
+	provider, ok := lookup(scheme)
+	if !ok {
+		Track(ref)
+		return ref, nil
+	}
+	value, err := provider.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("Не удалось получить секрет %s: %w", scheme, err)
+	}
+	if value == "" {
+		return "", fmt.Errorf("Секрет %s пуст.", ref)
+	}
+	Track(value)
+	return value, nil
+}
+
+func envSecret(_ context.Context, key string) (string, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("переменная окружения %s не задана", key)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+func fileSecret(_ context.Context, key string) (string, error) {
+	data, err := os.ReadFile(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveProviders(t *testing.T) {
+	t.Setenv("DCABOT_TEST_API_KEY", "  env-provider-key\n")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("file-provider-secret\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	emptyFile := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "env", ref: "env:DCABOT_TEST_API_KEY", want: "env-provider-key"},
+		{name: "env upper case scheme", ref: "ENV:DCABOT_TEST_API_KEY", want: "env-provider-key"},
+		{name: "env missing", ref: "env:DCABOT_TEST_MISSING", wantErr: "не задана"},
+		{name: "file", ref: "file:" + secretFile, want: "file-provider-secret"},
+		{name: "file missing", ref: "file:" + filepath.Join(t.TempDir(), "missing"), wantErr: "Не удалось получить секрет file"},
+		{name: "file empty", ref: "file:" + emptyFile, wantErr: "пуст"},
+		{name: "plain value", ref: "plain-api-key", want: "plain-api-key"},
+		{name: "unknown scheme", ref: "https://example.com/key", want: "https://example.com/key"},
+		{name: "empty", ref: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve(%q) error = %v, want containing %q", tt.ref, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.ref, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Resolve(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+			if got != "" && strings.Contains(Redact("value "+got), got) {
+				t.Fatalf("resolved value %q is not redacted", got)
+			}
+		})
+	}
+}
+
+func TestResolveKeystoreRef(t *testing.T) {
+	path := saveTestKeystore(t, map[string]string{"api_key": "ks-resolve-key"})
+	ks, err := OpenKeystore(path, testPassphrase)
+	if err != nil {
+		t.Fatalf("OpenKeystore error = %v", err)
+	}
+	Register("keystore", ks)
+
+	got, err := Resolve(context.Background(), "keystore:api_key")
+	if err != nil || got != "ks-resolve-key" {
+		t.Fatalf("Resolve = %q, %v; want ks-resolve-key", got, err)
+	}
+	if _, err := Resolve(context.Background(), "keystore:missing"); err == nil {
+		t.Fatal("Resolve of missing keystore key returned no error")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	Track("redact-test-secret-value", "abc")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "tracked value", in: "key redact-test-secret-value failed", want: "key *** failed"},
+		{name: "short value not tracked", in: "abc", want: "abc"},
+		{name: "signature param", in: "GET /api?symbol=BTCUSDT&signature=deadbeef&x=1", want: "GET /api?symbol=BTCUSDT&signature=***&x=1"},
+		{name: "api key param", in: `apiKey=k123 "api_key=k456"`, want: `apiKey=*** "api_key=***"`},
+		{name: "empty", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Fatalf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	if RedactError(nil) != nil {
+		t.Fatal("RedactError(nil) != nil")
+	}
+	Track("redact-error-secret")
+	cause := errors.New("denied for redact-error-secret")
+	err := RedactError(cause)
+	if strings.Contains(err.Error(), "redact-error-secret") {
+		t.Fatalf("error not redacted: %q", err.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Fatal("RedactError does not unwrap to the cause")
+	}
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPProvider читает секреты из KV-хранилища, совместимого с HTTP API Vault.
+// Ссылка задаётся как path#field, например secret/data/dcabot#api_key.
+type HTTPProvider struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+func NewHTTPProvider(address, token string, timeout time.Duration) *HTTPProvider {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	Track(token)
+	return &HTTPProvider{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPProvider) Get(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || field == "" {
+		return "", fmt.Errorf("ссылка %s должна иметь вид path#field", ref)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.address+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if p.token != "" {
+		req.Header.Set("X-Vault-Token", p.token)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", RedactError(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("неуспешный статус хранилища: %s", resp.Status)
+	}
+
+	var payload struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", fmt.Errorf("не удалось разобрать ответ хранилища: %w", err)
+	}
+
+	values := payload.Data
+	if nested, ok := payload.Data["data"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil {
+			values = inner
+		}
+	}
+
+	raw, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("поле %s не найдено по пути %s", field, path)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("поле %s по пути %s не строка", field, path)
+	}
+	return value, nil
+}
@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testVaultToken = "s.test-vault-token"
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != testVaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/secret/data/dcabot":
+			_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"kv2-key","secret":"kv2-secret","retries":3},"metadata":{"version":1}}}`))
+		case "/v1/kv/dcabot":
+			_, _ = w.Write([]byte(`{"data":{"api_key":"kv1-key"}}`))
+		case "/v1/broken":
+			_, _ = w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPProviderGet(t *testing.T) {
+	server := newVaultServer(t)
+	provider := NewHTTPProvider(server.URL+"/", testVaultToken, time.Second)
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{name: "kv v2", ref: "secret/data/dcabot#api_key", want: "kv2-key"},
+		{name: "kv v2 leading slash", ref: "/secret/data/dcabot#secret", want: "kv2-secret"},
+		{name: "kv v1", ref: "kv/dcabot#api_key", want: "kv1-key"},
+		{name: "missing field", ref: "secret/data/dcabot#passphrase", wantErr: "поле passphrase не найдено"},
+		{name: "not a string", ref: "secret/data/dcabot#retries", wantErr: "не строка"},
+		{name: "no field", ref: "secret/data/dcabot", wantErr: "path#field"},
+		{name: "not found", ref: "secret/data/missing#api_key", wantErr: "404"},
+		{name: "bad payload", ref: "broken#api_key", wantErr: "не удалось разобрать"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.Get(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Get(%q) error = %v, want containing %q", tt.ref, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get(%q) error = %v", tt.ref, err)
+			}
+			if got != tt.want {
+				t.Fatalf("Get(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderWrongToken(t *testing.T) {
+	server := newVaultServer(t)
+	provider := NewHTTPProvider(server.URL, "s.wrong-token", time.Second)
+
+	_, err := provider.Get(context.Background(), "secret/data/dcabot#api_key")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Get with wrong token error = %v, want 403", err)
+	}
+}
+
+func TestResolveVaultRef(t *testing.T) {
+	server := newVaultServer(t)
+	Register("vault", NewHTTPProvider(server.URL, testVaultToken, time.Second))
+
+	got, err := Resolve(context.Background(), "vault:secret/data/dcabot#api_key")
+	if err != nil {
+		t.Fatalf("Resolve error = %v", err)
+	}
+	if got != "kv2-key" {
+		t.Fatalf("Resolve = %q, want kv2-key", got)
+	}
+	if redactedValue := Redact("key=" + got + " token=" + testVaultToken); strings.Contains(redactedValue, got) || strings.Contains(redactedValue, testVaultToken) {
+		t.Fatalf("Redact left secrets in %q", redactedValue)
+	}
+}